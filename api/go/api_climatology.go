@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+)
+
+// GetClimatology returns a state's report count for a day or month next
+// to the climatological baseline for that day or month.
+func (s ServerAndDB) GetClimatology(c echo.Context) error {
+	if s.Climatology == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "climatology is not enabled"})
+	}
+
+	state := strings.ToUpper(c.QueryParam("state"))
+	if len(state) != 2 {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "state must be a two letter abbreviation"})
+	}
+	rptType := database.ReportType(strings.ToLower(c.QueryParam("type")))
+	if _, ok := magnitudeParams[rptType]; !ok {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "type must be hail, wind, or tornado"})
+	}
+	period := c.QueryParam("period")
+	switch period {
+	case "":
+		period = PeriodMonth
+	case PeriodDay, PeriodMonth:
+	default:
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "period must be day or month"})
+	}
+	t := time.Now()
+	if v := c.QueryParam("date"); v != "" {
+		day, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "date value not valid format, use YYYY-MM-DD"})
+		}
+		// noon is the start of the convective day named by the date.
+		t = day.Add(12 * time.Hour)
+	}
+
+	cmp, err := s.Climatology.Compare(c.Request().Context(), state, rptType, period, t)
+	if err != nil {
+		s.Logger.Error("failed to compare with climatology", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	return c.JSON(http.StatusOK, cmp)
+}
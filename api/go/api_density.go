@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultDensitySize = 0.25
+	minDensitySize     = 0.05
+	maxDensitySize     = 10
+)
+
+// GetDensity bins the reports matching the report filters into grid or
+// hex cells, as json or GeoJSON.
+func (s ServerAndDB) GetDensity(c echo.Context) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	cell := c.QueryParam("cell")
+	switch cell {
+	case "":
+		cell = CellGrid
+	case CellGrid, CellHex:
+	default:
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "cell must be grid or hex"})
+	}
+	size := defaultDensitySize
+	if v := c.QueryParam("size"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < minDensitySize || n > maxDensitySize {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: fmt.Sprintf("size must be between %g and %g degrees", minDensitySize, float64(maxDensitySize))})
+		}
+		size = n
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "geojson" {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "format must be json or geojson"})
+	}
+
+	density, err := QueryDensity(c.Request().Context(), s.Conn, f, cell, size)
+	if err != nil {
+		s.Logger.Error("failed to query report density", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	setUsageRows(c, len(density.Cells))
+	if format == "geojson" {
+		return geoJSON(c, featureCollection(density.Cells))
+	}
+	return c.JSON(http.StatusOK, density)
+}
+
+// GetTile returns the pre-aggregated report cells of a slippy map tile as
+// GeoJSON, each tile is a 16x16 grid of cells.
+func (s ServerAndDB) GetTile(c echo.Context) error {
+	if s.Tiles == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "tiles are not enabled"})
+	}
+	z, x, y, errResponse := parseTile(c.Param("z"), c.Param("x"), c.Param("y"), MaxTileZoom)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	f, errResponse := parseTileFilter(c)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+
+	cells, err := s.Tiles.Tile(c.Request().Context(), z, x, y, f)
+	if err != nil {
+		s.Logger.Error("failed to query tile", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	setUsageRows(c, len(cells))
+	return geoJSON(c, featureCollection(cells))
+}
+
+func parseTile(zParam, xParam, yParam string, maxZoom int) (z, x, y int, errResponse ApiResponse) {
+	z, err := strconv.Atoi(zParam)
+	if err != nil || z < 0 || z > maxZoom {
+		return 0, 0, 0, ApiResponse{Code: 400, Message: fmt.Sprintf("z must be between 0 and %d", maxZoom)}
+	}
+	n := 1 << z
+	x, errX := strconv.Atoi(xParam)
+	y, errY := strconv.Atoi(yParam)
+	if errX != nil || errY != nil || x < 0 || y < 0 || x >= n || y >= n {
+		return 0, 0, 0, ApiResponse{Code: 400, Message: fmt.Sprintf("x and y must be between 0 and %d at zoom %d", n-1, z)}
+	}
+	return z, x, y, ApiResponse{}
+}
+
+// parseTileFilter reads the filters tiles accept, type and the dates.
+// Tiles are aggregated by convective day so times are ignored.
+func parseTileFilter(c echo.Context) (TileFilter, ApiResponse) {
+	rf, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return TileFilter{}, errResponse
+	}
+	f := TileFilter{Types: rf.Types}
+	if !rf.From.IsZero() {
+		f.From = rf.From.Truncate(24 * time.Hour)
+	}
+	if !rf.To.IsZero() {
+		// To is exclusive.
+		f.To = rf.To.Add(-time.Microsecond).Truncate(24 * time.Hour)
+	}
+	return f, ApiResponse{}
+}
+
+func geoJSON(c echo.Context, v any) error {
+	c.Response().Header().Set(echo.HeaderContentType, "application/geo+json")
+	c.Response().WriteHeader(http.StatusOK)
+	return json.NewEncoder(c.Response()).Encode(v)
+}
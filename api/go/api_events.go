@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+)
+
+// GetEvents returns the storm events matching the filters, newest first.
+func (s ServerAndDB) GetEvents(c echo.Context) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	minReports := 1
+	if v := c.QueryParam("min-reports"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "min-reports must be a whole number of at least 1"})
+		}
+		minReports = n
+	}
+
+	evts, err := QueryEvents(c.Request().Context(), s.Conn, f, minReports)
+	if err != nil {
+		s.Logger.Error("failed to query events", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	setUsageRows(c, len(evts))
+	return c.JSON(http.StatusOK, StormEvents{Events: evts})
+}
+
+// RebuildEvents clusters every stored report into events again, with the
+// thresholds the consumer uses.
+func (s ServerAndDB) RebuildEvents(c echo.Context) error {
+	n, rpts, err := events.Rebuild(c.Request().Context(), s.Conn, s.EventThresholds)
+	if err != nil {
+		s.Logger.Error("failed to rebuild events", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error rebuilding events"})
+	}
+	s.Logger.Info("rebuilt events", "events", n, "reports", rpts)
+	return c.JSON(http.StatusOK, EventRebuild{Events: n, Reports: rpts})
+}
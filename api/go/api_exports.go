@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CreateExport queues an export of the reports matching the report
+// filters.  The response is the job, poll its status_url until it is
+// complete and then download the file from its download_url.
+func (s ServerAndDB) CreateExport(c echo.Context) error {
+	if s.Exports == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "exports are not enabled"})
+	}
+	qp := c.QueryParams()
+	format := qp.Get("format")
+	if format != ExportShapefile && format != ExportParquet {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "format must be shapefile or parquet"})
+	}
+	// the filters are checked now so a bad one fails here rather than in
+	// the background.
+	if _, errResponse := ParseReportFilter(qp, ""); errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	filters := c.Request().URL.Query()
+	filters.Del("format")
+
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	j, err := s.Exports.Submit(c.Request().Context(), keyID, format, filters)
+	if errors.Is(err, errTooManyExports) {
+		return c.JSON(http.StatusTooManyRequests, ApiResponse{Code: 429, Message: err.Error()})
+	}
+	if err != nil {
+		s.Logger.Error("failed to queue export", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	j = withExportURLs(j)
+	c.Response().Header().Set(echo.HeaderLocation, j.StatusURL)
+	return c.JSON(http.StatusAccepted, j)
+}
+
+// GetExport returns the status of one of the api key's exports.
+func (s ServerAndDB) GetExport(c echo.Context) error {
+	return s.withExport(c, func(j ExportJob) error {
+		return c.JSON(http.StatusOK, withExportURLs(j))
+	})
+}
+
+// DownloadExport sends the file of a complete export.
+func (s ServerAndDB) DownloadExport(c echo.Context) error {
+	return s.withExport(c, func(j ExportJob) error {
+		if j.Status != ExportComplete {
+			return c.JSON(http.StatusConflict, ApiResponse{Code: 409, Message: "export is " + j.Status})
+		}
+		path := s.Exports.Path(j)
+		if _, err := os.Stat(path); err != nil {
+			s.Logger.Error("export file is missing", "error", err, "export", j.ID)
+			return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: errExportNotFound.Error()})
+		}
+		if j.Rows != nil {
+			setUsageRows(c, int(*j.Rows))
+		}
+		return c.Attachment(path, "reports-"+j.ID+".zip")
+	})
+}
+
+// withExport looks up the api key's export named in the path and calls
+// fn with it, mapping errExportNotFound to a 404.
+func (s ServerAndDB) withExport(c echo.Context, fn func(j ExportJob) error) error {
+	if s.Exports == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "exports are not enabled"})
+	}
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	j, err := s.Exports.Get(c.Request().Context(), keyID, c.Param("id"))
+	if errors.Is(err, errExportNotFound) {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: err.Error()})
+	}
+	if err != nil {
+		s.Logger.Error("failed to query export", "error", err, "export", c.Param("id"))
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	return fn(j)
+}
+
+// withExportURLs fills in where to poll and download the export.
+func withExportURLs(j ExportJob) ExportJob {
+	j.StatusURL = "/api/v1/exports/" + j.ID
+	if j.Status == ExportComplete {
+		j.DownloadURL = j.StatusURL + "/download"
+	}
+	return j
+}
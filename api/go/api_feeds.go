@@ -0,0 +1,125 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// feedEntries is how many of the newest reports a feed has.
+const feedEntries = 100
+
+// GetReportsAtom returns the newest reports matching the filters as an
+// Atom feed.
+func (s ServerAndDB) GetReportsAtom(c echo.Context) error {
+	return s.serveFeed(c, false, atomContentType, renderAtom)
+}
+
+// GetReportsRSS returns the newest reports matching the filters as an
+// RSS feed.
+func (s ServerAndDB) GetReportsRSS(c echo.Context) error {
+	return s.serveFeed(c, false, rssContentType, renderRSS)
+}
+
+// GetAlertsCAP returns the newest significant reports matching the
+// filters as an Atom feed of CAP 1.2 alerts.
+func (s ServerAndDB) GetAlertsCAP(c echo.Context) error {
+	return s.serveFeed(c, true, atomContentType, renderCAP)
+}
+
+// serveFeed serves a feed of the newest reports matching the report
+// filters, from the response cache and with conditional requests like
+// the report endpoints.
+func (s ServerAndDB) serveFeed(c echo.Context, significant bool, contentType string, render func(feed, []StoredReport) ([]byte, error)) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	f.Significant, f.Newest, f.Limit = significant, true, feedEntries
+
+	key := cacheKey(c)
+	entry, generation, ok := s.Cache.get(key)
+	if !ok {
+		count, lastModified, err := ReportsVersion(c.Request().Context(), s.Conn, f)
+		if err != nil {
+			s.Logger.Error("failed to query report version", "error", err)
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+		}
+		entry = &cacheEntry{
+			filter:       f,
+			etag:         reportsETag(key, count, lastModified),
+			lastModified: lastModified,
+		}
+	}
+	setCacheHeaders(c, f, entry.etag, entry.lastModified)
+	if notModified(c.Request(), entry.etag, entry.lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if !ok {
+		rpts, errResponse := s.getReportsByFilter(c, f)
+		if errResponse.Code > 0 {
+			return c.JSON(int(errResponse.Code), errResponse)
+		}
+		body, err := render(feed{
+			Title:   feedTitle(f),
+			URL:     feedURL(c),
+			Sender:  c.Request().Host,
+			Updated: time.Now(),
+		}, rpts)
+		if err != nil {
+			return err
+		}
+		entry.body = body
+		entry.rows = len(rpts)
+		s.Cache.set(key, generation, entry)
+	}
+
+	setUsageRows(c, entry.rows)
+	return c.Blob(http.StatusOK, contentType, entry.body)
+}
+
+// feedTitle describes what the feed has, e.g. "StormSync hail reports
+// for Cleveland County, OK".
+func feedTitle(f ReportFilter) string {
+	kind := "storm"
+	if len(f.Types) > 0 {
+		types := make([]string, len(f.Types))
+		for i, t := range f.Types {
+			types[i] = string(t)
+		}
+		kind = strings.Join(types, " and ")
+	}
+	title := "StormSync " + kind + " reports"
+	if f.Significant {
+		title = "StormSync significant " + kind + " reports"
+	}
+	var where []string
+	if f.County != "" {
+		where = append(where, f.County+" County")
+	}
+	if f.State != "" {
+		where = append(where, f.State)
+	}
+	if f.Office != "" {
+		where = append(where, "office "+f.Office)
+	}
+	if len(where) > 0 {
+		title += " for " + strings.Join(where, ", ")
+	}
+	return title
+}
+
+// feedURL is the address the feed was requested at, less the api key so
+// it isn't published in the feed.
+func feedURL(c echo.Context) string {
+	u := *c.Request().URL
+	q := u.Query()
+	q.Del(apiKeyQueryParam)
+	u.RawQuery = q.Encode()
+	u.Scheme = c.Scheme()
+	u.Host = c.Request().Host
+	return u.String()
+}
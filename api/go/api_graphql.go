@@ -0,0 +1,329 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// graphQLSocketProtocol is the websocket subprotocol of graphql-ws,
+	// the subscriptions-transport-ws protocol it replaced isn't spoken.
+	graphQLSocketProtocol = "graphql-transport-ws"
+	graphQLInitTimeout    = 10 * time.Second
+	graphQLMaxMessageSize = 64 * 1024
+)
+
+// close codes of graphql-transport-ws.
+const (
+	graphQLCloseBadRequest   = 4400
+	graphQLCloseUnauthorized = 4401
+	graphQLCloseInitTimeout  = 4408
+	graphQLCloseDuplicateID  = 4409
+	graphQLCloseTooManyInits = 4429
+)
+
+var graphQLUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	Subprotocols:    []string{graphQLSocketProtocol},
+	// as for the reports socket, clients authenticate with an api key.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// GraphQL executes a GraphQL query.  Errors in the query, including
+// queries that are too complex, are returned as GraphQL errors with a
+// 200 like any GraphQL server.
+func (s ServerAndDB) GraphQL(c echo.Context) error {
+	var req GraphQLRequest
+	if err := c.Bind(&req); err != nil || req.Query == "" {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "body must be a json object with a query"})
+	}
+	ctx, gc := s.newGraphQLContext(c.Request().Context())
+	op, errs := prepareGraphQL(req)
+	var res *graphql.Result
+	switch {
+	case errs != nil:
+		res = &graphql.Result{Errors: errs}
+	case op != nil && op.Operation == ast.OperationTypeSubscription:
+		res = &graphql.Result{Errors: []gqlerrors.FormattedError{
+			gqlerrors.NewFormattedError("subscriptions are served over the websocket at /api/v1/graphql/ws"),
+		}}
+	default:
+		res = s.executeGraphQL(ctx, req)
+	}
+	setUsageRows(c, int(gc.rows.Load()))
+	return c.JSON(http.StatusOK, res)
+}
+
+// prepareGraphQL parses the request and checks it isn't too complex,
+// returning the operation to execute.  A nil operation with no errors is
+// left for the executor to report.
+func prepareGraphQL(req GraphQLRequest) (*ast.OperationDefinition, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	op := graphQLOperation(doc, req.OperationName)
+	if op == nil {
+		return nil, nil
+	}
+	if _, err := graphQLComplexity(doc, op, req.Variables); err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	return op, nil
+}
+
+func (s ServerAndDB) executeGraphQL(ctx context.Context, req GraphQLRequest) *graphql.Result {
+	return graphql.Do(graphql.Params{
+		Schema:         *s.graphQL,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+}
+
+// GraphQLSocket serves GraphQL operations, and subscriptions in
+// particular, over a websocket with the graphql-transport-ws protocol.
+func (s ServerAndDB) GraphQLSocket(c echo.Context) error {
+	conn, err := graphQLUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader has already written the error response.
+		return nil
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != graphQLSocketProtocol {
+		closeGraphQLSocket(conn, graphQLCloseBadRequest, "Subprotocol not acceptable")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	ctx, gc := s.newGraphQLContext(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		setUsageRows(c, int(gc.rows.Load()))
+	}()
+
+	requests := make(chan GraphQLSocketMessage)
+	done := make(chan struct{})
+	defer close(done)
+	go readGraphQLMessages(conn, requests, done)
+
+	results := make(chan GraphQLSocketMessage)
+	ops := map[string]context.CancelFunc{}
+	acked := false
+	initTimeout := time.NewTimer(graphQLInitTimeout)
+	defer initTimeout.Stop()
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+	for {
+		var msg GraphQLSocketMessage
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return nil
+			}
+			switch req.Type {
+			case "connection_init":
+				if acked {
+					closeGraphQLSocket(conn, graphQLCloseTooManyInits, "Too many initialisation requests")
+					return nil
+				}
+				acked = true
+				msg = GraphQLSocketMessage{Type: "connection_ack"}
+			case "ping":
+				msg = GraphQLSocketMessage{Type: "pong"}
+			case "pong":
+				continue
+			case "subscribe":
+				if !acked {
+					closeGraphQLSocket(conn, graphQLCloseUnauthorized, "Unauthorized")
+					return nil
+				}
+				var payload GraphQLRequest
+				if req.ID == "" || json.Unmarshal(req.Payload, &payload) != nil || payload.Query == "" {
+					closeGraphQLSocket(conn, graphQLCloseBadRequest, "Invalid message")
+					return nil
+				}
+				if _, ok := ops[req.ID]; ok {
+					closeGraphQLSocket(conn, graphQLCloseDuplicateID, fmt.Sprintf("Subscriber for %s already exists", req.ID))
+					return nil
+				}
+				if len(ops) >= socketMaxSubscriptions {
+					msg = graphQLSocketError(req.ID, gqlerrors.FormatErrors(
+						fmt.Errorf("a connection can have at most %d operations", socketMaxSubscriptions)))
+					break
+				}
+				opCtx, opCancel := context.WithCancel(ctx)
+				ops[req.ID] = opCancel
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.runGraphQLOperation(opCtx, req.ID, payload, results)
+				}()
+				continue
+			case "complete":
+				if opCancel, ok := ops[req.ID]; ok {
+					opCancel()
+					delete(ops, req.ID)
+				}
+				continue
+			default:
+				closeGraphQLSocket(conn, graphQLCloseBadRequest, "Invalid message")
+				return nil
+			}
+		case res := <-results:
+			opCancel, ok := ops[res.ID]
+			if !ok {
+				// the client completed it already.
+				continue
+			}
+			if res.Type != "next" {
+				opCancel()
+				delete(ops, res.ID)
+			}
+			msg = res
+		case <-initTimeout.C:
+			if !acked {
+				closeGraphQLSocket(conn, graphQLCloseInitTimeout, "Connection initialisation timeout")
+				return nil
+			}
+			continue
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait)); err != nil {
+				return nil
+			}
+			continue
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		if err := conn.WriteJSON(msg); err != nil {
+			return nil
+		}
+	}
+}
+
+// runGraphQLOperation executes an operation for the socket, sending its
+// results until it completes or ctx is cancelled.  Queries send a single
+// result.
+func (s ServerAndDB) runGraphQLOperation(ctx context.Context, id string, req GraphQLRequest, results chan<- GraphQLSocketMessage) {
+	send := func(msg GraphQLSocketMessage) bool {
+		select {
+		case results <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	next := func(res *graphql.Result) bool {
+		payload, err := json.Marshal(res)
+		if err != nil {
+			s.Logger.Error("failed to marshal graphql result", "error", err)
+			return false
+		}
+		return send(GraphQLSocketMessage{Type: "next", ID: id, Payload: payload})
+	}
+	complete := GraphQLSocketMessage{Type: "complete", ID: id}
+
+	op, errs := prepareGraphQL(req)
+	if errs != nil {
+		send(graphQLSocketError(id, errs))
+		return
+	}
+	if op == nil || op.Operation != ast.OperationTypeSubscription {
+		if next(s.executeGraphQL(ctx, req)) {
+			send(complete)
+		}
+		return
+	}
+
+	stream := graphql.Subscribe(graphql.Params{
+		Schema:         *s.graphQL,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+	// the executor blocks sending results, drain it so it sees ctx is
+	// done and exits.
+	defer func() {
+		for range stream {
+		}
+	}()
+	first := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case res, ok := <-stream:
+			if !ok {
+				send(complete)
+				return
+			}
+			// a subscription that fails to start, e.g. it doesn't
+			// validate, sends a single result with only errors.
+			if first && res.Data == nil && res.HasErrors() {
+				send(graphQLSocketError(id, res.Errors))
+				return
+			}
+			first = false
+			if !next(res) {
+				return
+			}
+		}
+	}
+}
+
+func graphQLSocketError(id string, errs []gqlerrors.FormattedError) GraphQLSocketMessage {
+	payload, _ := json.Marshal(errs)
+	return GraphQLSocketMessage{Type: "error", ID: id, Payload: payload}
+}
+
+// readGraphQLMessages reads client messages until the connection fails,
+// then closes requests.  Bad json is passed on as a message without a
+// type, which closes the connection as the protocol requires.
+func readGraphQLMessages(conn *websocket.Conn, requests chan<- GraphQLSocketMessage, done <-chan struct{}) {
+	defer close(requests)
+	conn.SetReadLimit(graphQLMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+	for {
+		var msg GraphQLSocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			msg = GraphQLSocketMessage{}
+		}
+		select {
+		case requests <- msg:
+		case <-done:
+			return
+		}
+	}
+}
+
+func closeGraphQLSocket(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(socketWriteWait))
+}
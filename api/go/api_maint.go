@@ -7,5 +7,5 @@ import (
 )
 
 func (s ServerAndDB) AddReport(c echo.Context) error {
-	return c.String(http.StatusOK, "Working")
+	return c.JSON(http.StatusOK, MessageResponse{Message: "Working"})
 }
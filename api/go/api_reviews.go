@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/quality"
+)
+
+const defaultReviewLimit = 100
+
+// ListReviews returns flagged reports by review status, pending reports
+// are the ones waiting for a review.
+func (s ServerAndDB) ListReviews(c echo.Context) error {
+	f := ReportFilter{Review: ReviewPending, Limit: defaultReviewLimit}
+	switch v := c.QueryParam("status"); v {
+	case "":
+	case ReviewPending, ReviewAccepted, ReviewRejected:
+		f.Review = v
+	default:
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "status must be pending, accepted or rejected"})
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxReportLimit {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: fmt.Sprintf("limit must be between 1 and %d", maxReportLimit)})
+		}
+		f.Limit = n
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "offset must be zero or greater"})
+		}
+		f.Offset = n
+	}
+
+	ctx := c.Request().Context()
+	rpts, err := QueryReports(ctx, s.Conn, f)
+	if err != nil {
+		s.Logger.Error("failed to query review queue", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	flags, err := reportFlags(ctx, s.Conn, rpts)
+	if err != nil {
+		s.Logger.Error("failed to query report flags", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+
+	queue := ReviewQueue{Status: f.Review, Reports: []FlaggedReport{}}
+	for i, r := range dbToReportModel(rpts).Reports {
+		queue.Reports = append(queue.Reports, FlaggedReport{Report: r, Flags: flags[rpts[i].ID]})
+	}
+	setUsageRows(c, len(rpts))
+	return c.JSON(http.StatusOK, queue)
+}
+
+// ReviewReport accepts or rejects a report.  Reviewing a report again
+// replaces the earlier review.
+func (s ServerAndDB) ReviewReport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "report id must be a number"})
+	}
+	var req ReviewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "request body must be a review"})
+	}
+	if req.Status != ReviewAccepted && req.Status != ReviewRejected {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "status must be accepted or rejected"})
+	}
+
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	review := Review{ReportID: id, Status: req.Status, Note: req.Note, KeyID: keyID}
+	ctx := c.Request().Context()
+	err = pgx.BeginFunc(ctx, s.Conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+insert into report_reviews (report_id, status, key_id, note)
+select id, $2, $3, $4 from reports where id = $1
+on conflict (report_id) do update
+    set status = excluded.status, key_id = excluded.key_id, note = excluded.note, reviewed_at = now()
+returning reviewed_at`, id, req.Status, keyID, req.Note).Scan(&review.ReviewedAt)
+		if err != nil {
+			return err
+		}
+		// rejected reports leave their storm event, and go back into one
+		// if the rejection is reversed.
+		if req.Status == ReviewRejected {
+			return events.Remove(ctx, tx, s.EventThresholds, id)
+		}
+		return events.Restore(ctx, tx, s.EventThresholds, id)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "report not found"})
+	}
+	if err != nil {
+		s.Logger.Error("failed to review report", "error", err, "report", id)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+
+	// a review can move a report in or out of any cached response.
+	s.Cache.purge()
+	return c.JSON(http.StatusOK, review)
+}
+
+// reportFlags returns the flags raised for each report, by report id.
+func reportFlags(ctx context.Context, db database.DBTX, rpts []StoredReport) (map[int64][]quality.Flag, error) {
+	ids := make([]int64, len(rpts))
+	for i, r := range rpts {
+		ids[i] = r.ID
+	}
+	rows, err := db.Query(ctx, `
+select report_id, flag, coalesce(detail, '')
+from report_flags
+where report_id = any($1)
+order by report_id, flag`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	flags := map[int64][]quality.Flag{}
+	for rows.Next() {
+		var id int64
+		var f quality.Flag
+		if err := rows.Scan(&id, &f.Name, &f.Detail); err != nil {
+			return nil, err
+		}
+		flags[id] = append(flags[id], f)
+	}
+	return flags, rows.Err()
+}
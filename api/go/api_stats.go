@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+)
+
+// statsGroups maps each group-by value to the sql it groups on.  Days are
+// convective days, which run from 12Z to 12Z and are named for the day
+// they start on, the same as SPC's daily reports.
+var statsGroups = map[string]string{
+	"type":      "rpt_type::text",
+	"state":     `"state"`,
+	"county":    "county",
+	"office":    "nws_office",
+	"day":       "to_char((reported_time at time zone 'UTC') - interval '12 hours', 'YYYY-MM-DD')",
+	"week":      "to_char(reported_time at time zone 'UTC', 'IYYY-\"W\"IW')",
+	"month":     "to_char(reported_time at time zone 'UTC', 'YYYY-MM')",
+	"year":      "to_char(reported_time at time zone 'UTC', 'YYYY')",
+	"magnitude": magnitudeBucketSQL,
+}
+
+// magnitudeBucketSQL buckets var_col by report type, hail in inches, wind
+// in mph from the severe threshold up, and tornadoes by EF rating.  Hail
+// and wind stored as 0 are unknown.
+const magnitudeBucketSQL = `case
+    when var_col is null or (rpt_type in ('hail', 'wind') and var_col = 0) then 'unknown'
+    when rpt_type = 'hail' and var_col < 100 then '<1in'
+    when rpt_type = 'hail' and var_col < 200 then '1-1.99in'
+    when rpt_type = 'hail' and var_col < 300 then '2-2.99in'
+    when rpt_type = 'hail' then '>=3in'
+    when rpt_type = 'wind' and var_col < 58 then '<58mph'
+    when rpt_type = 'wind' and var_col < 75 then '58-74mph'
+    when rpt_type = 'wind' and var_col < 100 then '75-99mph'
+    when rpt_type = 'wind' then '>=100mph'
+    else 'EF' || var_col
+end`
+
+const defaultStatsGroup = "type"
+
+// GetStatsCounts counts the reports matching the report filters, grouped
+// by the group-by columns, as json or csv.
+func (s ServerAndDB) GetStatsCounts(c echo.Context) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	groups, errResponse := parseStatsGroups(c.QueryParam("group-by"))
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != "csv" {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "format must be json or csv"})
+	}
+
+	stats, err := QueryStatsCounts(c.Request().Context(), s.Conn, f, groups)
+	if err != nil {
+		s.Logger.Error("failed to query report counts", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	setUsageRows(c, len(stats.Counts))
+
+	if format != "csv" {
+		return c.JSON(http.StatusOK, stats)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="report_counts.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+	w := csv.NewWriter(c.Response())
+	_ = w.Write(append(slices.Clone(groups), "count"))
+	for _, row := range stats.Counts {
+		record := make([]string, 0, len(groups)+1)
+		for _, g := range groups {
+			v, _ := row[g].(string)
+			record = append(record, v)
+		}
+		record = append(record, strconv.FormatInt(row["count"].(int64), 10))
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func parseStatsGroups(v string) ([]string, ApiResponse) {
+	if v == "" {
+		return []string{defaultStatsGroup}, ApiResponse{}
+	}
+	var groups []string
+	for _, g := range strings.Split(v, ",") {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if _, ok := statsGroups[g]; !ok {
+			return nil, ApiResponse{Code: 400, Message: fmt.Sprintf("group-by %q not valid, use type, state, county, office, day, week, month, year or magnitude", g)}
+		}
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, ApiResponse{}
+}
+
+// QueryStatsCounts counts the reports matching the filter grouped by
+// groups, ordered by the groups.  The filter's limit and offset page
+// through the groups.
+func QueryStatsCounts(ctx context.Context, db database.DBTX, f ReportFilter, groups []string) (StatsCounts, error) {
+	cols := make([]string, len(groups))
+	positions := make([]string, len(groups))
+	for i, g := range groups {
+		cols[i] = statsGroups[g]
+		positions[i] = strconv.Itoa(i + 1)
+	}
+	where, args := f.where()
+	query := "select " + strings.Join(cols, ",\n       ") + ",\n       count(*),\n       sum(count(*)) over ()::bigint" +
+		"\nfrom reports\nwhere " + where +
+		"\ngroup by " + strings.Join(positions, ", ") +
+		"\norder by " + strings.Join(positions, ", ")
+	if f.Limit > 0 {
+		query += " limit " + strconv.Itoa(f.Limit)
+	}
+	if f.Offset > 0 {
+		query += " offset " + strconv.Itoa(f.Offset)
+	}
+
+	stats := StatsCounts{GroupBy: groups, Counts: []map[string]any{}}
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values := make([]*string, len(groups))
+		var count int64
+		dest := make([]any, 0, len(groups)+2)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &count, &stats.Total)
+		if err := rows.Scan(dest...); err != nil {
+			return stats, err
+		}
+		row := map[string]any{"count": count}
+		for i, g := range groups {
+			if values[i] != nil {
+				row[g] = *values[i]
+			} else {
+				row[g] = nil
+			}
+		}
+		stats.Counts = append(stats.Counts, row)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseStatsGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		want     []string
+		wantCode int32
+	}{
+		{
+			name:  "should group by type by default",
+			value: "",
+			want:  []string{"type"},
+		},
+		{
+			name:  "should keep the order given and drop repeats",
+			value: "month, State,month",
+			want:  []string{"month", "state"},
+		},
+		{
+			name:     "should reject an unknown column",
+			value:    "state,planet",
+			wantCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errResponse := parseStatsGroups(tt.value)
+			assert.Equal(t, tt.wantCode, errResponse.Code)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
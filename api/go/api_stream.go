@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+const (
+	streamBuffer         = 256
+	streamHeartbeat      = 15 * time.Second
+	streamReplayMaxCount = maxReportLimit
+)
+
+// StreamReports pushes reports to the client as Server-Sent Events as
+// soon as the consumer stores them.  It accepts the same filters as the
+// report endpoints and resumes from the Last-Event-ID header.
+func (s ServerAndDB) StreamReports(c echo.Context) error {
+	if s.Broker == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "streaming is not enabled"})
+	}
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+
+	var lastID int64
+	if v := c.Request().Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "Last-Event-ID must be an id sent by this stream"})
+		}
+		lastID = id
+	}
+
+	sub, missed, complete := s.Broker.SubscribeSince(streamBuffer, lastID)
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	// stop proxies from buffering the stream.
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	var sent int
+	defer func() { setUsageRows(c, sent) }()
+	send := func(msg pubsub.Message) error {
+		if msg.ID <= lastID {
+			return nil
+		}
+		if err := writeReportEvent(res, msg); err != nil {
+			return err
+		}
+		lastID = msg.ID
+		sent++
+		return nil
+	}
+
+	// the client has been gone longer than the broker remembers, catch
+	// it up from the database first.
+	if !complete {
+		replay := f
+		replay.AfterID = lastID
+		replay.Limit = streamReplayMaxCount
+		rpts, err := QueryReports(c.Request().Context(), s.Conn, replay)
+		if err != nil {
+			s.Logger.Error("failed to replay reports for stream", "error", err)
+			return nil
+		}
+		// the query has applied the filter.
+		for _, r := range rpts {
+			if err := send(pubsub.Message{
+				ID:     r.ID,
+				Report: database.InsertReportParams(r.Report),
+				Flags:  r.Flags,
+			}); err != nil {
+				return nil
+			}
+		}
+		// there is more to catch up on than one replay sends, end the
+		// stream so the client reconnects from the last id it got.
+		if len(rpts) == streamReplayMaxCount {
+			_, _ = fmt.Fprintf(res, "event: truncated\ndata: {\"last_id\":%d}\n\n", lastID)
+			res.Flush()
+			return nil
+		}
+	}
+	for _, msg := range missed {
+		if !f.MatchesMessage(msg) {
+			continue
+		}
+		if err := send(msg); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case msg, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if !f.MatchesMessage(msg) {
+				continue
+			}
+			if err := send(msg); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// writeReportEvent writes a single report event, the data is the same
+// report model the rest endpoints return.
+func writeReportEvent(w http.ResponseWriter, msg pubsub.Message) error {
+	data, err := json.Marshal(messageToReport(msg))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: report\ndata: %s\n\n", msg.ID, data)
+	return err
+}
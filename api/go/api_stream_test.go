@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+func TestStreamReports_resume(t *testing.T) {
+	broker := pubsub.NewBroker(16)
+	s := NewRouter(RouterConfig{
+		ROKey:  "rokey",
+		RWKey:  "rwkey",
+		Broker: broker,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	srv := httptest.NewServer(s.Web)
+	defer srv.Close()
+
+	// reports stored together share a created_at, the id tells them apart.
+	stored := time.Date(2024, 5, 6, 21, 0, 0, 0, time.UTC)
+	var id int64
+	publish := func(typ database.ReportType) pubsub.Message {
+		id++
+		msg := pubsub.Message{ID: id, Report: database.InsertReportParams{
+			RptType:   typ,
+			CreatedAt: pgtype.Timestamptz{Time: stored, Valid: true},
+		}}
+		broker.Publish(msg)
+		return msg
+	}
+	seen := publish(database.ReportTypeHail)
+	publish(database.ReportTypeWind)
+	missedHail := publish(database.ReportTypeHail)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/stream/reports?type=hail", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Api-Key", "rokey")
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(seen.ID, 10))
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	lines := bufio.NewScanner(res.Body)
+	nextID := func() string {
+		t.Helper()
+		for lines.Scan() {
+			if id, ok := strings.CutPrefix(lines.Text(), "id: "); ok {
+				return id
+			}
+		}
+		require.NoError(t, lines.Err())
+		return ""
+	}
+
+	// the wind report doesn't match, the hail report after the last id
+	// is sent from the history.
+	assert.Equal(t, strconv.FormatInt(missedHail.ID, 10), nextID())
+
+	// then reports are sent as they are stored.
+	live := publish(database.ReportTypeHail)
+	assert.Equal(t, strconv.FormatInt(live.ID, 10), nextID())
+}
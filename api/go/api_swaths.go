@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+)
+
+// GetSwaths returns the likely hail and wind swaths of a convective day
+// as GeoJSON polygons, one per cluster of reports.
+func (s ServerAndDB) GetSwaths(c echo.Context) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), "")
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	badRequest := func(format string, a ...any) error {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: fmt.Sprintf(format, a...)})
+	}
+	day, err := time.Parse(time.DateOnly, c.QueryParam("date"))
+	if err != nil {
+		return badRequest("date is required, the convective day as YYYY-MM-DD")
+	}
+	// the convective day runs 12Z to 12Z.
+	f.From = day.Add(12 * time.Hour)
+	f.To = f.From.Add(24 * time.Hour)
+	if len(f.Types) == 0 {
+		f.Types = []database.ReportType{database.ReportTypeHail, database.ReportTypeWind}
+	} else if slices.Contains(f.Types, database.ReportTypeTornado) {
+		return badRequest("type must be hail or wind, tornadoes have tracks")
+	}
+
+	buffer := float64(defaultSwathBuffer)
+	if v := c.QueryParam("buffer"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < minSwathBuffer || n > maxSwathBuffer {
+			return badRequest("buffer must be between %g and %d miles", minSwathBuffer, maxSwathBuffer)
+		}
+		buffer = n
+	}
+	t := s.EventThresholds
+	if v := c.QueryParam("radius"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n <= 0 || n > maxSwathRadius {
+			return badRequest("radius must be more than 0 and at most %d miles", maxSwathRadius)
+		}
+		t.RadiusMiles = n
+	}
+	if v := c.QueryParam("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSwathWindow {
+			return badRequest("window must be between 1 and %d minutes", maxSwathWindow)
+		}
+		t.Window = time.Duration(n) * time.Minute
+	}
+
+	rows, err := QueryReports(c.Request().Context(), s.Conn, f)
+	if err != nil {
+		s.Logger.Error("failed to query swath reports", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	setUsageRows(c, len(rows))
+	return geoJSON(c, swathFeatureCollection(buildSwaths(rows, t, buffer)))
+}
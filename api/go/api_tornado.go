@@ -1,57 +1,46 @@
 package api
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/events"
 )
 
 func (s ServerAndDB) GetTornadoReports(c echo.Context) error {
-	qp := c.QueryParams()
+	return s.serveReports(c, database.ReportTypeTornado, func(rpts []StoredReport) any {
+		return dbToReportModel(rpts).ToTornadoReports()
+	}, func(r StoredReport) any {
+		return dbToReportModel([]StoredReport{r}).ToTornadoReports().Reports[0]
+	})
+}
 
-	// s.DB.GetAllTornadoReports()
-	if len(qp) == 0 {
-		dbReports, errResp := s.DB.GetReportsByType(c.Request().Context(), database.ReportTypeTornado)
-		if errResp != nil {
-			return c.JSON(500, "error makin query to database")
-		}
-		return c.JSON(200, dbToReportModel(dbReports).ToTornadoReports())
+// GetTornadoTracks returns the tornado reports matching the filters linked
+// into tracks, as GeoJSON LineStrings.
+func (s ServerAndDB) GetTornadoTracks(c echo.Context) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), database.ReportTypeTornado)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
 	}
-
-	if val, ok := qp["date"]; ok {
-		dbReports, errResponse := s.getReportsByDateAndType(c, val[0], database.ReportTypeTornado)
-		if errResponse.Code > 0 {
-			return c.JSON(int(errResponse.Code), errResponse)
-		}
-		return c.JSON(200, dbToReportModel(dbReports).ToTornadoReports())
+	if f.From.IsZero() {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "date or from-date is required"})
 	}
-	var qState, qCounty string
-	if val, ok := qp["state"]; ok {
-		qState = val[0]
+	to := f.To
+	if to.IsZero() {
+		to = time.Now()
 	}
-
-	if val, ok := qp["county"]; ok {
-		qCounty = val[0]
+	if to.Sub(f.From) > maxTrackRange {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "tracks can be built for at most 31 days at a time"})
 	}
 
-	if qState != "" && qCounty != "" {
-		dbReports, errResponse := s.getReportsByTypeByCountyAndState(c, qCounty, qState, database.ReportTypeTornado)
-		if errResponse.Code > 0 {
-			return c.JSON(int(errResponse.Code), errResponse)
-		}
-		return c.JSON(200, dbToReportModel(dbReports).ToTornadoReports())
+	rows, err := QueryReports(c.Request().Context(), s.Conn, f)
+	if err != nil {
+		s.Logger.Error("failed to query tornado reports for tracks", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
 	}
-
-	if qState != "" {
-		dbReports, errResponse := s.getReportsByTypeByState(c, qState, database.ReportTypeTornado)
-		if errResponse.Code > 0 {
-			return c.JSON(int(errResponse.Code), errResponse)
-		}
-		return c.JSON(200, dbToReportModel(dbReports).ToTornadoReports())
-	}
-
-	return c.JSON(400, ApiResponse{
-		Code:    400,
-		Message: "valid query params are state, county, location, and date",
-	})
-
+	setUsageRows(c, len(rows))
+	return geoJSON(c, trackFeatureCollection(buildTracks(rows, events.DefaultTrackThresholds)))
 }
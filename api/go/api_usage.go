@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultUsageDays = 30
+
+// GetAccountUsage returns the hourly usage of the api key making the request.
+func (s ServerAndDB) GetAccountUsage(c echo.Context) error {
+	if s.Usage == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "usage accounting is not enabled"})
+	}
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+
+	from, to, errResponse := parseUsageRange(c)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+
+	rollups, err := s.Usage.Rollups(c.Request().Context(), keyID, from, to)
+	if err != nil {
+		s.Logger.Error("failed to query usage", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	return c.JSON(200, AccountUsage{
+		KeyID: keyID,
+		From:  from,
+		To:    to,
+		Usage: rollups,
+	})
+}
+
+// ExportUsage writes the hourly usage for any key, or all keys when
+// key_id is omitted, as CSV.
+func (s ServerAndDB) ExportUsage(c echo.Context) error {
+	if s.Usage == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "usage accounting is not enabled"})
+	}
+
+	from, to, errResponse := parseUsageRange(c)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+
+	rollups, err := s.Usage.Rollups(c.Request().Context(), c.QueryParam("key_id"), from, to)
+	if err != nil {
+		s.Logger.Error("failed to query usage", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+
+	filename := fmt.Sprintf("usage_%s_%s.csv", from.Format(time.DateOnly), to.Add(-time.Nanosecond).Format(time.DateOnly))
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	return writeUsageCSV(c.Response(), rollups)
+}
+
+// writeUsageCSV writes the rollups as CSV with a header row.
+func writeUsageCSV(out io.Writer, rollups []UsageRollup) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"key_id", "hour", "route", "requests", "rows", "bytes", "avg_latency_ms", "max_latency_ms"})
+	for _, r := range rollups {
+		_ = w.Write([]string{
+			r.KeyID,
+			r.Hour.UTC().Format(time.RFC3339),
+			r.Route,
+			strconv.FormatInt(r.Requests, 10),
+			strconv.FormatInt(r.Rows, 10),
+			strconv.FormatInt(r.Bytes, 10),
+			strconv.FormatFloat(r.AvgLatencyMs, 'f', 3, 64),
+			strconv.FormatFloat(r.MaxLatencyMs, 'f', 3, 64),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// parseUsageRange reads the from and to query params, both YYYY-MM-DD and
+// inclusive, and returns them as a half open [from, to) range.  Without
+// params the last 30 days are returned.
+func parseUsageRange(c echo.Context) (time.Time, time.Time, ApiResponse) {
+	to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	from := to.AddDate(0, 0, -defaultUsageDays)
+
+	if v := c.QueryParam("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return from, to, ApiResponse{Code: 400, Message: "from value not valid format, use YYYY-MM-DD"}
+		}
+		from = t
+	}
+	if v := c.QueryParam("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return from, to, ApiResponse{Code: 400, Message: "to value not valid format, use YYYY-MM-DD"}
+		}
+		to = t.Add(24 * time.Hour)
+	}
+	if !from.Before(to) {
+		return from, to, ApiResponse{Code: 400, Message: "from must be on or before to"}
+	}
+	return from, to, ApiResponse{}
+}
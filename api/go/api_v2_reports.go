@@ -0,0 +1,145 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+)
+
+// Unit systems accepted by the units param of the v2 report endpoints.
+const (
+	UnitsImperial = "imperial"
+	UnitsMetric   = "metric"
+)
+
+const (
+	mmPerHundredthInch = 0.254
+	kmPerMile          = 1.609344
+)
+
+// GetAllReportsV2 returns reports of every type with typed magnitudes.
+func (s ServerAndDB) GetAllReportsV2(c echo.Context) error {
+	return s.serveReportsV2(c, "")
+}
+
+// GetHailReportsV2 returns hail reports with typed magnitudes.
+func (s ServerAndDB) GetHailReportsV2(c echo.Context) error {
+	return s.serveReportsV2(c, database.ReportTypeHail)
+}
+
+// GetWindReportsV2 returns wind reports with typed magnitudes.
+func (s ServerAndDB) GetWindReportsV2(c echo.Context) error {
+	return s.serveReportsV2(c, database.ReportTypeWind)
+}
+
+// GetTornadoReportsV2 returns tornado reports with typed magnitudes.
+func (s ServerAndDB) GetTornadoReportsV2(c echo.Context) error {
+	return s.serveReportsV2(c, database.ReportTypeTornado)
+}
+
+// serveReportsV2 serves the v2 report endpoints, they take the v1 filters
+// plus units.
+func (s ServerAndDB) serveReportsV2(c echo.Context, reportType database.ReportType) error {
+	units := strings.ToLower(c.QueryParam("units"))
+	switch units {
+	case "":
+		units = UnitsImperial
+	case UnitsImperial, UnitsMetric:
+	default:
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "units must be imperial or metric"})
+	}
+	return s.serveReports(c, reportType, func(rpts []StoredReport) any {
+		return dbToReportV2Model(rpts, units)
+	}, func(r StoredReport) any {
+		return storedToReportV2(r, units)
+	})
+}
+
+func dbToReportV2Model(rpts []StoredReport, units string) ReportsV2 {
+	reports := ReportsV2{Units: units, Reports: make([]ReportV2, 0, len(rpts))}
+	for _, row := range rpts {
+		reports.Reports = append(reports.Reports, storedToReportV2(row, units))
+	}
+	return reports
+}
+
+// storedToReportV2 renders one stored report in the given units.
+func storedToReportV2(row StoredReport, units string) ReportV2 {
+	r := ReportV2{
+		ID:        row.ID,
+		Type:      string(row.RptType),
+		Time:      row.ReportedTime.Time,
+		Magnitude: magnitude(row.RptType, row.VarCol.Int32, row.VarCol.Valid, units),
+		Distance:  distance(row.DistFromLocation, units),
+		Direction: row.HeadingFromLocation,
+		Location:  row.Location,
+		County:    row.County,
+		State:     row.State.String,
+		Lat:       parseCoordinate(row.Latitude.String),
+		Lon:       parseCoordinate(row.Longitude.String),
+		Comments:  row.Comments.String,
+		Flags:     row.Flags,
+	}
+	if r.Flags == nil {
+		r.Flags = []string{}
+	}
+	if row.Measurement != "" {
+		r.Measurement = &row.Measurement
+	}
+	if row.NwsOffice.Valid && row.NwsOffice.String != "" {
+		r.Office = &row.NwsOffice.String
+	}
+	if row.Review != "" {
+		r.Review = &row.Review
+	}
+	return r
+}
+
+// magnitude converts a stored var_col, hundredths of an inch for hail,
+// mph for wind and the EF rating for tornadoes.  A hail size or wind speed
+// of 0 is unknown, older reports were stored with 0 for UNK.
+func magnitude(t database.ReportType, v int32, valid bool, units string) *Quantity {
+	if !valid || (v == 0 && t != database.ReportTypeTornado) {
+		return nil
+	}
+	switch t {
+	case database.ReportTypeHail:
+		if units == UnitsMetric {
+			return &Quantity{Value: round(float64(v)*mmPerHundredthInch, 1), Unit: "mm"}
+		}
+		return &Quantity{Value: float64(v) / 100, Unit: "in"}
+	case database.ReportTypeWind:
+		if units == UnitsMetric {
+			return &Quantity{Value: round(float64(v)*kmPerMile, 1), Unit: "km/h"}
+		}
+		return &Quantity{Value: float64(v), Unit: "mph"}
+	case database.ReportTypeTornado:
+		return &Quantity{Value: float64(v), Unit: "EF"}
+	}
+	return nil
+}
+
+// distance converts a stored distance in miles.
+func distance(miles int32, units string) Quantity {
+	if units == UnitsMetric {
+		return Quantity{Value: round(float64(miles)*kmPerMile, 1), Unit: "km"}
+	}
+	return Quantity{Value: float64(miles), Unit: "mi"}
+}
+
+func parseCoordinate(v string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
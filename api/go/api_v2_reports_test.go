@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dbToReportV2Model(t *testing.T) {
+	reported := time.Date(2024, 5, 9, 21, 30, 0, 0, time.UTC)
+	row := func(rt database.ReportType, v pgtype.Int4) StoredReport {
+		return StoredReport{
+			ID: 7,
+			Report: database.Report{
+				RptType:          rt,
+				ReportedTime:     pgtype.Timestamptz{Time: reported, Valid: true},
+				VarCol:           v,
+				DistFromLocation: 2,
+				State:            pgtype.Text{String: "FL", Valid: true},
+				Latitude:         pgtype.Text{String: "30.35", Valid: true},
+				Longitude:        pgtype.Text{String: "UNK", Valid: true},
+			},
+		}
+	}
+	lat := 30.35
+	tests := []struct {
+		name  string
+		row   StoredReport
+		units string
+		want  *Quantity
+		dist  Quantity
+	}{
+		{
+			name:  "should report hail in inches",
+			row:   row(database.ReportTypeHail, pgtype.Int4{Int32: 175, Valid: true}),
+			units: UnitsImperial,
+			want:  &Quantity{Value: 1.75, Unit: "in"},
+			dist:  Quantity{Value: 2, Unit: "mi"},
+		},
+		{
+			name:  "should convert hail to millimetres",
+			row:   row(database.ReportTypeHail, pgtype.Int4{Int32: 175, Valid: true}),
+			units: UnitsMetric,
+			want:  &Quantity{Value: 44.5, Unit: "mm"},
+			dist:  Quantity{Value: 3.2, Unit: "km"},
+		},
+		{
+			name:  "should convert wind to km/h",
+			row:   row(database.ReportTypeWind, pgtype.Int4{Int32: 60, Valid: true}),
+			units: UnitsMetric,
+			want:  &Quantity{Value: 96.6, Unit: "km/h"},
+			dist:  Quantity{Value: 3.2, Unit: "km"},
+		},
+		{
+			name:  "should give a hail size of 0 as unknown",
+			row:   row(database.ReportTypeHail, pgtype.Int4{Int32: 0, Valid: true}),
+			units: UnitsMetric,
+			want:  nil,
+			dist:  Quantity{Value: 3.2, Unit: "km"},
+		},
+		{
+			name:  "should give a wind speed of 0 as unknown",
+			row:   row(database.ReportTypeWind, pgtype.Int4{Int32: 0, Valid: true}),
+			units: UnitsImperial,
+			want:  nil,
+			dist:  Quantity{Value: 2, Unit: "mi"},
+		},
+		{
+			name:  "should keep tornadoes on the EF scale",
+			row:   row(database.ReportTypeTornado, pgtype.Int4{Int32: 2, Valid: true}),
+			units: UnitsMetric,
+			want:  &Quantity{Value: 2, Unit: "EF"},
+			dist:  Quantity{Value: 3.2, Unit: "km"},
+		},
+		{
+			name:  "should give a missing magnitude as null",
+			row:   row(database.ReportTypeHail, pgtype.Int4{}),
+			units: UnitsImperial,
+			want:  nil,
+			dist:  Quantity{Value: 2, Unit: "mi"},
+		},
+	}
+	spec, err := LoadSpec()
+	if err != nil {
+		t.Fatal("unable to load spec: ", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dbToReportV2Model([]StoredReport{tt.row}, tt.units)
+			assert.Equal(t, tt.units, got.Units)
+			r := got.Reports[0]
+			assert.Equal(t, tt.want, r.Magnitude)
+			assert.Equal(t, tt.dist, r.Distance)
+			assert.Equal(t, reported, r.Time)
+			assert.Equal(t, &lat, r.Lat)
+			assert.Nil(t, r.Lon)
+
+			// the rendered json has to be valid against the spec.
+			body, err := json.Marshal(got)
+			assert.NoError(t, err)
+			var v any
+			assert.NoError(t, json.Unmarshal(body, &v))
+			assert.NoError(t, spec.Components.Schemas["ReportsV2"].Value.VisitJSON(v))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/mvt"
+)
+
+// mvtContentType is the media type of a Mapbox Vector Tile.
+const mvtContentType = "application/vnd.mapbox-vector-tile"
+
+// GetReportTile returns the reports in slippy map tile z/x/y as a Mapbox
+// Vector Tile, clustered below reportClusterZoom.  The tiles are public
+// to caches so a CDN can sit in front of them, they vary by key.
+func (s ServerAndDB) GetReportTile(c echo.Context) error {
+	var reportType database.ReportType
+	switch t := c.Param("type"); t {
+	case "all":
+	case string(database.ReportTypeHail), string(database.ReportTypeWind), string(database.ReportTypeTornado):
+		reportType = database.ReportType(t)
+	default:
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "type must be all, hail, wind, or tornado"})
+	}
+	yParam, ok := strings.CutSuffix(c.Param("y"), ".mvt")
+	if !ok {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "tiles are only served as .mvt"})
+	}
+	z, x, y, errResponse := parseTile(c.Param("z"), c.Param("x"), yParam, MaxVectorTileZoom)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	f, errResponse := ParseReportFilter(c.QueryParams(), reportType)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	bbox := reportTileBBox(z, x, y)
+	f.BBox = &bbox
+
+	// the key is left out of the etag, the same tile is the same for
+	// every key.
+	qp := c.QueryParams()
+	qp.Del(apiKeyQueryParam)
+	count, lastModified, err := ReportsVersion(c.Request().Context(), s.Conn, f)
+	if err != nil {
+		s.Logger.Error("failed to query report version", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	etag := reportsETag(c.Request().URL.Path+"?"+qp.Encode(), count, lastModified)
+	setCacheHeaders(c, f, etag, lastModified)
+	h := c.Response().Header()
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheMaxAge(f, time.Now()).Seconds())))
+	h.Add(echo.HeaderVary, "X-Api-Key")
+	if notModified(c.Request(), etag, lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	var layer mvt.Layer
+	var reports int
+	if z < reportClusterZoom {
+		clusters, err := QueryTileClusters(c.Request().Context(), s.Conn, f, z, x, y)
+		if err != nil {
+			s.Logger.Error("failed to query tile clusters", "error", err)
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+		}
+		for _, cl := range clusters {
+			reports += cl.Count
+		}
+		layer = clusterTileLayer(clusters)
+	} else {
+		points, err := QueryTilePoints(c.Request().Context(), s.Conn, f)
+		if err != nil {
+			s.Logger.Error("failed to query tile points", "error", err)
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+		}
+		reports = len(points)
+		layer = reportTileLayer(points, z, x, y)
+	}
+	body, err := mvt.Tile{Layers: []mvt.Layer{layer}}.Marshal()
+	if err != nil {
+		return err
+	}
+	setUsageRows(c, reports)
+	return c.Blob(http.StatusOK, mvtContentType, body)
+}
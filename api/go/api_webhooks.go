@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+// CreateWebhook registers a webhook for the api key making the request.
+// The response holds the signing secret, it isn't returned again.
+func (s ServerAndDB) CreateWebhook(c echo.Context) error {
+	if s.Webhooks == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "webhooks are not enabled"})
+	}
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "request body must be a webhook"})
+	}
+	if errResponse := validateWebhookRequest(c.Request().Context(), req); errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.Logger.Error("failed to generate webhook secret", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "unable to create webhook"})
+	}
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	w, err := NewWebhookStore(s.Conn).Create(c.Request().Context(), keyID, req, secret)
+	if err != nil {
+		s.Logger.Error("failed to create webhook", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	s.Webhooks.Reload()
+	return c.JSON(http.StatusCreated, w)
+}
+
+// ListWebhooks returns the webhooks registered by the api key.
+func (s ServerAndDB) ListWebhooks(c echo.Context) error {
+	if s.Webhooks == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "webhooks are not enabled"})
+	}
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	hooks, err := NewWebhookStore(s.Conn).List(c.Request().Context(), keyID)
+	if err != nil {
+		s.Logger.Error("failed to list webhooks", "error", err)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	return c.JSON(http.StatusOK, hooks)
+}
+
+// GetWebhook returns one of the api key's webhooks.
+func (s ServerAndDB) GetWebhook(c echo.Context) error {
+	return s.withWebhook(c, func(store *WebhookStore, keyID string, id int64) (any, error) {
+		return store.Get(c.Request().Context(), keyID, id)
+	})
+}
+
+// DeleteWebhook removes one of the api key's webhooks.
+func (s ServerAndDB) DeleteWebhook(c echo.Context) error {
+	return s.withWebhook(c, func(store *WebhookStore, keyID string, id int64) (any, error) {
+		if err := store.Delete(c.Request().Context(), keyID, id); err != nil {
+			return nil, err
+		}
+		return MessageResponse{Message: "webhook deleted"}, nil
+	})
+}
+
+// EnableWebhook turns a webhook that was disabled after repeated failures
+// back on.
+func (s ServerAndDB) EnableWebhook(c echo.Context) error {
+	return s.withWebhook(c, func(store *WebhookStore, keyID string, id int64) (any, error) {
+		return store.Enable(c.Request().Context(), keyID, id)
+	})
+}
+
+// ListWebhookDeliveries returns the delivery log of one of the api key's
+// webhooks, newest first.
+func (s ServerAndDB) ListWebhookDeliveries(c echo.Context) error {
+	limit := defaultDeliveryLimit
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: fmt.Sprintf("limit must be between 1 and %d", maxDeliveryLimit)})
+		}
+		limit = n
+	}
+	return s.withWebhook(c, func(store *WebhookStore, keyID string, id int64) (any, error) {
+		return store.Deliveries(c.Request().Context(), keyID, id, limit)
+	})
+}
+
+// withWebhook parses the webhook id and renders the result of fn, mapping
+// errWebhookNotFound to a 404.
+func (s ServerAndDB) withWebhook(c echo.Context, fn func(store *WebhookStore, keyID string, id int64) (any, error)) error {
+	if s.Webhooks == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "webhooks are not enabled"})
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "webhook id must be a number"})
+	}
+	keyID, _ := c.Get(apiKeyIDContextKey).(string)
+	result, err := fn(NewWebhookStore(s.Conn), keyID, id)
+	if errors.Is(err, errWebhookNotFound) {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: err.Error()})
+	}
+	if err != nil {
+		s.Logger.Error("failed to query webhook", "error", err, "webhook", id)
+		return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+	}
+	// deleting, enabling or disabling changes what the dispatcher sends.
+	if c.Request().Method != http.MethodGet {
+		s.Webhooks.Reload()
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// lookupWebhookHost resolves a webhook url's host, tests replace it to
+// avoid dns.
+var lookupWebhookHost = net.DefaultResolver.LookupNetIP
+
+func validateWebhookRequest(ctx context.Context, req WebhookRequest) ApiResponse {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return ApiResponse{Code: 400, Message: "url must be an absolute http or https url"}
+	}
+	addrs, err := lookupWebhookHost(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return ApiResponse{Code: 400, Message: fmt.Sprintf("url host %q can't be resolved", u.Hostname())}
+	}
+	for _, ip := range addrs {
+		if !publicAddress(ip) {
+			return ApiResponse{Code: 400, Message: errWebhookAddress.Error()}
+		}
+	}
+	if len(req.Area) == 0 {
+		return ApiResponse{Code: 400, Message: "area is required"}
+	}
+	if _, err := geo.ParseGeoJSON(req.Area); err != nil {
+		return ApiResponse{Code: 400, Message: "area " + err.Error()}
+	}
+	for _, t := range req.Types {
+		if !slices.Contains([]string{"hail", "wind", "tornado"}, t) {
+			return ApiResponse{Code: 400, Message: fmt.Sprintf("type %q not valid, use hail, wind, or tornado", t)}
+		}
+	}
+	if req.MinHailSize != nil && *req.MinHailSize < 0 {
+		return ApiResponse{Code: 400, Message: "min_hail_size can't be negative"}
+	}
+	if req.MinWindSpeed != nil && *req.MinWindSpeed < 0 {
+		return ApiResponse{Code: 400, Message: "min_wind_speed can't be negative"}
+	}
+	return ApiResponse{}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+)
+
+const (
+	socketWriteWait        = 10 * time.Second
+	socketPongWait         = 60 * time.Second
+	socketPingInterval     = socketPongWait * 9 / 10
+	socketMaxMessageSize   = 4096
+	socketMaxSubscriptions = 16
+)
+
+// socketFilterParams are the report filter params a subscription accepts,
+// paging makes no sense for a live feed.
+var socketFilterParams = []string{
+	"type", "date", "from-date", "to-date", "state", "office", "county", "location",
+	"direction", "distance", "comments", "bbox", "measurement",
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// clients authenticate with an api key rather than cookies, so a
+	// cross-origin page can't ride on someone else's session.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ReportsSocket upgrades the request to a websocket over which the client
+// manages any number of filtered subscriptions and receives every newly
+// stored report that matches one of them.
+func (s ServerAndDB) ReportsSocket(c echo.Context) error {
+	if s.Broker == nil {
+		return c.JSON(http.StatusNotFound, ApiResponse{Code: 404, Message: "streaming is not enabled"})
+	}
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader has already written the error response.
+		return nil
+	}
+	defer conn.Close()
+
+	sub := s.Broker.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	requests := make(chan SocketRequest)
+	done := make(chan struct{})
+	defer close(done)
+	go readSocketRequests(conn, requests, done)
+
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+
+	subs := make(map[string]ReportFilter)
+	var sent int
+	var dropped int64
+	defer func() { setUsageRows(c, sent) }()
+	for {
+		var msg SocketMessage
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				return nil
+			}
+			msg = handleSocketRequest(subs, req)
+		case m, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			// let the client know it missed reports before sending the
+			// next one so it can refetch the gap over rest.
+			if d := sub.Dropped(); d != dropped {
+				if err := writeSocketMessage(conn, SocketMessage{Type: "dropped", Dropped: d - dropped}); err != nil {
+					return nil
+				}
+				dropped = d
+			}
+			ids := matchingSubscriptions(subs, m.Report)
+			if len(ids) == 0 {
+				continue
+			}
+			rpt := messageToReport(m)
+			msg = SocketMessage{Type: "report", Subscriptions: ids, Report: &rpt}
+			sent++
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait)); err != nil {
+				return nil
+			}
+			continue
+		}
+		if err := writeSocketMessage(conn, msg); err != nil {
+			return nil
+		}
+	}
+}
+
+// readSocketRequests reads client messages until the connection fails,
+// then closes requests.  Pongs extend the read deadline, a client that
+// stops answering pings is disconnected.
+func readSocketRequests(conn *websocket.Conn, requests chan<- SocketRequest, done <-chan struct{}) {
+	defer close(requests)
+	conn.SetReadLimit(socketMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+	for {
+		var req SocketRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			// tell the client about bad json rather than hanging up on it.
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			req = SocketRequest{}
+		}
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handleSocketRequest applies a client request to the connection's
+// subscriptions and returns the reply.
+func handleSocketRequest(subs map[string]ReportFilter, req SocketRequest) SocketMessage {
+	switch req.Action {
+	case "subscribe":
+		if req.ID == "" {
+			return SocketMessage{Type: "error", Message: "subscription id is required"}
+		}
+		if _, ok := subs[req.ID]; !ok && len(subs) >= socketMaxSubscriptions {
+			return SocketMessage{Type: "error", ID: req.ID, Message: fmt.Sprintf("a connection can have at most %d subscriptions", socketMaxSubscriptions)}
+		}
+		qp := make(url.Values, len(req.Filter))
+		for k, v := range req.Filter {
+			if !slices.Contains(socketFilterParams, k) {
+				return SocketMessage{Type: "error", ID: req.ID, Message: fmt.Sprintf("unknown filter param %q", k)}
+			}
+			qp.Set(k, v)
+		}
+		f, errResponse := ParseReportFilter(qp, "")
+		if errResponse.Code > 0 {
+			return SocketMessage{Type: "error", ID: req.ID, Message: errResponse.Message}
+		}
+		subs[req.ID] = f
+		return SocketMessage{Type: "subscribed", ID: req.ID}
+	case "unsubscribe":
+		if _, ok := subs[req.ID]; !ok {
+			return SocketMessage{Type: "error", ID: req.ID, Message: "no such subscription"}
+		}
+		delete(subs, req.ID)
+		return SocketMessage{Type: "unsubscribed", ID: req.ID}
+	case "":
+		return SocketMessage{Type: "error", Message: "messages must be json objects with an action"}
+	default:
+		return SocketMessage{Type: "error", ID: req.ID, Message: fmt.Sprintf("unknown action %q", req.Action)}
+	}
+}
+
+// matchingSubscriptions returns the ids of the subscriptions the report
+// matches, sorted so clients see a stable order.
+func matchingSubscriptions(subs map[string]ReportFilter, r database.InsertReportParams) []string {
+	var ids []string
+	for id, f := range subs {
+		if f.Matches(r) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func writeSocketMessage(conn *websocket.Conn, msg SocketMessage) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return conn.WriteJSON(msg)
+}
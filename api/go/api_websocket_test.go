@@ -0,0 +1,89 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+func TestReportsSocket(t *testing.T) {
+	broker := pubsub.NewBroker(0)
+	s := NewRouter(RouterConfig{
+		ROKey:             "rokey",
+		RWKey:             "rwkey",
+		Broker:            broker,
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ValidateResponses: true,
+	})
+	srv := httptest.NewServer(s.Web)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/ws/reports"
+
+	_, res, err := websocket.DefaultDialer.Dial(url+"?api_key=wrong", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?api_key=rokey", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	read := func() SocketMessage {
+		t.Helper()
+		var msg SocketMessage
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+	send := func(req SocketRequest) SocketMessage {
+		t.Helper()
+		require.NoError(t, conn.WriteJSON(req))
+		return read()
+	}
+	publish := func(typ database.ReportType, state string) {
+		broker.Publish(pubsub.Message{Report: database.InsertReportParams{
+			RptType: typ,
+			State:   pgtype.Text{String: state, Valid: true},
+		}})
+	}
+
+	assert.Equal(t, SocketMessage{Type: "subscribed", ID: "map"}, send(SocketRequest{Action: "subscribe", ID: "map", Filter: map[string]string{"type": "hail"}}))
+	assert.Equal(t, "error", send(SocketRequest{Action: "subscribe", ID: "bad", Filter: map[string]string{"limit": "10"}}).Type)
+	assert.Equal(t, "error", send(SocketRequest{Action: "subscribe", ID: "bad", Filter: map[string]string{"state": "Oklahoma"}}).Type)
+
+	publish(database.ReportTypeWind, "OK")
+	publish(database.ReportTypeHail, "OK")
+	msg := read()
+	assert.Equal(t, "report", msg.Type)
+	assert.Equal(t, []string{"map"}, msg.Subscriptions)
+	assert.Equal(t, "hail", msg.Report.Type)
+
+	// changing the filter replaces it.
+	assert.Equal(t, "subscribed", send(SocketRequest{Action: "subscribe", ID: "map", Filter: map[string]string{"type": "wind", "state": "tx"}}).Type)
+	assert.Equal(t, "subscribed", send(SocketRequest{Action: "subscribe", ID: "tx", Filter: map[string]string{"state": "TX"}}).Type)
+	publish(database.ReportTypeHail, "TX")
+	publish(database.ReportTypeWind, "TX")
+	msg = read()
+	assert.Equal(t, []string{"tx"}, msg.Subscriptions)
+	assert.Equal(t, "hail", msg.Report.Type)
+	msg = read()
+	assert.Equal(t, []string{"map", "tx"}, msg.Subscriptions)
+
+	assert.Equal(t, SocketMessage{Type: "unsubscribed", ID: "tx"}, send(SocketRequest{Action: "unsubscribe", ID: "tx"}))
+	assert.Equal(t, "error", send(SocketRequest{Action: "unsubscribe", ID: "tx"}).Type)
+	publish(database.ReportTypeHail, "TX")
+	publish(database.ReportTypeWind, "TX")
+	msg = read()
+	assert.Equal(t, []string{"map"}, msg.Subscriptions)
+	assert.Equal(t, "wind", msg.Report.Type)
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+const (
+	// closed history only changes when a report is corrected.
+	historyMaxAge = 24 * time.Hour
+	// ranges that include the current convective day get new reports
+	// throughout an outbreak.
+	liveMaxAge = time.Minute
+)
+
+// ResponseCache holds rendered report responses in memory.  Entries are
+// dropped when the consumer stores a report that matches their filter,
+// or once their ttl passes.
+type ResponseCache struct {
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+	maxEntries int
+	ttl        time.Duration
+	// generation is bumped on every invalidation so a response rendered
+	// from data that changed mid-request isn't stored.
+	generation uint64
+}
+
+type cacheEntry struct {
+	filter       ReportFilter
+	body         []byte
+	etag         string
+	lastModified time.Time
+	rows         int
+	expires      time.Time
+}
+
+// NewResponseCache creates a cache holding at most maxEntries responses
+// for up to ttl each.
+func NewResponseCache(maxEntries int, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		entries:    make(map[string]*cacheEntry),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+	}
+}
+
+// Run invalidates entries as reports are published until ctx is done.
+func (rc *ResponseCache) Run(ctx context.Context, sub *pubsub.Subscription) {
+	defer sub.Close()
+	var dropped int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			// if we missed a message we can't know what it touched.
+			if d := sub.Dropped(); d != dropped {
+				dropped = d
+				rc.purge()
+				continue
+			}
+			rc.invalidate(msg.Report)
+		}
+	}
+}
+
+func (rc *ResponseCache) get(key string) (*cacheEntry, uint64, bool) {
+	if rc == nil {
+		return nil, 0, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	e, ok := rc.entries[key]
+	if ok && time.Now().After(e.expires) {
+		delete(rc.entries, key)
+		ok = false
+	}
+	return e, rc.generation, ok
+}
+
+// set stores the entry unless the cache was invalidated after generation
+// was read.
+func (rc *ResponseCache) set(key string, generation uint64, e *cacheEntry) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if generation != rc.generation {
+		return
+	}
+
+	now := time.Now()
+	if len(rc.entries) >= rc.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, v := range rc.entries {
+			if now.After(v.expires) {
+				delete(rc.entries, k)
+				continue
+			}
+			if oldestKey == "" || v.expires.Before(oldest) {
+				oldestKey, oldest = k, v.expires
+			}
+		}
+		if len(rc.entries) >= rc.maxEntries {
+			delete(rc.entries, oldestKey)
+		}
+	}
+	e.expires = now.Add(rc.ttl)
+	rc.entries[key] = e
+}
+
+func (rc *ResponseCache) invalidate(r database.InsertReportParams) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	for k, e := range rc.entries {
+		if e.filter.Matches(r) {
+			delete(rc.entries, k)
+		}
+	}
+}
+
+func (rc *ResponseCache) purge() {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	clear(rc.entries)
+}
+
+// cacheKey identifies a response by route and normalised query.
+func cacheKey(c echo.Context) string {
+	return c.Path() + "?" + c.QueryParams().Encode()
+}
+
+// reportsETag derives a weak etag from the request and the version of
+// its result set.
+func reportsETag(key string, count int64, lastModified time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", key, count, lastModified.UnixMicro())))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// cacheControl returns the Cache-Control value for a filter.
+func cacheControl(f ReportFilter, now time.Time) string {
+	return fmt.Sprintf("private, max-age=%d", int(cacheMaxAge(f, now).Seconds()))
+}
+
+// cacheMaxAge returns how long a response for the filter stays fresh.
+// Ranges that end before the current convective day, which starts at 12Z,
+// are history and can be cached for a long time.
+func cacheMaxAge(f ReportFilter, now time.Time) time.Duration {
+	now = now.UTC()
+	dayStart := now.Truncate(24 * time.Hour).Add(12 * time.Hour)
+	if now.Before(dayStart) {
+		dayStart = dayStart.Add(-24 * time.Hour)
+	}
+	if !f.To.IsZero() && !f.To.After(dayStart) {
+		return historyMaxAge
+	}
+	return liveMaxAge
+}
+
+// setCacheHeaders sets the validators and Cache-Control for a response.
+func setCacheHeaders(c echo.Context, f ReportFilter, etag string, lastModified time.Time) {
+	h := c.Response().Header()
+	h.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		h.Set(echo.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+	}
+	h.Set("Cache-Control", cacheControl(f, time.Now()))
+}
+
+// notModified evaluates If-None-Match, and If-Modified-Since when there
+// is no If-None-Match, against the validators of the current response.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get(echo.HeaderIfModifiedSince); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache_invalidate(t *testing.T) {
+	rc := NewResponseCache(10, time.Minute)
+	_, gen, _ := rc.get("hail")
+	rc.set("hail", gen, &cacheEntry{filter: ReportFilter{Types: []database.ReportType{database.ReportTypeHail}}})
+	rc.set("ok", gen, &cacheEntry{filter: ReportFilter{State: "OK"}})
+
+	rc.invalidate(database.InsertReportParams{
+		RptType: database.ReportTypeHail,
+		State:   pgtype.Text{String: "TX", Valid: true},
+	})
+
+	_, _, ok := rc.get("hail")
+	assert.False(t, ok, "matching entry should be invalidated")
+	_, _, ok = rc.get("ok")
+	assert.True(t, ok, "entry for another state should be kept")
+
+	// a response rendered before the invalidation must not be stored.
+	rc.set("stale", gen, &cacheEntry{})
+	_, _, ok = rc.get("stale")
+	assert.False(t, ok)
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 5, 9, 18, 30, 15, 500, time.UTC)
+	etag := reportsETag("/api/v1/report/hail?", 10, lastModified)
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   bool
+	}{
+		{
+			name:   "should match the etag",
+			header: map[string]string{"If-None-Match": etag},
+			want:   true,
+		},
+		{
+			name:   "should match a strong form of the etag",
+			header: map[string]string{"If-None-Match": `"other", ` + etag[2:]},
+			want:   true,
+		},
+		{
+			name:   "should ignore If-Modified-Since when If-None-Match doesn't match",
+			header: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			want:   false,
+		},
+		{
+			name:   "should not be modified since the last report",
+			header: map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			want:   true,
+		},
+		{
+			name:   "should be modified after an earlier date",
+			header: map[string]string{"If-Modified-Since": lastModified.Add(-time.Minute).Format(http.TimeFormat)},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/report/hail", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, notModified(req, etag, lastModified))
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	now := time.Date(2024, 5, 9, 18, 0, 0, 0, time.UTC)
+	history := ReportFilter{To: time.Date(2024, 5, 9, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(t, "private, max-age=86400", cacheControl(history, now))
+	assert.Equal(t, "private, max-age=60", cacheControl(ReportFilter{}, now))
+	today := ReportFilter{To: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(t, "private, max-age=60", cacheControl(today, now))
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stormsync/database"
+)
+
+// Climatology periods.
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// notRejectedSQL leaves out reports rejected in review, they aren't
+// history.
+const notRejectedSQL = `not exists (select 1 from report_reviews rv where rv.report_id = reports.id and rv.status = 'rejected')`
+
+// Both baselines are computed from the history before this year, from the
+// day or month of the first report on, with the days or months a state
+// and type had no reports counted as zero.  Periods before the first
+// report aren't counted, so history starting mid-year doesn't pull the
+// earlier months towards zero.  Days are convective days, months are
+// calendar months in UTC.  $2 is the start from climatologySeriesStart.
+const (
+	computeDailyClimatology = `
+with history as (select "state", rpt_type::text as rpt_type, day
+                 from (select "state", rpt_type, ((reported_time at time zone 'UTC') - interval '12 hours')::date as day
+                       from reports
+                       where "state" is not null
+                         and ` + notRejectedSQL + `) r
+                 where day < date_trunc('year', $1::timestamptz at time zone 'UTC')),
+     days as (select d::date as day
+              from generate_series($2::timestamp,
+                                   date_trunc('year', $1::timestamptz at time zone 'UTC') - interval '1 day',
+                                   interval '1 day') d),
+     keys as (select distinct "state", rpt_type from history),
+     counts as (select "state", rpt_type, day, count(*) as n from history group by 1, 2, 3),
+     filled as (select k."state", k.rpt_type, days.day, coalesce(c.n, 0) as n
+                from keys k
+                         cross join days
+                         left join counts c on c."state" = k."state" and c.rpt_type = k.rpt_type and c.day = days.day),
+     stats as (select "state",
+                      rpt_type,
+                      extract(month from day)::int * 100 + extract(day from day)::int as period_key,
+                      count(*)                                                          as years,
+                      avg(n)                                                            as mean,
+                      coalesce(stddev_samp(n), 0)                                       as stddev,
+                      percentile_cont(array [0.1, 0.25, 0.5, 0.75, 0.9]) within group (order by n) as p
+               from filled
+               group by 1, 2, 3)
+insert into report_climatology (period, "state", rpt_type, period_key, years, mean, stddev, p10, p25, p50, p75, p90, computed_at)
+select 'day', "state", rpt_type, period_key, years, mean, stddev, p[1], p[2], p[3], p[4], p[5], $1
+from stats
+on conflict (period, "state", rpt_type, period_key) do update
+    set years       = excluded.years,
+        mean        = excluded.mean,
+        stddev      = excluded.stddev,
+        p10         = excluded.p10,
+        p25         = excluded.p25,
+        p50         = excluded.p50,
+        p75         = excluded.p75,
+        p90         = excluded.p90,
+        computed_at = excluded.computed_at`
+
+	computeMonthlyClimatology = `
+with history as (select "state", rpt_type::text as rpt_type, date_trunc('month', reported_time at time zone 'UTC') as month
+                 from reports
+                 where "state" is not null
+                   and reported_time < date_trunc('year', $1::timestamptz at time zone 'UTC') at time zone 'UTC'
+                   and ` + notRejectedSQL + `),
+     months as (select m as month
+                from generate_series($2::timestamp,
+                                     date_trunc('year', $1::timestamptz at time zone 'UTC') - interval '1 month',
+                                     interval '1 month') m),
+     keys as (select distinct "state", rpt_type from history),
+     counts as (select "state", rpt_type, month, count(*) as n from history group by 1, 2, 3),
+     filled as (select k."state", k.rpt_type, months.month, coalesce(c.n, 0) as n
+                from keys k
+                         cross join months
+                         left join counts c on c."state" = k."state" and c.rpt_type = k.rpt_type and c.month = months.month),
+     stats as (select "state",
+                      rpt_type,
+                      extract(month from month)::int as period_key,
+                      count(*)                       as years,
+                      avg(n)                         as mean,
+                      coalesce(stddev_samp(n), 0)    as stddev,
+                      percentile_cont(array [0.1, 0.25, 0.5, 0.75, 0.9]) within group (order by n) as p
+               from filled
+               group by 1, 2, 3)
+insert into report_climatology (period, "state", rpt_type, period_key, years, mean, stddev, p10, p25, p50, p75, p90, computed_at)
+select 'month', "state", rpt_type, period_key, years, mean, stddev, p[1], p[2], p[3], p[4], p[5], $1
+from stats
+on conflict (period, "state", rpt_type, period_key) do update
+    set years       = excluded.years,
+        mean        = excluded.mean,
+        stddev      = excluded.stddev,
+        p10         = excluded.p10,
+        p25         = excluded.p25,
+        p50         = excluded.p50,
+        p75         = excluded.p75,
+        p90         = excluded.p90,
+        computed_at = excluded.computed_at`
+
+	// firstReport is when the history the baselines are computed from
+	// starts.
+	firstReport = `
+select min(reported_time)
+from reports
+where "state" is not null
+  and reported_time < date_trunc('year', $1::timestamptz at time zone 'UTC') at time zone 'UTC'
+  and ` + notRejectedSQL
+
+	// rows that weren't recomputed belong to a state and type with no
+	// history left, e.g. after reports were rejected.
+	deleteStaleClimatology = `delete from report_climatology where computed_at < $1`
+
+	getClimatology = `
+select years, mean, stddev, p10, p25, p50, p75, p90, computed_at
+from report_climatology
+where period = $1
+  and "state" = $2
+  and rpt_type = $3
+  and period_key = $4`
+
+	countPeriodReports = `
+select count(*)
+from reports
+where "state" = $1
+  and rpt_type::text = $2
+  and reported_time >= $3
+  and reported_time < $4
+  and ` + notRejectedSQL
+)
+
+// Climatology keeps the report_climatology baselines up to date and
+// compares periods with them.
+type Climatology struct {
+	db     database.DBTX
+	logger *slog.Logger
+}
+
+// NewClimatology creates a climatology backed by db.  Run must be started
+// for the baselines to be computed.
+func NewClimatology(db database.DBTX, logger *slog.Logger) *Climatology {
+	return &Climatology{db: db, logger: logger}
+}
+
+// Run recomputes the baselines now and every interval until ctx is
+// cancelled.  The history only changes by a year at a time, reviews
+// aside, so a long interval is fine.
+func (cl *Climatology) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := cl.Compute(ctx); err != nil {
+			cl.logger.Error("failed to compute climatology", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compute recomputes every baseline from the stored history.
+func (cl *Climatology) Compute(ctx context.Context) error {
+	start := time.Now().UTC()
+	var first *time.Time
+	if err := cl.db.QueryRow(ctx, firstReport, start).Scan(&first); err != nil {
+		return err
+	}
+	// without history every baseline is stale.
+	if first != nil {
+		for period, q := range map[string]string{PeriodDay: computeDailyClimatology, PeriodMonth: computeMonthlyClimatology} {
+			if _, err := cl.db.Exec(ctx, q, start, climatologySeriesStart(period, *first)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := cl.db.Exec(ctx, deleteStaleClimatology, start)
+	cl.logger.Info("computed climatology", "took", time.Since(start))
+	return err
+}
+
+// Compare counts the state's reports of type rptType in the day or month
+// containing t and compares the count with its baseline.
+func (cl *Climatology) Compare(ctx context.Context, state string, rptType database.ReportType, period string, t time.Time) (ClimatologyComparison, error) {
+	start, end, key := climatologyPeriod(period, t)
+	cmp := ClimatologyComparison{
+		State:    state,
+		Type:     string(rptType),
+		Period:   period,
+		Start:    start,
+		End:      end,
+		Complete: !time.Now().Before(end),
+	}
+	if err := cl.db.QueryRow(ctx, countPeriodReports, state, string(rptType), start, end).Scan(&cmp.Count); err != nil {
+		return cmp, err
+	}
+
+	var b ClimatologyBaseline
+	err := cl.db.QueryRow(ctx, getClimatology, period, state, string(rptType), key).
+		Scan(&b.Years, &b.Mean, &b.StdDev, &b.P10, &b.P25, &b.P50, &b.P75, &b.P90, &b.ComputedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return cmp, nil
+	}
+	if err != nil {
+		return cmp, err
+	}
+	cmp.Baseline = &b
+	cmp.Anomaly = anomaly(cmp.Count, b)
+	return cmp, nil
+}
+
+// climatologyPeriod returns the bounds of the day or month containing t
+// and its period_key.  Days are convective days, 12Z to 12Z.
+func climatologyPeriod(period string, t time.Time) (start, end time.Time, key int) {
+	t = t.UTC()
+	if period == PeriodMonth {
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0), int(t.Month())
+	}
+	day := t.Add(-12 * time.Hour)
+	start = time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.UTC)
+	return start, start.Add(24 * time.Hour), int(day.Month())*100 + day.Day()
+}
+
+// climatologySeriesStart returns the day or month holding the first
+// report of the history as the midnight the baseline queries count from,
+// a convective day by its date.
+func climatologySeriesStart(period string, first time.Time) time.Time {
+	start, _, _ := climatologyPeriod(period, first)
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func anomaly(count int64, b ClimatologyBaseline) *Anomaly {
+	var a Anomaly
+	if b.StdDev > 0 {
+		z := round((float64(count)-b.Mean)/b.StdDev, 2)
+		a.ZScore = &z
+	}
+	if b.Mean > 0 {
+		r := round(float64(count)/b.Mean, 2)
+		a.Ratio = &r
+	}
+	return &a
+}
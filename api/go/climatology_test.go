@@ -0,0 +1,68 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_climatologyPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		period    string
+		t         time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantKey   int
+	}{
+		{
+			name:      "should use the calendar month",
+			period:    PeriodMonth,
+			t:         time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			wantKey:   5,
+		},
+		{
+			name:      "should start the convective day at 12Z",
+			period:    PeriodDay,
+			t:         time.Date(2024, 5, 9, 21, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 5, 9, 12, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+			wantKey:   509,
+		},
+		{
+			name:      "should put the early morning in the previous convective day",
+			period:    PeriodDay,
+			t:         time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			wantKey:   229,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, key := climatologyPeriod(tt.period, tt.t)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+			assert.Equal(t, tt.wantKey, key)
+		})
+	}
+}
+
+func Test_climatologySeriesStart(t *testing.T) {
+	// history that starts mid-year, the months and days before it aren't
+	// counted as zero.
+	first := time.Date(2019, 7, 15, 3, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC), climatologySeriesStart(PeriodMonth, first))
+	// before 12Z, so in the convective day of the 14th.
+	assert.Equal(t, time.Date(2019, 7, 14, 0, 0, 0, 0, time.UTC), climatologySeriesStart(PeriodDay, first))
+	assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), climatologySeriesStart(PeriodMonth, time.Date(2019, 1, 1, 18, 0, 0, 0, time.UTC)))
+}
+
+func Test_anomaly(t *testing.T) {
+	z, ratio := 2.0, 1.4
+	assert.Equal(t, &Anomaly{ZScore: &z, Ratio: &ratio}, anomaly(70, ClimatologyBaseline{Mean: 50, StdDev: 10}))
+	assert.Equal(t, &Anomaly{}, anomaly(3, ClimatologyBaseline{}))
+}
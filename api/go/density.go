@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// Cell shapes for density.
+const (
+	CellGrid = "grid"
+	CellHex  = "hex"
+)
+
+// magnitudeSQL is var_col with the old 0 for an unknown hail size or
+// wind speed treated as unknown.
+const magnitudeSQL = `(case when rpt_type in ('hail', 'wind') and var_col = 0 then null else var_col end)`
+
+// gridCellSQL and hexCellSQL bin the points of the density query, they
+// are the sql version of geo.GridCell and geo.HexCell.
+const (
+	gridCellSQL = `
+select floor(lon / %[1]s)::int as a, floor(lat / %[1]s)::int as b, rpt_type, mag
+from points`
+
+	hexCellSQL = `
+select (case when dq > dr and dq > ds then -rr - rs else rq end)::int as a,
+       (case when dq > dr and dq > ds then rr when dr > ds then -rq - rs else rr end)::int as b,
+       rpt_type,
+       mag
+from points
+         cross join lateral (select (sqrt(3) / 3 * lon - lat / 3) / %[1]s as fq, 2.0 / 3 * lat / %[1]s as fr) f
+         cross join lateral (select round(fq) as rq, round(fr) as rr, round(-fq - fr) as rs) r
+         cross join lateral (select abs(rq - fq) as dq, abs(rr - fr) as dr, abs(rs + fq + fr) as ds) d`
+)
+
+// QueryDensity bins the reports matching the filter into cells of the
+// given shape and size.  The filter's limit and offset are ignored.
+func QueryDensity(ctx context.Context, db database.DBTX, f ReportFilter, cell string, size float64) (Density, error) {
+	f.Limit, f.Offset = 0, 0
+	where, args := f.where()
+	args = append(args, size)
+	sizeArg := "$" + strconv.Itoa(len(args)) + "::double precision"
+
+	binSQL := gridCellSQL
+	if cell == CellHex {
+		binSQL = hexCellSQL
+	}
+	query := `
+with points as (select ` + latitudeSQL + ` as lat, ` + longitudeSQL + ` as lon, rpt_type::text as rpt_type, ` + magnitudeSQL + ` as mag
+                from reports
+                where ` + where + `),
+     cells as (` + fmt.Sprintf(binSQL, sizeArg) + `
+               where lat is not null and lon is not null)
+select a, b, rpt_type, count(*), max(mag)
+from cells
+group by 1, 2, 3
+order by 1, 2`
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return Density{}, err
+	}
+	defer rows.Close()
+
+	density := Density{Cell: cell, Size: size, Cells: []DensityCell{}}
+	byCell := map[[2]int]int{}
+	for rows.Next() {
+		var a, b int
+		var rptType string
+		var count int64
+		var mag *int32
+		if err := rows.Scan(&a, &b, &rptType, &count, &mag); err != nil {
+			return Density{}, err
+		}
+		i, ok := byCell[[2]int{a, b}]
+		if !ok {
+			c := DensityCell{ID: strconv.Itoa(a) + "/" + strconv.Itoa(b), MaxMagnitude: map[string]int32{}}
+			if cell == CellHex {
+				c.Center, c.outline = geo.HexCenter(a, b, size), geo.HexPolygon(a, b, size)
+			} else {
+				c.outline = geo.GridPolygon(a, b, size)
+				c.Center = geo.Point{Lon: (float64(a) + 0.5) * size, Lat: (float64(b) + 0.5) * size}
+			}
+			i = len(density.Cells)
+			byCell[[2]int{a, b}] = i
+			density.Cells = append(density.Cells, c)
+		}
+		density.Cells[i].Count += count
+		if mag != nil {
+			density.Cells[i].MaxMagnitude[rptType] = *mag
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Density{}, err
+	}
+	sort.SliceStable(density.Cells, func(i, j int) bool { return density.Cells[i].Count > density.Cells[j].Count })
+	return density, nil
+}
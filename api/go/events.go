@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/stormsync/database"
+)
+
+const defaultEventLimit = 100
+
+// QueryEvents returns the storm events matching the filter, newest
+// first.  Of the report filters only the types, times, state and bbox
+// apply, an event matches when its time span overlaps the times, one of
+// its reports is from the state and its centroid is in the bbox.
+// minReports leaves out events with fewer reports.
+func QueryEvents(ctx context.Context, db database.DBTX, f ReportFilter, minReports int) ([]StormEvent, error) {
+	var conds []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if len(f.Types) > 0 {
+		types := make([]string, len(f.Types))
+		for i, t := range f.Types {
+			types[i] = string(t)
+		}
+		conds = append(conds, "e.rpt_type = any("+arg(types)+")")
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "e.ended_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "e.started_at < "+arg(f.To))
+	}
+	if f.State != "" {
+		conds = append(conds, arg(f.State)+" = any(e.states)")
+	}
+	if f.BBox != nil {
+		conds = append(conds,
+			"e.latitude between "+arg(f.BBox.MinLat)+" and "+arg(f.BBox.MaxLat),
+			"e.longitude between "+arg(f.BBox.MinLon)+" and "+arg(f.BBox.MaxLon))
+	}
+	if minReports > 1 {
+		conds = append(conds, "e.reports >= "+arg(minReports))
+	}
+	where := "true"
+	if len(conds) > 0 {
+		where = strings.Join(conds, "\n  and ")
+	}
+	limit := f.Limit
+	if limit == 0 {
+		limit = defaultEventLimit
+	}
+
+	query := `
+select e.id, e.rpt_type, e.started_at, e.ended_at, e.longitude, e.latitude, e.max_magnitude, e.states,
+       array(select er.report_id from storm_event_reports er where er.event_id = e.id order by er.report_id)
+from storm_events e
+where ` + where + `
+order by e.started_at desc, e.id desc
+limit ` + strconv.Itoa(limit) + ` offset ` + strconv.Itoa(f.Offset)
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []StormEvent{}
+	for rows.Next() {
+		var e StormEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Start, &e.End, &e.Centroid.Lon, &e.Centroid.Lat, &e.MaxMagnitude, &e.States, &e.ReportIDs); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,484 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/shapefile"
+)
+
+// Export formats.
+const (
+	// ExportShapefile is a zip of a point Shapefile with typed columns.
+	ExportShapefile = "shapefile"
+	// ExportParquet is a zip of Parquet files partitioned by year and
+	// report type.
+	ExportParquet = "parquet"
+)
+
+// Export job statuses.
+const (
+	ExportPending  = "pending"
+	ExportRunning  = "running"
+	ExportComplete = "complete"
+	ExportFailed   = "failed"
+)
+
+const (
+	// exportTTL is how long a finished export can be downloaded.
+	exportTTL = 7 * 24 * time.Hour
+	// maxQueuedExports is how many unfinished exports a key may have.
+	maxQueuedExports = 5
+	// exportPollInterval is how often idle workers look for jobs another
+	// instance queued.
+	exportPollInterval = 10 * time.Second
+	// exportLease is how long a running job stays with the instance
+	// building it without a heartbeat, after that another instance can
+	// take it over.
+	exportLease = 2 * time.Minute
+	// exportHeartbeat is how often a running job's lease is renewed.
+	exportHeartbeat = 30 * time.Second
+)
+
+var (
+	// errExportNotFound is returned when an export doesn't exist, has
+	// expired, or belongs to another key.
+	errExportNotFound = errors.New("export not found")
+	// errTooManyExports is returned when a key already has
+	// maxQueuedExports unfinished exports.
+	errTooManyExports = fmt.Errorf("at most %d exports may be queued or running at once", maxQueuedExports)
+	// errExportLeaseLost is returned when another instance took over a
+	// job whose lease expired while it was being built.
+	errExportLeaseLost = errors.New("export lease expired and was taken over")
+)
+
+const exportColumns = `id, format, status, rows, size_bytes, coalesce(error, ''), created_at, started_at, finished_at, expires_at`
+
+func scanExportJob(row pgx.Row) (ExportJob, error) {
+	var j ExportJob
+	err := row.Scan(&j.ID, &j.Format, &j.Status, &j.Rows, &j.SizeBytes, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.ExpiresAt)
+	return j, err
+}
+
+// Exporter builds export files in the background.  Jobs are queued in
+// the export_jobs table so any instance can pick them up, the files are
+// written to dir.  A running job is leased to the instance building it
+// and is only taken over once that instance stops renewing the lease.
+type Exporter struct {
+	db     database.DBTX
+	dir    string
+	logger *slog.Logger
+	wake   chan struct{}
+	// instance identifies this exporter in the leases of the jobs it is
+	// building.
+	instance string
+}
+
+// NewExporter creates an exporter that writes files to dir.  Run must be
+// started for jobs to be built.
+func NewExporter(db database.DBTX, dir string, logger *slog.Logger) *Exporter {
+	host, _ := os.Hostname()
+	return &Exporter{db: db, dir: dir, logger: logger, wake: make(chan struct{}, 1), instance: host + "/" + strconv.Itoa(os.Getpid())}
+}
+
+// Submit queues an export of the reports matching query, the report
+// filters as they were sent.
+func (ex *Exporter) Submit(ctx context.Context, keyID, format string, query url.Values) (ExportJob, error) {
+	var queued int
+	if err := ex.db.QueryRow(ctx, `select count(*) from export_jobs where key_id = $1 and status in ('pending', 'running')`, keyID).Scan(&queued); err != nil {
+		return ExportJob{}, err
+	}
+	if queued >= maxQueuedExports {
+		return ExportJob{}, errTooManyExports
+	}
+	id, err := newExportID()
+	if err != nil {
+		return ExportJob{}, err
+	}
+	j, err := scanExportJob(ex.db.QueryRow(ctx, `insert into export_jobs (id, key_id, format, query)
+values ($1, $2, $3, $4)
+returning `+exportColumns, id, keyID, format, query.Encode()))
+	if err != nil {
+		return ExportJob{}, err
+	}
+	select {
+	case ex.wake <- struct{}{}:
+	default:
+	}
+	return j, nil
+}
+
+// Get returns one of keyID's exports.
+func (ex *Exporter) Get(ctx context.Context, keyID, id string) (ExportJob, error) {
+	j, err := scanExportJob(ex.db.QueryRow(ctx, `select `+exportColumns+`
+from export_jobs
+where key_id = $1
+  and id = $2
+  and (expires_at is null or expires_at > now())`, keyID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ExportJob{}, errExportNotFound
+	}
+	return j, err
+}
+
+// Path returns where a complete export's file is.
+func (ex *Exporter) Path(j ExportJob) string {
+	return filepath.Join(ex.dir, j.ID+".zip")
+}
+
+// Run builds queued exports with the given number of workers, and
+// removes expired ones, until ctx is done.
+func (ex *Exporter) Run(ctx context.Context, workers int) {
+	if err := os.MkdirAll(ex.dir, 0o750); err != nil {
+		ex.logger.Error("failed to create export dir, exports are disabled", "error", err, "dir", ex.dir)
+		return
+	}
+	for i := 0; i < workers; i++ {
+		go ex.work(ctx)
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		ex.expire(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ex *Exporter) work(ctx context.Context) {
+	ticker := time.NewTicker(exportPollInterval)
+	defer ticker.Stop()
+	for {
+		claimed, err := ex.runNext(ctx)
+		if err != nil {
+			ex.logger.Error("export worker failed", "error", err)
+		}
+		if claimed {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ex.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext builds the oldest pending export, or a running one whose lease
+// expired because the instance building it stopped, it reports whether
+// there was one.
+func (ex *Exporter) runNext(ctx context.Context) (bool, error) {
+	var id, format, query string
+	err := ex.db.QueryRow(ctx, `update export_jobs
+set status = 'running', started_at = now(), claimed_by = $1, heartbeat_at = now()
+where id = (select id
+            from export_jobs
+            where status = 'pending'
+               or (status = 'running' and heartbeat_at < now() - make_interval(secs => $2))
+            order by created_at
+            limit 1 for update skip locked)
+returning id, format, query`, ex.instance, exportLease.Seconds()).Scan(&id, &format, &query)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	buildCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ex.heartbeat(buildCtx, id, cancel)
+	}()
+	rows, size, err := ex.build(buildCtx, id, format, query)
+	cancel(nil)
+	<-done
+
+	// the updates below only apply while the job is still leased here.
+	if cause := context.Cause(buildCtx); errors.Is(cause, errExportLeaseLost) {
+		ex.logger.Warn("export was taken over by another instance", "export", id)
+		return true, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			// shutting down, let another instance have it straight away.
+			_, err = ex.db.Exec(context.WithoutCancel(ctx), `update export_jobs
+set status = 'pending', started_at = null, claimed_by = null, heartbeat_at = null
+where id = $1 and claimed_by = $2`, id, ex.instance)
+			return true, err
+		}
+		ex.logger.Error("failed to build export", "error", err, "export", id)
+		_, err = ex.db.Exec(context.WithoutCancel(ctx), `update export_jobs
+set status = 'failed', error = $3, finished_at = now(), expires_at = now() + make_interval(secs => $4)
+where id = $1 and claimed_by = $2`, id, ex.instance, err.Error(), exportTTL.Seconds())
+		return true, err
+	}
+	ex.logger.Info("built export", "export", id, "rows", rows, "bytes", size, "took", time.Since(start))
+	_, err = ex.db.Exec(ctx, `update export_jobs
+set status = 'complete', rows = $3, size_bytes = $4, finished_at = now(), expires_at = now() + make_interval(secs => $5)
+where id = $1 and claimed_by = $2`, id, ex.instance, rows, size, exportTTL.Seconds())
+	return true, err
+}
+
+// heartbeat renews the lease on job id every exportHeartbeat until ctx is
+// done.  If the lease was lost the build is cancelled with
+// errExportLeaseLost.
+func (ex *Exporter) heartbeat(ctx context.Context, id string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(exportHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tag, err := ex.db.Exec(ctx, `update export_jobs
+set heartbeat_at = now()
+where id = $1 and claimed_by = $2 and status = 'running'`, id, ex.instance)
+		if err != nil {
+			// the lease holds for a while, try again next beat.
+			ex.logger.Warn("failed to renew export lease", "error", err, "export", id)
+			continue
+		}
+		if tag.RowsAffected() == 0 {
+			cancel(errExportLeaseLost)
+			return
+		}
+	}
+}
+
+// build writes the export's file, it returns the number of reports in it
+// and its size.
+func (ex *Exporter) build(ctx context.Context, id, format, query string) (int64, int64, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	f, errResponse := ParseReportFilter(values, "")
+	if errResponse.Code > 0 {
+		return 0, 0, errors.New(errResponse.Message)
+	}
+
+	tmp, err := os.MkdirTemp(ex.dir, "build-")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.RemoveAll(tmp)
+	var rows int64
+	switch format {
+	case ExportShapefile:
+		rows, err = writeReportShapefile(ctx, ex.db, f, tmp, "reports")
+	case ExportParquet:
+		rows, err = WriteReportParquet(ctx, ex.db, f, tmp)
+	default:
+		err = fmt.Errorf("format %s is not supported", format)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// every attempt zips to a file of its own, an instance whose lease
+	// expired may still be zipping when the one that took over finishes.
+	out, err := os.CreateTemp(ex.dir, id+"-*.zip.tmp")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer out.Close()
+	size, err := zipDir(tmp, out)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return 0, 0, err
+	}
+	return rows, size, os.Rename(out.Name(), filepath.Join(ex.dir, id+".zip"))
+}
+
+// expire removes the rows and files of exports past their expiry.
+func (ex *Exporter) expire(ctx context.Context) {
+	rows, err := ex.db.Query(ctx, `delete from export_jobs where expires_at < now() returning id`)
+	if err != nil {
+		ex.logger.Error("failed to expire exports", "error", err)
+		return
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		ex.logger.Error("failed to expire exports", "error", err)
+		return
+	}
+	for _, id := range ids {
+		// and any zip an instance left behind when it stopped part way.
+		leftover, _ := filepath.Glob(filepath.Join(ex.dir, id+"-*.zip.tmp"))
+		for _, path := range append(leftover, filepath.Join(ex.dir, id+".zip")) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				ex.logger.Warn("failed to remove expired export", "error", err, "export", id)
+			}
+		}
+	}
+}
+
+// reportShapefileFields are the columns of a report Shapefile.  Names are
+// limited to 10 characters.
+var reportShapefileFields = []shapefile.Field{
+	{Name: "ID", Type: shapefile.Numeric, Length: 12},
+	{Name: "TYPE", Type: shapefile.Character, Length: 7},
+	{Name: "TIME", Type: shapefile.Character, Length: 20},
+	{Name: "DATE", Type: shapefile.Date, Length: 8},
+	{Name: "MAG", Type: shapefile.Numeric, Length: 7, Decimals: 2},
+	{Name: "MAG_UNIT", Type: shapefile.Character, Length: 3},
+	{Name: "MEASURE", Type: shapefile.Character, Length: 9},
+	{Name: "DIST_MI", Type: shapefile.Numeric, Length: 5},
+	{Name: "DIRECTION", Type: shapefile.Character, Length: 3},
+	{Name: "LOCATION", Type: shapefile.Character, Length: 100},
+	{Name: "COUNTY", Type: shapefile.Character, Length: 50},
+	{Name: "STATE", Type: shapefile.Character, Length: 2},
+	{Name: "OFFICE", Type: shapefile.Character, Length: 3},
+	{Name: "LAT", Type: shapefile.Numeric, Length: 10, Decimals: 5},
+	{Name: "LON", Type: shapefile.Numeric, Length: 11, Decimals: 5},
+	{Name: "COMMENTS", Type: shapefile.Character, Length: 254},
+	{Name: "FLAGS", Type: shapefile.Character, Length: 100},
+	{Name: "REVIEW", Type: shapefile.Character, Length: 8},
+}
+
+// writeReportShapefile writes the located reports matching the filter to
+// name.shp and its sidecar files in dir.  Magnitudes are in inches, mph
+// and EF ratings.
+func writeReportShapefile(ctx context.Context, db database.DBTX, f ReportFilter, dir, name string) (int64, error) {
+	path := filepath.Join(dir, name)
+	for ext, contents := range map[string]string{".prj": shapefile.WGS84, ".cpg": shapefile.CodePage} {
+		if err := os.WriteFile(path+ext, []byte(contents), 0o640); err != nil {
+			return 0, err
+		}
+	}
+	var files []*os.File
+	for _, ext := range []string{".shp", ".shx", ".dbf"} {
+		file, err := os.Create(path + ext)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		files = append(files, file)
+	}
+	w, err := shapefile.NewPointWriter(files[0], files[1], files[2], reportShapefileFields)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	err = EachReport(ctx, db, f, func(row StoredReport) error {
+		r := storedToReportV2(row, UnitsImperial)
+		if r.Lat == nil || r.Lon == nil {
+			return nil
+		}
+		values := []any{
+			r.ID,
+			r.Type,
+			r.Time.UTC().Format(time.RFC3339),
+			r.Time.UTC(),
+			nil,
+			nil,
+			nil,
+			int64(r.Distance.Value),
+			r.Direction,
+			r.Location,
+			r.County,
+			r.State,
+			nil,
+			*r.Lat,
+			*r.Lon,
+			r.Comments,
+			strings.Join(r.Flags, ","),
+			nil,
+		}
+		if r.Magnitude != nil {
+			values[4], values[5] = r.Magnitude.Value, r.Magnitude.Unit
+		}
+		if r.Measurement != nil {
+			values[6] = *r.Measurement
+		}
+		if r.Office != nil {
+			values[12] = *r.Office
+		}
+		if r.Review != nil {
+			values[17] = *r.Review
+		}
+		rows++
+		return w.Write(*r.Lon, *r.Lat, values)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, w.Close()
+}
+
+// zipDir zips the files under dir into out, keeping their paths relative
+// to dir, and returns the size of the zip.
+func zipDir(dir string, out *os.File) (int64, error) {
+	zw := zip.NewWriter(out)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return zipFile(zw, path, filepath.ToSlash(name))
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func zipFile(zw *zip.Writer, path, name string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+func newExportID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
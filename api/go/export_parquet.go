@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/parquet"
+)
+
+// reportParquetColumns are the columns of a report Parquet file, the
+// reports table with numbers and timestamps typed.  rpt_type isn't a
+// column, it is a partition.
+var reportParquetColumns = []parquet.Column{
+	{Name: "id", Type: parquet.Int64},
+	{Name: "reported_time", Type: parquet.Timestamp},
+	{Name: "created_at", Type: parquet.Timestamp, Optional: true},
+	{Name: "magnitude", Type: parquet.Double, Optional: true},
+	{Name: "magnitude_unit", Type: parquet.String, Optional: true},
+	{Name: "wind_measurement", Type: parquet.String, Optional: true},
+	{Name: "dist_from_location", Type: parquet.Int32},
+	{Name: "heading_from_location", Type: parquet.String},
+	{Name: "location", Type: parquet.String},
+	{Name: "county", Type: parquet.String},
+	{Name: "state", Type: parquet.String, Optional: true},
+	{Name: "nws_office", Type: parquet.String, Optional: true},
+	{Name: "latitude", Type: parquet.Double, Optional: true},
+	{Name: "longitude", Type: parquet.Double, Optional: true},
+	{Name: "comments", Type: parquet.String, Optional: true},
+	{Name: "flags", Type: parquet.String, Optional: true},
+	{Name: "review", Type: parquet.String, Optional: true},
+}
+
+// reportPartition is where a report's Parquet file goes, hive style so
+// pandas, DuckDB and Spark read year and type back as columns.
+func reportPartition(year int, rptType string) string {
+	return filepath.Join("year="+strconv.Itoa(year), "type="+rptType, "part-0.parquet")
+}
+
+// WriteReportParquet writes the reports matching the filter to dir as
+// Parquet files partitioned by year and type.  Magnitudes are in inches,
+// mph and EF ratings.  It returns the number of reports written.
+func WriteReportParquet(ctx context.Context, db database.DBTX, f ReportFilter, dir string) (int64, error) {
+	type partition struct {
+		file *os.File
+		w    *parquet.Writer
+	}
+	// reports come ordered by time, so a year's files are finished once a
+	// later year shows up.
+	open := map[string]partition{}
+	year := 0
+	closeAll := func() error {
+		for path, p := range open {
+			if err := p.w.Close(); err != nil {
+				return err
+			}
+			if err := p.file.Close(); err != nil {
+				return err
+			}
+			delete(open, path)
+		}
+		return nil
+	}
+	defer func() {
+		for _, p := range open {
+			p.file.Close()
+		}
+	}()
+
+	var rows int64
+	err := EachReport(ctx, db, f, func(row StoredReport) error {
+		r := storedToReportV2(row, UnitsImperial)
+		t := r.Time.UTC()
+		if t.Year() != year {
+			if err := closeAll(); err != nil {
+				return err
+			}
+			year = t.Year()
+		}
+		path := filepath.Join(dir, reportPartition(year, r.Type))
+		p, ok := open[path]
+		if !ok {
+			if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+				return err
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			p = partition{file: file, w: parquet.NewWriter(file, reportParquetColumns)}
+			open[path] = p
+		}
+		rows++
+		return p.w.Write(reportParquetRow(row, r))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, closeAll()
+}
+
+func reportParquetRow(row StoredReport, r ReportV2) []any {
+	values := []any{
+		r.ID,
+		r.Time,
+		nil,
+		nil,
+		nil,
+		nil,
+		row.DistFromLocation,
+		r.Direction,
+		r.Location,
+		r.County,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+	}
+	if row.CreatedAt.Valid {
+		values[2] = row.CreatedAt.Time
+	}
+	if r.Magnitude != nil {
+		values[3], values[4] = r.Magnitude.Value, r.Magnitude.Unit
+	}
+	if r.Measurement != nil {
+		values[5] = *r.Measurement
+	}
+	if row.State.Valid {
+		values[10] = r.State
+	}
+	if r.Office != nil {
+		values[11] = *r.Office
+	}
+	if r.Lat != nil && r.Lon != nil {
+		values[12], values[13] = *r.Lat, *r.Lon
+	}
+	if row.Comments.Valid {
+		values[14] = r.Comments
+	}
+	if len(r.Flags) > 0 {
+		values[15] = strings.Join(r.Flags, ",")
+	}
+	if r.Review != nil {
+		values[16] = *r.Review
+	}
+	return values
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_reportParquetRow(t *testing.T) {
+	reported := time.Date(2024, 5, 9, 21, 30, 0, 0, time.UTC)
+	row := StoredReport{
+		ID: 7,
+		Report: database.Report{
+			RptType:          database.ReportTypeWind,
+			ReportedTime:     pgtype.Timestamptz{Time: reported, Valid: true},
+			VarCol:           pgtype.Int4{Int32: 0, Valid: true},
+			DistFromLocation: 2,
+			Location:         "Norman",
+			Latitude:         pgtype.Text{String: "35.22", Valid: true},
+			Longitude:        pgtype.Text{String: "UNK", Valid: true},
+		},
+		Measurement: "measured",
+	}
+	values := reportParquetRow(row, storedToReportV2(row, UnitsImperial))
+	require.Len(t, values, len(reportParquetColumns))
+	want := map[string]any{
+		"id":                 int64(7),
+		"reported_time":      reported,
+		"magnitude":          nil,
+		"wind_measurement":   "measured",
+		"dist_from_location": int32(2),
+		"location":           "Norman",
+		"state":              nil,
+		"latitude":           nil,
+		"longitude":          nil,
+	}
+	for i, c := range reportParquetColumns {
+		if v, ok := want[c.Name]; ok {
+			assert.Equal(t, v, values[i], c.Name)
+		}
+	}
+}
+
+func Test_reportPartition(t *testing.T) {
+	assert.Equal(t, filepath.Join("year=2024", "type=hail", "part-0.parquet"), reportPartition(2024, "hail"))
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_withExportURLs(t *testing.T) {
+	pending := withExportURLs(ExportJob{ID: "abc", Status: ExportPending})
+	assert.Equal(t, "/api/v1/exports/abc", pending.StatusURL)
+	assert.Empty(t, pending.DownloadURL)
+
+	complete := withExportURLs(ExportJob{ID: "abc", Status: ExportComplete})
+	assert.Equal(t, "/api/v1/exports/abc/download", complete.DownloadURL)
+}
+
+func Test_zipDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"reports.shp", "reports.dbf"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600))
+	}
+	partition := filepath.Join(dir, "year=2024", "type=hail")
+	require.NoError(t, os.MkdirAll(partition, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(partition, "part-0.parquet"), []byte("PAR1"), 0o600))
+	dst := filepath.Join(t.TempDir(), "export.zip")
+	out, err := os.Create(dst)
+	require.NoError(t, err)
+	size, err := zipDir(dir, out)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	info, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), size)
+	zr, err := zip.OpenReader(dst)
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{"reports.shp", "reports.dbf", "year=2024/type=hail/part-0.parquet"}, names)
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stormsync/database"
+)
+
+const (
+	atomContentType = "application/atom+xml"
+	rssContentType  = "application/rss+xml"
+	capContentType  = "application/cap+xml"
+
+	// capAlertLifetime is how long after a report its alert expires.
+	capAlertLifetime = 6 * time.Hour
+	// capAlertRadius is the radius, in km, of the circle around a report
+	// its alert covers.  Reports are points, the circle stands in for
+	// the storm that produced it.
+	capAlertRadius = 10
+)
+
+// feed is what the feed renderers need besides the reports.
+type feed struct {
+	Title string
+	// URL is the feed's own address, without the api key.
+	URL string
+	// Sender identifies the service in CAP alerts, the host the feed is
+	// served from.
+	Sender string
+	// Updated is the feed's time when it has no reports.
+	Updated time.Time
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    *atomContent   `xml:"content"`
+	Point      string         `xml:"http://www.georss.org/georss point,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string    `xml:"type,attr"`
+	Alert *capAlert `xml:"alert"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category"`
+	Point       string  `xml:"http://www.georss.org/georss point,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+// capAlert is an OASIS CAP 1.2 alert, the elements are in the order the
+// schema requires.
+type capAlert struct {
+	XMLName    xml.Name `xml:"urn:oasis:names:tc:emergency:cap:1.2 alert"`
+	Identifier string   `xml:"identifier"`
+	Sender     string   `xml:"sender"`
+	Sent       string   `xml:"sent"`
+	Status     string   `xml:"status"`
+	MsgType    string   `xml:"msgType"`
+	Scope      string   `xml:"scope"`
+	Info       capInfo  `xml:"info"`
+}
+
+type capInfo struct {
+	Language    string         `xml:"language"`
+	Category    string         `xml:"category"`
+	Event       string         `xml:"event"`
+	Urgency     string         `xml:"urgency"`
+	Severity    string         `xml:"severity"`
+	Certainty   string         `xml:"certainty"`
+	Effective   string         `xml:"effective"`
+	Expires     string         `xml:"expires"`
+	SenderName  string         `xml:"senderName"`
+	Headline    string         `xml:"headline"`
+	Description string         `xml:"description,omitempty"`
+	Parameters  []capParameter `xml:"parameter"`
+	Area        capArea        `xml:"area"`
+}
+
+type capParameter struct {
+	ValueName string `xml:"valueName"`
+	Value     string `xml:"value"`
+}
+
+type capArea struct {
+	AreaDesc string `xml:"areaDesc"`
+	Circle   string `xml:"circle,omitempty"`
+}
+
+// renderAtom renders the reports as an Atom feed, an entry per report
+// with its location as a GeoRSS point.
+func renderAtom(f feed, rpts []StoredReport) ([]byte, error) {
+	return renderAtomFeed(f, rpts, func(_ StoredReport, r ReportV2) atomEntry {
+		return atomEntry{Title: reportHeadline(r), Summary: reportSummary(r)}
+	})
+}
+
+// renderCAP renders the reports as an Atom feed of CAP alerts, each
+// entry's content is the report's alert.
+func renderCAP(f feed, rpts []StoredReport) ([]byte, error) {
+	return renderAtomFeed(f, rpts, func(row StoredReport, r ReportV2) atomEntry {
+		alert := reportAlert(f.Sender, row, r)
+		return atomEntry{Title: alert.Info.Headline, Content: &atomContent{Type: capContentType, Alert: &alert}}
+	})
+}
+
+// renderAtomFeed renders an Atom feed of the reports, entry fills in the
+// title and body of each report's entry.
+func renderAtomFeed(f feed, rpts []StoredReport, entry func(StoredReport, ReportV2) atomEntry) ([]byte, error) {
+	af := atomFeed{
+		ID:      f.URL,
+		Title:   f.Title,
+		Updated: feedUpdated(f, rpts).Format(time.RFC3339),
+		Link:    atomLink{Href: f.URL, Rel: "self", Type: atomContentType},
+		Author:  atomAuthor{Name: "StormSync"},
+	}
+	for _, row := range rpts {
+		r := storedToReportV2(row, UnitsImperial)
+		e := entry(row, r)
+		e.ID = reportURN(r.ID)
+		e.Updated = reportUpdated(row).UTC().Format(time.RFC3339)
+		e.Published = r.Time.UTC().Format(time.RFC3339)
+		e.Categories = []atomCategory{{Term: r.Type}}
+		e.Point = geoRSSPoint(r)
+		af.Entries = append(af.Entries, e)
+	}
+	return marshalFeed(af)
+}
+
+// renderRSS renders the reports as an RSS 2.0 feed.
+func renderRSS(f feed, rpts []StoredReport) ([]byte, error) {
+	rf := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.URL,
+			Self:          atomLink{Href: f.URL, Rel: "self", Type: rssContentType},
+			Description:   f.Title + ", newest first.",
+			LastBuildDate: feedUpdated(f, rpts).Format(time.RFC1123Z),
+		},
+	}
+	for _, row := range rpts {
+		r := storedToReportV2(row, UnitsImperial)
+		rf.Channel.Items = append(rf.Channel.Items, rssItem{
+			Title:       reportHeadline(r),
+			Description: reportSummary(r),
+			GUID:        rssGUID{ID: reportURN(r.ID)},
+			PubDate:     r.Time.UTC().Format(time.RFC1123Z),
+			Category:    r.Type,
+			Point:       geoRSSPoint(r),
+		})
+	}
+	return marshalFeed(rf)
+}
+
+// feedUpdated is when the newest report was stored, or f.Updated when
+// there are none.
+func feedUpdated(f feed, rpts []StoredReport) time.Time {
+	if len(rpts) == 0 {
+		return f.Updated.UTC()
+	}
+	var updated time.Time
+	for _, row := range rpts {
+		if u := reportUpdated(row); u.After(updated) {
+			updated = u
+		}
+	}
+	return updated.UTC()
+}
+
+// reportAlert is the CAP alert for a significant report.  The alert is
+// sent when the report was stored and is in effect from the time of the
+// event.
+func reportAlert(sender string, row StoredReport, r ReportV2) capAlert {
+	severity := "Severe"
+	if r.Type == string(database.ReportTypeTornado) && r.Magnitude != nil && r.Magnitude.Value >= 2 {
+		severity = "Extreme"
+	}
+	info := capInfo{
+		Language:    "en-US",
+		Category:    "Met",
+		Event:       capEvents[database.ReportType(r.Type)],
+		Urgency:     "Immediate",
+		Severity:    severity,
+		Certainty:   "Observed",
+		Effective:   capTime(r.Time),
+		Expires:     capTime(r.Time.Add(capAlertLifetime)),
+		SenderName:  "StormSync",
+		Headline:    reportHeadline(r),
+		Description: r.Comments,
+		Area:        capArea{AreaDesc: reportPlace(r)},
+	}
+	if r.Magnitude != nil {
+		info.Parameters = append(info.Parameters, capParameter{ValueName: "magnitude", Value: formatQuantity(*r.Magnitude)})
+	}
+	if r.Office != nil {
+		info.Parameters = append(info.Parameters, capParameter{ValueName: "office", Value: *r.Office})
+	}
+	if r.Lat != nil && r.Lon != nil {
+		info.Area.Circle = fmt.Sprintf("%s,%s %d", formatFloat(*r.Lat), formatFloat(*r.Lon), capAlertRadius)
+	}
+	return capAlert{
+		Identifier: fmt.Sprintf("stormsync-report-%d", r.ID),
+		Sender:     sender,
+		Sent:       capTime(reportUpdated(row)),
+		Status:     "Actual",
+		MsgType:    "Alert",
+		Scope:      "Public",
+		Info:       info,
+	}
+}
+
+var capEvents = map[database.ReportType]string{
+	database.ReportTypeHail:    "Hail Report",
+	database.ReportTypeWind:    "Wind Report",
+	database.ReportTypeTornado: "Tornado Report",
+}
+
+// capTime formats a time as CAP requires, with -00:00 for UTC.
+func capTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05") + "-00:00"
+}
+
+func marshalFeed(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// reportUpdated is when the report was stored, or its time for reports
+// stored before created_at was.
+func reportUpdated(row StoredReport) time.Time {
+	if row.CreatedAt.Valid {
+		return row.CreatedAt.Time
+	}
+	return row.ReportedTime.Time
+}
+
+func reportURN(id int64) string {
+	return "urn:stormsync:report:" + strconv.FormatInt(id, 10)
+}
+
+// reportHeadline describes a report in a line, e.g. "1.75 in hail 3 mi N
+// of Norman, Cleveland, OK".
+func reportHeadline(r ReportV2) string {
+	what := r.Type
+	if r.Magnitude != nil {
+		if r.Magnitude.Unit == "EF" {
+			what = "EF" + formatFloat(r.Magnitude.Value) + " " + what
+		} else {
+			what = formatQuantity(*r.Magnitude) + " " + what
+		}
+	}
+	what = strings.ToUpper(what[:1]) + what[1:]
+	return what + " " + reportPlace(r)
+}
+
+// reportPlace is where a report is, e.g. "3 mi N of Norman, Cleveland, OK".
+func reportPlace(r ReportV2) string {
+	place := "at " + r.Location
+	if r.Distance.Value > 0 {
+		place = formatQuantity(r.Distance) + " " + r.Direction + " of " + r.Location
+	}
+	for _, v := range []string{r.County, r.State} {
+		if v != "" {
+			place += ", " + v
+		}
+	}
+	return place
+}
+
+// reportSummary is the remarks of a report and who reported it when.
+func reportSummary(r ReportV2) string {
+	summary := "Reported " + r.Time.UTC().Format("2006-01-02 15:04 UTC")
+	if r.Office != nil {
+		summary += " by " + *r.Office
+	}
+	summary += "."
+	if r.Comments != "" {
+		summary = r.Comments + " " + summary
+	}
+	return summary
+}
+
+func geoRSSPoint(r ReportV2) string {
+	if r.Lat == nil || r.Lon == nil {
+		return ""
+	}
+	return formatFloat(*r.Lat) + " " + formatFloat(*r.Lon)
+}
+
+func formatQuantity(q Quantity) string {
+	return formatFloat(q.Value) + " " + q.Unit
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
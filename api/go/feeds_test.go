@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func feedReport(id int64, t database.ReportType, magnitude int32, lat string) StoredReport {
+	reported := time.Date(2024, 5, 6, 23, 10, 0, 0, time.UTC)
+	return StoredReport{
+		ID: id,
+		Report: database.Report{
+			RptType:             t,
+			ReportedTime:        pgtype.Timestamptz{Time: reported, Valid: true},
+			CreatedAt:           pgtype.Timestamptz{Time: reported.Add(time.Duration(id) * time.Minute), Valid: true},
+			VarCol:              pgtype.Int4{Int32: magnitude, Valid: true},
+			DistFromLocation:    3,
+			HeadingFromLocation: "N",
+			Location:            "Norman",
+			County:              "Cleveland",
+			State:               pgtype.Text{String: "OK", Valid: true},
+			Latitude:            pgtype.Text{String: lat, Valid: lat != ""},
+			Longitude:           pgtype.Text{String: "-97.44", Valid: lat != ""},
+			Comments:            pgtype.Text{String: "Roof damage.", Valid: true},
+			NwsOffice:           pgtype.Text{String: "OUN", Valid: true},
+		},
+	}
+}
+
+func Test_reportHeadline(t *testing.T) {
+	tests := []struct {
+		name string
+		row  StoredReport
+		want string
+	}{
+		{
+			name: "should give hail size in inches",
+			row:  feedReport(1, database.ReportTypeHail, 175, "35.25"),
+			want: "1.75 in hail 3 mi N of Norman, Cleveland, OK",
+		},
+		{
+			name: "should give the ef rating before tornado",
+			row:  feedReport(1, database.ReportTypeTornado, 2, "35.25"),
+			want: "EF2 tornado 3 mi N of Norman, Cleveland, OK",
+		},
+		{
+			name: "should leave out an unknown wind speed",
+			row:  feedReport(1, database.ReportTypeWind, 0, "35.25"),
+			want: "Wind 3 mi N of Norman, Cleveland, OK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, reportHeadline(storedToReportV2(tt.row, UnitsImperial)))
+		})
+	}
+}
+
+func TestRenderFeeds(t *testing.T) {
+	f := feed{Title: "StormSync storm reports", URL: "https://example.com/api/v1/feeds/reports.atom", Sender: "example.com"}
+	rpts := []StoredReport{
+		feedReport(2, database.ReportTypeTornado, 3, "35.25"),
+		feedReport(1, database.ReportTypeHail, 200, ""),
+	}
+
+	body, err := renderAtom(f, rpts)
+	require.NoError(t, err)
+	var atom atomFeed
+	require.NoError(t, xml.Unmarshal(body, &atom))
+	assert.Equal(t, "2024-05-06T23:12:00Z", atom.Updated)
+	require.Len(t, atom.Entries, 2)
+	assert.Equal(t, "urn:stormsync:report:2", atom.Entries[0].ID)
+	assert.Equal(t, "35.25 -97.44", atom.Entries[0].Point)
+	assert.Equal(t, "Roof damage. Reported 2024-05-06 23:10 UTC by OUN.", atom.Entries[0].Summary)
+	assert.Empty(t, atom.Entries[1].Point)
+
+	body, err = renderRSS(f, rpts)
+	require.NoError(t, err)
+	var rss rssFeed
+	require.NoError(t, xml.Unmarshal(body, &rss))
+	require.Len(t, rss.Channel.Items, 2)
+	assert.Equal(t, "Mon, 06 May 2024 23:10:00 +0000", rss.Channel.Items[1].PubDate)
+	assert.Equal(t, "2 in hail 3 mi N of Norman, Cleveland, OK", rss.Channel.Items[1].Title)
+
+	body, err = renderCAP(f, rpts)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">`)
+	var cap atomFeed
+	require.NoError(t, xml.Unmarshal(body, &cap))
+	require.Len(t, cap.Entries, 2)
+	alert := cap.Entries[0].Content.Alert
+	assert.Equal(t, "stormsync-report-2", alert.Identifier)
+	assert.Equal(t, "2024-05-06T23:12:00-00:00", alert.Sent)
+	assert.Equal(t, "2024-05-07T05:10:00-00:00", alert.Info.Expires)
+	assert.Equal(t, "Extreme", alert.Info.Severity)
+	assert.Equal(t, "35.25,-97.44 10", alert.Info.Area.Circle)
+	assert.True(t, strings.HasPrefix(alert.Info.Area.AreaDesc, "3 mi N of Norman"))
+	assert.Equal(t, "Severe", cap.Entries[1].Content.Alert.Info.Severity)
+	assert.Empty(t, cap.Entries[1].Content.Alert.Info.Area.Circle)
+}
+
+func Test_isSignificant(t *testing.T) {
+	tests := []struct {
+		name string
+		t    database.ReportType
+		v    pgtype.Int4
+		want bool
+	}{
+		{name: "should include every tornado", t: database.ReportTypeTornado, want: true},
+		{name: "should include 2in hail", t: database.ReportTypeHail, v: pgtype.Int4{Int32: 200, Valid: true}, want: true},
+		{name: "should leave out smaller hail", t: database.ReportTypeHail, v: pgtype.Int4{Int32: 175, Valid: true}},
+		{name: "should include 75mph wind", t: database.ReportTypeWind, v: pgtype.Int4{Int32: 75, Valid: true}, want: true},
+		{name: "should leave out unknown wind", t: database.ReportTypeWind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSignificant(tt.t, tt.v))
+		})
+	}
+}
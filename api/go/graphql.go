@@ -0,0 +1,669 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/stormsync/database"
+)
+
+const (
+	// graphQLDefaultLimit is how many reports or count groups are returned
+	// when a query doesn't give a limit.
+	graphQLDefaultLimit = 100
+	// graphQLMaxComplexity bounds the cost of an operation, see
+	// graphQLComplexity.
+	graphQLMaxComplexity = 50000
+	graphQLMaxDepth      = 8
+)
+
+// graphQLListFields are the query fields that return up to limit items,
+// the fields under them are charged once per item.
+var graphQLListFields = map[string]bool{"reports": true, "counts": true}
+
+// graphQLFilterParams maps the fields of the ReportFilter input to the
+// report endpoints' query params, the magnitude bounds and types are
+// handled separately.
+var graphQLFilterParams = map[string]string{
+	"date":        "date",
+	"fromDate":    "from-date",
+	"toDate":      "to-date",
+	"state":       "state",
+	"office":      "office",
+	"county":      "county",
+	"location":    "location",
+	"direction":   "direction",
+	"distance":    "distance",
+	"comments":    "comments",
+	"bbox":        "bbox",
+	"measurement": "measurement",
+	"quality":     "quality",
+}
+
+var errGraphQLDatabase = errors.New("error making query to database")
+
+// graphQLContext is what the resolvers of a request or websocket share.
+type graphQLContext struct {
+	offices *officeLoader
+	// rows is how many reports and count groups were returned, for the
+	// usage record.
+	rows atomic.Int64
+}
+
+type graphQLContextKey struct{}
+
+func (s ServerAndDB) newGraphQLContext(ctx context.Context) (context.Context, *graphQLContext) {
+	gc := &graphQLContext{offices: &officeLoader{db: s.Conn, logger: s.Logger}}
+	return context.WithValue(ctx, graphQLContextKey{}, gc), gc
+}
+
+func graphQLContextFrom(ctx context.Context) *graphQLContext {
+	gc, _ := ctx.Value(graphQLContextKey{}).(*graphQLContext)
+	return gc
+}
+
+// officeLoader batches the office lookups of a result.  Resolvers queue
+// the office they need and return a thunk, the executor calls the thunks
+// once the rest of that level of the result is built, and the first call
+// reads every queued office with one query.  Offices are cached for the
+// life of the loader.
+type officeLoader struct {
+	db     database.DBTX
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]bool
+	// offices holds nil for ids that aren't in nws_offices.
+	offices map[string]*Office
+}
+
+func (l *officeLoader) load(ctx context.Context, id string) func() (any, error) {
+	l.mu.Lock()
+	if _, ok := l.offices[id]; !ok {
+		if l.pending == nil {
+			l.pending = map[string]bool{}
+		}
+		l.pending[id] = true
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			if err := l.fetch(ctx); err != nil {
+				l.logger.Error("failed to query offices", "error", err)
+				return nil, errGraphQLDatabase
+			}
+		}
+		if o := l.offices[id]; o != nil {
+			return *o, nil
+		}
+		return nil, nil
+	}
+}
+
+// fetch reads the pending offices, l.mu must be held.
+func (l *officeLoader) fetch(ctx context.Context) error {
+	ids := make([]string, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	rows, err := l.db.Query(ctx, "select id, city, state from nws_offices where id = any($1)", ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if l.offices == nil {
+		l.offices = map[string]*Office{}
+	}
+	for rows.Next() {
+		var o Office
+		if err := rows.Scan(&o.ID, &o.City, &o.State); err != nil {
+			return err
+		}
+		l.offices[o.ID] = &o
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := l.offices[id]; !ok {
+			l.offices[id] = nil
+		}
+	}
+	clear(l.pending)
+	return nil
+}
+
+// graphQLDroppedReports is sent in place of a report to a subscription
+// that fell behind.
+type graphQLDroppedReports int64
+
+// newGraphQLSchema builds the schema served at /api/v1/graphql.  Reports
+// are the v2 reports, with the office resolved to its city and state.
+func newGraphQLSchema(s ServerAndDB) (graphql.Schema, error) {
+	reportTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ReportType",
+		Values: graphql.EnumValueConfigMap{
+			"HAIL":    {Value: string(database.ReportTypeHail)},
+			"WIND":    {Value: string(database.ReportTypeWind)},
+			"TORNADO": {Value: string(database.ReportTypeTornado)},
+		},
+	})
+	unitsEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Units",
+		Values: graphql.EnumValueConfigMap{
+			"IMPERIAL": {Value: UnitsImperial},
+			"METRIC":   {Value: UnitsMetric},
+		},
+	})
+	countGroupValues := graphql.EnumValueConfigMap{}
+	for g := range statsGroups {
+		countGroupValues[strings.ToUpper(g)] = &graphql.EnumValueConfig{Value: g}
+	}
+	countGroupEnum := graphql.NewEnum(graphql.EnumConfig{Name: "CountGroup", Values: countGroupValues})
+
+	quantityType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Quantity",
+		Fields: graphql.Fields{
+			"value": {Type: graphql.NewNonNull(graphql.Float)},
+			"unit":  {Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	officeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Office",
+		Description: "A National Weather Service Weather Forecast Office.",
+		Fields: graphql.Fields{
+			"id":    {Type: graphql.NewNonNull(graphql.ID)},
+			"city":  {Type: graphql.String},
+			"state": {Type: graphql.String},
+		},
+	})
+
+	// reportFields are the fields every report type has.
+	reportFields := func() graphql.Fields {
+		return graphql.Fields{
+			"id":   {Type: graphql.NewNonNull(graphql.ID)},
+			"type": {Type: graphql.NewNonNull(reportTypeEnum)},
+			"time": {Type: graphql.NewNonNull(graphql.DateTime)},
+			"magnitude": {
+				Type:        quantityType,
+				Description: "The hail size, wind speed or tornado EF rating, null when it isn't known.",
+			},
+			"distance":  {Type: graphql.NewNonNull(quantityType)},
+			"direction": {Type: graphql.NewNonNull(graphql.String)},
+			"location":  {Type: graphql.NewNonNull(graphql.String)},
+			"county":    {Type: graphql.NewNonNull(graphql.String)},
+			"state":     {Type: graphql.NewNonNull(graphql.String)},
+			"lat":       {Type: graphql.Float},
+			"lon":       {Type: graphql.Float},
+			"comments":  {Type: graphql.NewNonNull(graphql.String)},
+			"office": {
+				Type: officeType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					r := p.Source.(ReportV2)
+					if r.Office == nil {
+						return nil, nil
+					}
+					return graphQLContextFrom(p.Context).offices.load(p.Context, *r.Office), nil
+				},
+			},
+			"flags":  {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"review": {Type: graphql.String},
+		}
+	}
+	magnitudeField := func(description string) *graphql.Field {
+		return &graphql.Field{
+			Type:        quantityType,
+			Description: description,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(ReportV2).Magnitude, nil
+			},
+		}
+	}
+
+	reportInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:   "Report",
+		Fields: reportFields(),
+	})
+	hailFields := reportFields()
+	hailFields["size"] = magnitudeField("The hail size in inches or mm.")
+	hailType := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "HailReport",
+		Interfaces: []*graphql.Interface{reportInterface},
+		Fields:     hailFields,
+	})
+	windFields := reportFields()
+	windFields["speed"] = magnitudeField("The wind speed in mph or km/h.")
+	windFields["measurement"] = &graphql.Field{
+		Type:        graphql.String,
+		Description: "How the speed was arrived at, measured or estimated.",
+	}
+	windType := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "WindReport",
+		Interfaces: []*graphql.Interface{reportInterface},
+		Fields:     windFields,
+	})
+	tornadoFields := reportFields()
+	tornadoFields["rating"] = &graphql.Field{
+		Type:        graphql.Int,
+		Description: "The EF rating.",
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			if m := p.Source.(ReportV2).Magnitude; m != nil {
+				return int(m.Value), nil
+			}
+			return nil, nil
+		},
+	}
+	tornadoType := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "TornadoReport",
+		Interfaces: []*graphql.Interface{reportInterface},
+		Fields:     tornadoFields,
+	})
+	reportInterface.ResolveType = func(p graphql.ResolveTypeParams) *graphql.Object {
+		switch database.ReportType(p.Value.(ReportV2).Type) {
+		case database.ReportTypeHail:
+			return hailType
+		case database.ReportTypeWind:
+			return windType
+		}
+		return tornadoType
+	}
+
+	filterInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ReportFilter",
+		Description: "The filters of the report endpoints, in the same formats.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"types":       {Type: graphql.NewList(graphql.NewNonNull(reportTypeEnum))},
+			"date":        {Type: graphql.String},
+			"fromDate":    {Type: graphql.String},
+			"toDate":      {Type: graphql.String},
+			"state":       {Type: graphql.String},
+			"office":      {Type: graphql.String},
+			"county":      {Type: graphql.String},
+			"location":    {Type: graphql.String},
+			"direction":   {Type: graphql.String},
+			"distance":    {Type: graphql.Int},
+			"comments":    {Type: graphql.String},
+			"bbox":        {Type: graphql.String, Description: "minLon,minLat,maxLon,maxLat"},
+			"measurement": {Type: graphql.String},
+			"quality":     {Type: graphql.String},
+			"magnitudeGreaterThan": {
+				Type:        graphql.Int,
+				Description: "Exclusive bound on the stored magnitude, needs exactly one type.",
+			},
+			"magnitudeLessThan": {
+				Type:        graphql.Int,
+				Description: "Exclusive bound on the stored magnitude, needs exactly one type.",
+			},
+		},
+	})
+	pageArgs := graphql.FieldConfigArgument{
+		"filter": {Type: filterInput},
+		"limit":  {Type: graphql.Int, DefaultValue: graphQLDefaultLimit},
+		"offset": {Type: graphql.Int},
+	}
+
+	countRowFields := graphql.Fields{"count": {Type: graphql.NewNonNull(graphql.Int)}}
+	for g := range statsGroups {
+		countRowFields[g] = &graphql.Field{Type: graphql.String}
+	}
+	countsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ReportCounts",
+		Fields: graphql.Fields{
+			"groupBy": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(countGroupEnum)))},
+			"total": {
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The number of reports in every group, including groups past the limit.",
+			},
+			"groups": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+					Name:   "CountGroupRow",
+					Fields: countRowFields,
+				})))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(StatsCounts).Counts, nil
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"reports": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reportInterface))),
+				Description: "Reports matching the filter, ordered by time.",
+				Args: withArgs(pageArgs, graphql.FieldConfigArgument{
+					"units": {Type: unitsEnum, DefaultValue: UnitsImperial},
+				}),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					f, err := graphQLFilter(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					rpts, err := QueryReports(p.Context, s.Conn, f)
+					if err != nil {
+						s.Logger.Error("failed to query reports", "error", err)
+						return nil, errGraphQLDatabase
+					}
+					units := p.Args["units"].(string)
+					out := make([]ReportV2, 0, len(rpts))
+					for _, r := range rpts {
+						out = append(out, storedToReportV2(r, units))
+					}
+					graphQLContextFrom(p.Context).rows.Add(int64(len(out)))
+					return out, nil
+				},
+			},
+			"counts": {
+				Type:        graphql.NewNonNull(countsType),
+				Description: "Counts of the reports matching the filter, grouped and ordered by groupBy.",
+				Args: withArgs(pageArgs, graphql.FieldConfigArgument{
+					"groupBy": {
+						Type:         graphql.NewList(graphql.NewNonNull(countGroupEnum)),
+						DefaultValue: []any{defaultStatsGroup},
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					f, err := graphQLFilter(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					var names []string
+					for _, g := range p.Args["groupBy"].([]any) {
+						names = append(names, g.(string))
+					}
+					groups, errResponse := parseStatsGroups(strings.Join(names, ","))
+					if errResponse.Code > 0 {
+						return nil, errors.New(errResponse.Message)
+					}
+					stats, err := QueryStatsCounts(p.Context, s.Conn, f, groups)
+					if err != nil {
+						s.Logger.Error("failed to query report counts", "error", err)
+						return nil, errGraphQLDatabase
+					}
+					graphQLContextFrom(p.Context).rows.Add(int64(len(stats.Counts)))
+					return stats, nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"reports": {
+				Type: reportInterface,
+				Description: "Newly stored reports that match the filter.  If the subscription falls behind, " +
+					"reports are skipped and a null report is sent with an error saying how many.",
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: filterInput},
+					"units":  {Type: unitsEnum, DefaultValue: UnitsImperial},
+				},
+				Subscribe: func(p graphql.ResolveParams) (any, error) {
+					if s.Broker == nil {
+						return nil, errors.New("streaming is not enabled")
+					}
+					f, err := graphQLFilter(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					f.Limit, f.Offset = 0, 0
+					return s.subscribeGraphQL(p.Context, f, p.Args["units"].(string)), nil
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if d, ok := p.Source.(graphQLDroppedReports); ok {
+						return nil, fmt.Errorf("%d reports were dropped because the subscription fell behind", d)
+					}
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Subscription: subscription,
+		Types:        []graphql.Type{hailType, windType, tornadoType},
+	})
+}
+
+func withArgs(args ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	all := graphql.FieldConfigArgument{}
+	for _, a := range args {
+		for name, arg := range a {
+			all[name] = arg
+		}
+	}
+	return all
+}
+
+// subscribeGraphQL sends the reports that match f until ctx is done.
+// The channel is the type the executor expects of a subscription.
+func (s ServerAndDB) subscribeGraphQL(ctx context.Context, f ReportFilter, units string) chan any {
+	sub := s.Broker.Subscribe(streamBuffer)
+	events := make(chan any)
+	gc := graphQLContextFrom(ctx)
+	go func() {
+		defer close(events)
+		defer sub.Close()
+		send := func(ev any) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		var dropped int64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				if d := sub.Dropped(); d != dropped {
+					if !send(graphQLDroppedReports(d - dropped)) {
+						return
+					}
+					dropped = d
+				}
+				if !f.MatchesMessage(m) {
+					continue
+				}
+				if !send(storedToReportV2(messageToStored(m), units)) {
+					return
+				}
+				gc.rows.Add(1)
+			}
+		}
+	}()
+	return events
+}
+
+// graphQLFilter parses the filter, limit and offset args with the report
+// endpoints' parser.
+func graphQLFilter(args map[string]any) (ReportFilter, error) {
+	qp := url.Values{}
+	filter, _ := args["filter"].(map[string]any)
+	for name, v := range filter {
+		if param, ok := graphQLFilterParams[name]; ok && v != nil {
+			qp.Set(param, fmt.Sprint(v))
+		}
+	}
+	var types []string
+	if v, ok := filter["types"].([]any); ok {
+		for _, t := range v {
+			types = append(types, t.(string))
+		}
+		qp.Set("type", strings.Join(types, ","))
+	}
+	if _, ok := args["limit"]; ok {
+		// an explicit null limit still gets the default.
+		limit, ok := args["limit"].(int)
+		if !ok {
+			limit = graphQLDefaultLimit
+		}
+		qp.Set("limit", strconv.Itoa(limit))
+	}
+	if v, ok := args["offset"].(int); ok && v != 0 {
+		qp.Set("offset", strconv.Itoa(v))
+	}
+
+	var reportType database.ReportType
+	gt, hasGT := filter["magnitudeGreaterThan"].(int)
+	lt, hasLT := filter["magnitudeLessThan"].(int)
+	if hasGT || hasLT {
+		if len(types) != 1 {
+			return ReportFilter{}, errMagnitudeType
+		}
+		reportType = database.ReportType(types[0])
+		names := magnitudeParams[reportType]
+		if hasGT {
+			qp.Set(names[0], strconv.Itoa(gt))
+		}
+		if hasLT {
+			qp.Set(names[1], strconv.Itoa(lt))
+		}
+	}
+
+	f, errResponse := ParseReportFilter(qp, reportType)
+	if errResponse.Code > 0 {
+		return ReportFilter{}, errors.New(errResponse.Message)
+	}
+	return f, nil
+}
+
+// graphQLOperation returns the operation of the document that would be
+// executed, nil if there isn't exactly one, which validation reports.
+func graphQLOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var op *ast.OperationDefinition
+	for _, d := range doc.Definitions {
+		o, ok := d.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" && op != nil {
+			return nil
+		}
+		if name == "" || (o.Name != nil && o.Name.Value == name) {
+			op = o
+		}
+	}
+	return op
+}
+
+// graphQLComplexity returns the cost of an operation.  Every field costs
+// 1, and the fields under a list field are charged once per item it may
+// return, its limit.  Operations nested deeper than graphQLMaxDepth, or
+// that cost more than graphQLMaxComplexity, are an error.
+func graphQLComplexity(doc *ast.Document, op *ast.OperationDefinition, variables map[string]any) (int, error) {
+	w := complexityWalker{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		query:     op.Operation == ast.OperationTypeQuery,
+		visiting:  map[string]bool{},
+	}
+	for _, d := range doc.Definitions {
+		if f, ok := d.(*ast.FragmentDefinition); ok {
+			w.fragments[f.Name.Value] = f
+		}
+	}
+	return w.selections(op.SelectionSet, 1)
+}
+
+type complexityWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// query is whether the operation is a query, the list fields are
+	// only at its root.
+	query bool
+	// visiting guards against fragments that spread themselves, which
+	// validation rejects after the cost is worked out.
+	visiting map[string]bool
+}
+
+func (w complexityWalker) selections(set *ast.SelectionSet, depth int) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+	if depth > graphQLMaxDepth {
+		return 0, fmt.Errorf("query is nested more than %d fields deep", graphQLMaxDepth)
+	}
+	cost := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			children, err := w.selections(sel.SelectionSet, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			cost += 1 + w.items(sel, depth)*children
+		case *ast.InlineFragment:
+			c, err := w.selections(sel.SelectionSet, depth)
+			if err != nil {
+				return 0, err
+			}
+			cost += c
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := w.fragments[name]
+			if !ok || w.visiting[name] {
+				continue
+			}
+			w.visiting[name] = true
+			c, err := w.selections(frag.SelectionSet, depth)
+			delete(w.visiting, name)
+			if err != nil {
+				return 0, err
+			}
+			cost += c
+		}
+		if cost > graphQLMaxComplexity {
+			return 0, fmt.Errorf("query costs more than %d, ask for fewer fields or a smaller limit", graphQLMaxComplexity)
+		}
+	}
+	return cost, nil
+}
+
+// items is how many items the field may return, its limit for a list
+// field and 1 otherwise.
+func (w complexityWalker) items(field *ast.Field, depth int) int {
+	if !w.query || depth != 1 || !graphQLListFields[field.Name.Value] {
+		return 1
+	}
+	limit := graphQLDefaultLimit
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if l, err := strconv.Atoi(v.Value); err == nil {
+				limit = l
+			}
+		case *ast.Variable:
+			switch l := w.variables[v.Name.Value].(type) {
+			case float64:
+				limit = int(l)
+			case int:
+				limit = l
+			}
+		}
+	}
+	// out of range limits are rejected by the resolver.
+	return min(max(limit, 1), maxReportLimit)
+}
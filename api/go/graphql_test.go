@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+func Test_graphQLComplexity(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:  "should charge each field once",
+			query: `{ counts(limit: 1) { total } }`,
+			want:  2,
+		},
+		{
+			name:  "should charge the fields under reports per item of the default limit",
+			query: `{ reports { id time } }`,
+			want:  1 + graphQLDefaultLimit*2,
+		},
+		{
+			name:  "should use a limit from a variable",
+			query: `query($n: Int) { reports(limit: $n) { id office { city } } }`,
+			variables: map[string]any{
+				"n": float64(10),
+			},
+			want: 1 + 10*3,
+		},
+		{
+			name:  "should count fragments",
+			query: `{ reports(limit: 10) { ...r ... on HailReport { size { value } } } } fragment r on Report { id }`,
+			want:  1 + 10*3,
+		},
+		{
+			name:  "should not multiply a subscription",
+			query: `subscription { reports { id time } }`,
+			want:  3,
+		},
+		{
+			name:    "should reject a large limit with many fields",
+			query:   `{ reports(limit: 10000) { id time state county office { id city state } } }`,
+			wantErr: true,
+		},
+		{
+			name:    "should reject deep nesting",
+			query:   `{ a { b { c { d { e { f { g { h { i } } } } } } } } }`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+			got, err := graphQLComplexity(doc, graphQLOperation(doc, ""), tt.variables)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// officeDB answers the office query and counts how often it is made.
+type officeDB struct {
+	database.DBTX
+	queries [][]string
+}
+
+func (db *officeDB) Query(_ context.Context, _ string, args ...any) (pgx.Rows, error) {
+	ids := args[0].([]string)
+	db.queries = append(db.queries, ids)
+	var found []string
+	for _, id := range ids {
+		if id != "XXX" {
+			found = append(found, id)
+		}
+	}
+	return &officeRows{ids: found, i: -1}, nil
+}
+
+type officeRows struct {
+	pgx.Rows
+	ids []string
+	i   int
+}
+
+func (r *officeRows) Next() bool { r.i++; return r.i < len(r.ids) }
+func (r *officeRows) Close()     {}
+func (r *officeRows) Err() error { return nil }
+func (r *officeRows) Scan(dest ...any) error {
+	city := "city of " + r.ids[r.i]
+	*dest[0].(*string) = r.ids[r.i]
+	*dest[1].(**string) = &city
+	return nil
+}
+
+func TestOfficeLoader(t *testing.T) {
+	db := &officeDB{}
+	l := &officeLoader{db: db, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx := context.Background()
+
+	var thunks []func() (any, error)
+	for _, id := range []string{"OUN", "TSA", "OUN", "XXX"} {
+		thunks = append(thunks, l.load(ctx, id))
+	}
+	var got []any
+	for _, thunk := range thunks {
+		o, err := thunk()
+		require.NoError(t, err)
+		got = append(got, o)
+	}
+	require.Len(t, db.queries, 1, "offices should be read with one query")
+	assert.ElementsMatch(t, []string{"OUN", "TSA", "XXX"}, db.queries[0])
+	assert.Equal(t, "OUN", got[0].(Office).ID)
+	assert.Equal(t, "city of TSA", *got[1].(Office).City)
+	assert.Equal(t, got[0], got[2])
+	assert.Nil(t, got[3])
+
+	o, err := l.load(ctx, "XXX")()
+	require.NoError(t, err)
+	assert.Nil(t, o)
+	assert.Len(t, db.queries, 1, "offices should be cached")
+}
+
+func TestGraphQL(t *testing.T) {
+	broker := pubsub.NewBroker(0)
+	s := NewRouter(RouterConfig{
+		ROKey:             "rokey",
+		RWKey:             "rwkey",
+		Broker:            broker,
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ValidateResponses: true,
+	})
+	srv := httptest.NewServer(s.Web)
+	defer srv.Close()
+
+	type result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	post := func(query string) result {
+		t.Helper()
+		body, _ := json.Marshal(GraphQLRequest{Query: query})
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Api-Key", "rokey")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var r result
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&r))
+		return r
+	}
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{
+			name:    "should reject a query that costs too much",
+			query:   `{ reports(limit: 10000) { id time state county office { id city state } } }`,
+			wantErr: "costs more than",
+		},
+		{
+			name:    "should reject filters the report endpoints reject",
+			query:   `{ reports(filter: {state: "Oklahoma"}) { id } }`,
+			wantErr: "state",
+		},
+		{
+			name:    "should reject magnitude bounds without a single type",
+			query:   `{ reports(filter: {magnitudeGreaterThan: 100}) { id } }`,
+			wantErr: "magnitude bounds",
+		},
+		{
+			name:    "should send subscriptions to the websocket",
+			query:   `subscription { reports { id } }`,
+			wantErr: "websocket",
+		},
+		{
+			name:    "should validate against the schema",
+			query:   `{ reports { speed } }`,
+			wantErr: "speed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := post(tt.query)
+			require.NotEmpty(t, r.Errors)
+			assert.Contains(t, r.Errors[0].Message, tt.wantErr)
+		})
+	}
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/graphql/ws?api_key=rokey"
+	dialer := websocket.Dialer{Subprotocols: []string{graphQLSocketProtocol}}
+	conn, _, err := dialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	read := func() GraphQLSocketMessage {
+		t.Helper()
+		var msg GraphQLSocketMessage
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	require.NoError(t, conn.WriteJSON(GraphQLSocketMessage{Type: "connection_init"}))
+	assert.Equal(t, "connection_ack", read().Type)
+	payload, _ := json.Marshal(GraphQLRequest{
+		Query: `subscription { reports(filter: {types: [HAIL], quality: "clean"}) { id type distance { unit } office { id } ... on HailReport { size { value unit } } } }`,
+	})
+	require.NoError(t, conn.WriteJSON(GraphQLSocketMessage{Type: "subscribe", ID: "1", Payload: payload}))
+	// the subscription starts asynchronously, keep publishing until it
+	// is listening.
+	stop := make(chan struct{})
+	go func() {
+		for {
+			broker.Publish(pubsub.Message{Report: database.InsertReportParams{
+				RptType: database.ReportTypeWind,
+			}})
+			// flagged, so quality: "clean" leaves it out.
+			broker.Publish(pubsub.Message{ID: 5, Flags: []string{"magnitude_outlier"}, Report: database.InsertReportParams{
+				RptType: database.ReportTypeHail,
+				VarCol:  pgtype.Int4{Int32: 500, Valid: true},
+			}})
+			broker.Publish(pubsub.Message{Report: database.InsertReportParams{
+				RptType: database.ReportTypeHail,
+				VarCol:  pgtype.Int4{Int32: 175, Valid: true},
+			}})
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	msg := read()
+	close(stop)
+	assert.Equal(t, "next", msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.JSONEq(t, `{"data":{"reports":{"id":"0","type":"HAIL","distance":{"unit":"mi"},"office":null,"size":{"value":1.75,"unit":"in"}}}}`, string(msg.Payload))
+
+	require.NoError(t, conn.WriteJSON(GraphQLSocketMessage{Type: "complete", ID: "1"}))
+	require.NoError(t, conn.WriteJSON(GraphQLSocketMessage{Type: "ping"}))
+	// reports published before the complete arrived may still come.
+	for msg = read(); msg.Type == "next"; msg = read() {
+	}
+	assert.Equal(t, "pong", msg.Type)
+}
@@ -0,0 +1,326 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stormsync/database"
+	report "github.com/stormsync/transformer/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jason-costello/weather/accesssvc/api/reportspb"
+)
+
+// grpcKeyMetadata is the metadata the api key is sent in, the REST header
+// lowercased as gRPC metadata keys are.
+const grpcKeyMetadata = "x-api-key"
+
+// grpcCall is what the interceptors know about a call, the handlers fill
+// in the filters and rows for the usage record.
+type grpcCall struct {
+	keyID   string
+	filters string
+	rows    int
+}
+
+type grpcCallContextKey struct{}
+
+// reportService serves the report queries over gRPC with the filters and
+// queries of the REST report endpoints.
+type reportService struct {
+	reportspb.UnimplementedReportServiceServer
+	s ServerAndDB
+}
+
+// newGRPCServer returns a gRPC server for the ReportService.  Calls are
+// authenticated with the read only key and recorded like REST requests.
+func newGRPCServer(s ServerAndDB, roKey string) *grpc.Server {
+	auth := func(ctx context.Context) (context.Context, *grpcCall, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(grpcKeyMetadata)
+		if len(keys) == 0 || keys[0] != roKey {
+			return nil, nil, status.Error(codes.Unauthenticated, "missing or invalid api key")
+		}
+		call := &grpcCall{keyID: apiKeyID(keys[0])}
+		return context.WithValue(ctx, grpcCallContextKey{}, call), call, nil
+	}
+	record := func(call *grpcCall, method string, start time.Time, err error) {
+		if s.Usage == nil {
+			return
+		}
+		s.Usage.Record(UsageRecord{
+			KeyID:   call.keyID,
+			Time:    start.UTC(),
+			Method:  "GRPC",
+			Route:   method,
+			Filters: call.filters,
+			Status:  grpcHTTPStatus(status.Code(err)),
+			Rows:    call.rows,
+			Latency: time.Since(start),
+		})
+	}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			start := time.Now()
+			ctx, call, err := auth(ctx)
+			if err != nil {
+				return nil, err
+			}
+			res, err := handler(ctx, req)
+			record(call, info.FullMethod, start, err)
+			return res, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			start := time.Now()
+			ctx, call, err := auth(ss.Context())
+			if err != nil {
+				return err
+			}
+			err = handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+			record(call, info.FullMethod, start, err)
+			return err
+		}),
+	)
+	reportspb.RegisterReportServiceServer(srv, &reportService{s: s})
+	return srv
+}
+
+// grpcServerStream carries the context the interceptor adds to.
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *grpcServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+// QueryReports returns the reports matching the query.
+func (rs *reportService) QueryReports(ctx context.Context, q *reportspb.ReportQuery) (*reportspb.ReportList, error) {
+	f, err := grpcReportFilter(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	rpts, err := QueryReports(ctx, rs.s.Conn, f)
+	if err != nil {
+		rs.s.Logger.Error("failed to query reports", "error", err)
+		return nil, status.Error(codes.Internal, "error making query to database")
+	}
+	list := &reportspb.ReportList{Reports: make([]*reportspb.Report, 0, len(rpts))}
+	for _, r := range rpts {
+		list.Reports = append(list.Reports, reportToProto(r))
+	}
+	grpcSetRows(ctx, len(rpts))
+	return list, nil
+}
+
+// StreamReports sends the reports matching the query as they are read
+// from a cursor.
+func (rs *reportService) StreamReports(q *reportspb.ReportQuery, stream reportspb.ReportService_StreamReportsServer) error {
+	ctx := stream.Context()
+	f, err := grpcReportFilter(ctx, q)
+	if err != nil {
+		return err
+	}
+	rows := 0
+	err = EachReportBatch(ctx, rs.s.Conn, f, cursorBatch, func(batch []StoredReport) error {
+		for _, r := range batch {
+			if err := stream.Send(reportToProto(r)); err != nil {
+				return err
+			}
+			rows++
+		}
+		return nil
+	})
+	grpcSetRows(ctx, rows)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		rs.s.Logger.Error("failed to stream reports", "error", err, "rows", rows)
+		return status.Error(codes.Internal, "error making query to database")
+	}
+	return nil
+}
+
+// Subscribe sends newly stored reports that match the query.
+func (rs *reportService) Subscribe(q *reportspb.ReportQuery, stream reportspb.ReportService_SubscribeServer) error {
+	if rs.s.Broker == nil {
+		return status.Error(codes.Unimplemented, "streaming is not enabled")
+	}
+	ctx := stream.Context()
+	f, err := grpcReportFilter(ctx, q)
+	if err != nil {
+		return err
+	}
+	f.Limit, f.Offset = 0, 0
+
+	sub := rs.s.Broker.Subscribe(streamBuffer)
+	defer sub.Close()
+	sent := 0
+	defer func() { grpcSetRows(ctx, sent) }()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if sub.Dropped() > 0 {
+				return status.Error(codes.DataLoss, "subscriber fell behind and reports were dropped")
+			}
+			if !f.MatchesMessage(m) {
+				continue
+			}
+			if err := stream.Send(reportToProto(messageToStored(m))); err != nil {
+				return err
+			}
+			sent++
+		}
+	}
+}
+
+// grpcReportFilter parses the query as the REST filters would be, and
+// notes them for the usage record.
+func grpcReportFilter(ctx context.Context, q *reportspb.ReportQuery) (ReportFilter, error) {
+	qp, reportType, err := reportQueryValues(q)
+	if err != nil {
+		return ReportFilter{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if call, ok := ctx.Value(grpcCallContextKey{}).(*grpcCall); ok {
+		call.filters = qp.Encode()
+	}
+	f, errResponse := ParseReportFilter(qp, reportType)
+	if errResponse.Code > 0 {
+		return ReportFilter{}, status.Error(codes.InvalidArgument, errResponse.Message)
+	}
+	return f, nil
+}
+
+func grpcSetRows(ctx context.Context, rows int) {
+	if call, ok := ctx.Value(grpcCallContextKey{}).(*grpcCall); ok {
+		call.rows = rows
+	}
+}
+
+// reportQueryValues converts a query to the REST query params.  Magnitude
+// bounds are only accepted by the single type endpoints, so they need
+// exactly one type, which is returned as the endpoint's type.
+func reportQueryValues(q *reportspb.ReportQuery) (url.Values, database.ReportType, error) {
+	qp := url.Values{}
+	set := func(name, v string) {
+		if v != "" {
+			qp.Set(name, v)
+		}
+	}
+	set("type", strings.Join(q.GetTypes(), ","))
+	set("date", q.GetDate())
+	set("from-date", q.GetFromDate())
+	set("to-date", q.GetToDate())
+	set("state", q.GetState())
+	set("office", q.GetOffice())
+	set("county", q.GetCounty())
+	set("location", q.GetLocation())
+	set("direction", q.GetDirection())
+	set("comments", q.GetComments())
+	set("bbox", q.GetBbox())
+	set("measurement", q.GetMeasurement())
+	set("quality", q.GetQuality())
+	if q.Distance != nil {
+		qp.Set("distance", strconv.Itoa(int(q.GetDistance())))
+	}
+	if q.GetLimit() != 0 {
+		qp.Set("limit", strconv.Itoa(int(q.GetLimit())))
+	}
+	if q.GetOffset() != 0 {
+		qp.Set("offset", strconv.Itoa(int(q.GetOffset())))
+	}
+
+	if q.MagnitudeGreaterThan == nil && q.MagnitudeLessThan == nil {
+		return qp, "", nil
+	}
+	if len(q.GetTypes()) != 1 {
+		return nil, "", errMagnitudeType
+	}
+	reportType := database.ReportType(strings.ToLower(strings.TrimSpace(q.GetTypes()[0])))
+	names, ok := magnitudeParams[reportType]
+	if !ok {
+		return nil, "", errMagnitudeType
+	}
+	if q.MagnitudeGreaterThan != nil {
+		qp.Set(names[0], strconv.Itoa(int(q.GetMagnitudeGreaterThan())))
+	}
+	if q.MagnitudeLessThan != nil {
+		qp.Set(names[1], strconv.Itoa(int(q.GetMagnitudeLessThan())))
+	}
+	return qp, reportType, nil
+}
+
+var errMagnitudeType = errors.New("magnitude bounds need exactly one of hail, wind or tornado in types")
+
+// reportToProto converts a stored report to the transformer message of
+// its type.  Times are unix seconds and magnitudes are as stored, as the
+// transformer sends them.
+func reportToProto(row StoredReport) *reportspb.Report {
+	r := &reportspb.Report{
+		Id:          row.ID,
+		Office:      row.NwsOffice.String,
+		Flags:       row.Flags,
+		Review:      row.Review,
+		Measurement: row.Measurement,
+	}
+	t := row.ReportedTime.Time.Unix()
+	switch row.RptType {
+	case database.ReportTypeHail:
+		r.Report = &reportspb.Report_Hail{Hail: &report.HailMsg{
+			Time: t, Size: row.VarCol.Int32, Distance: row.DistFromLocation, Direction: row.HeadingFromLocation,
+			Location: row.Location, County: row.County, State: row.State.String, Lat: row.Latitude.String,
+			Lon: row.Longitude.String, Remarks: row.Comments.String, Type: string(row.RptType),
+		}}
+	case database.ReportTypeWind:
+		r.Report = &reportspb.Report_Wind{Wind: &report.WindMsg{
+			Time: t, Speed: row.VarCol.Int32, Distance: row.DistFromLocation, Direction: row.HeadingFromLocation,
+			Location: row.Location, County: row.County, State: row.State.String, Lat: row.Latitude.String,
+			Lon: row.Longitude.String, Remarks: row.Comments.String, Type: string(row.RptType),
+		}}
+	case database.ReportTypeTornado:
+		r.Report = &reportspb.Report_Tornado{Tornado: &report.TornadoMsg{
+			Time: t, F_Scale: row.VarCol.Int32, Distance: row.DistFromLocation, Direction: row.HeadingFromLocation,
+			Location: row.Location, County: row.County, State: row.State.String, Lat: row.Latitude.String,
+			Lon: row.Longitude.String, Remarks: row.Comments.String, Type: string(row.RptType),
+		}}
+	}
+	return r
+}
+
+// grpcHTTPStatus maps a gRPC code to the HTTP status usage is recorded
+// with.
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
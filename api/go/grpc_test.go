@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jason-costello/weather/accesssvc/api/reportspb"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+func TestReportService(t *testing.T) {
+	broker := pubsub.NewBroker(0)
+	s := NewRouter(RouterConfig{
+		ROKey:  "rokey",
+		RWKey:  "rwkey",
+		Broker: broker,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	lis := bufconn.Listen(1 << 16)
+	go s.GRPC.Serve(lis)
+	defer s.GRPC.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := reportspb.NewReportServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	authed := metadata.AppendToOutgoingContext(ctx, grpcKeyMetadata, "rokey")
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		query *reportspb.ReportQuery
+		want  codes.Code
+	}{
+		{
+			name:  "should reject a call without a key",
+			ctx:   ctx,
+			query: &reportspb.ReportQuery{},
+			want:  codes.Unauthenticated,
+		},
+		{
+			name:  "should reject a wrong key",
+			ctx:   metadata.AppendToOutgoingContext(ctx, grpcKeyMetadata, "wrong"),
+			query: &reportspb.ReportQuery{},
+			want:  codes.Unauthenticated,
+		},
+		{
+			name:  "should reject an unknown type like the rest endpoints",
+			ctx:   authed,
+			query: &reportspb.ReportQuery{Types: []string{"hail", "snow"}},
+			want:  codes.InvalidArgument,
+		},
+		{
+			name:  "should reject magnitude bounds without a single type",
+			ctx:   authed,
+			query: &reportspb.ReportQuery{MagnitudeGreaterThan: ptr(int32(100))},
+			want:  codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.QueryReports(tt.ctx, tt.query)
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+
+	stream, err := client.Subscribe(authed, &reportspb.ReportQuery{Types: []string{"hail"}, State: "KS"})
+	require.NoError(t, err)
+	clean, err := client.Subscribe(authed, &reportspb.ReportQuery{Types: []string{"hail"}, Quality: QualityClean})
+	require.NoError(t, err)
+	// the subscriptions start on the server some time after the call, so
+	// keep publishing until something arrives.
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// flagged, so the clean subscription leaves it out.
+			broker.Publish(pubsub.Message{ID: 6, Flags: []string{"magnitude_outlier"}, Report: database.InsertReportParams{
+				RptType: database.ReportTypeHail,
+				VarCol:  pgtype.Int4{Int32: 500, Valid: true},
+				State:   pgtype.Text{String: "OK", Valid: true},
+			}})
+			for _, typ := range []database.ReportType{database.ReportTypeWind, database.ReportTypeHail} {
+				broker.Publish(pubsub.Message{ID: 7, Report: database.InsertReportParams{
+					RptType: typ,
+					VarCol:  pgtype.Int4{Int32: 175, Valid: true},
+					State:   pgtype.Text{String: "KS", Valid: true},
+				}})
+			}
+		}
+	}()
+	r, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), r.GetId())
+	require.NotNil(t, r.GetHail())
+	assert.Equal(t, int32(175), r.GetHail().GetSize())
+	assert.Equal(t, "KS", r.GetHail().GetState())
+
+	r, err = clean.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), r.GetId(), "flagged reports don't match quality=clean")
+}
+
+func Test_reportQueryValues(t *testing.T) {
+	qp, reportType, err := reportQueryValues(&reportspb.ReportQuery{
+		Types:                []string{"wind"},
+		FromDate:             "2024-05-01",
+		Distance:             ptr(int32(0)),
+		MagnitudeGreaterThan: ptr(int32(58)),
+		Limit:                10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, database.ReportTypeWind, reportType)
+	assert.Equal(t, "distance=0&from-date=2024-05-01&limit=10&speed-greater-than=58&type=wind", qp.Encode())
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
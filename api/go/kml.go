@@ -0,0 +1,254 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report export formats besides json.
+const (
+	FormatKML = "kml"
+	FormatKMZ = "kmz"
+)
+
+const (
+	kmlContentType = "application/vnd.google-earth.kml+xml"
+	kmzContentType = "application/vnd.google-earth.kmz"
+	// kmlIcon is tinted per report type by the styles.
+	kmlIcon = "https://maps.google.com/mapfiles/kml/shapes/placemark_circle.png"
+)
+
+// kmlTypes are the report types placemarks are styled for, with their
+// colors in KML's aabbggrr and the magnitude buckets of their icon sizes.
+// Colors are SPC's, hail green, wind blue and tornadoes red.  Buckets are
+// the ones the stats endpoint uses.
+var kmlTypes = []struct {
+	Type    string
+	Color   string
+	Buckets []int
+}{
+	{Type: "hail", Color: "ff00b400", Buckets: []int{100, 200, 300}},
+	{Type: "wind", Color: "ffff5000", Buckets: []int{58, 75, 100}},
+	{Type: "tornado", Color: "ff0000ff", Buckets: []int{1, 2, 3, 4, 5}},
+}
+
+// kmlScale is the icon scale of a report with an unknown magnitude, each
+// bucket up is kmlScaleStep bigger.
+const (
+	kmlScale     = 0.8
+	kmlScaleStep = 0.2
+)
+
+type kmlPlacemark struct {
+	XMLName     xml.Name      `xml:"Placemark"`
+	ID          string        `xml:"id,attr,omitempty"`
+	Name        string        `xml:"name"`
+	Description string        `xml:"description,omitempty"`
+	TimeStamp   *kmlTimeStamp `xml:"TimeStamp"`
+	StyleURL    string        `xml:"styleUrl"`
+	Data        []kmlData     `xml:"ExtendedData>Data"`
+	Coordinates string        `xml:"Point>coordinates"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlStyle struct {
+	XMLName xml.Name `xml:"Style"`
+	ID      string   `xml:"id,attr"`
+	Color   string   `xml:"IconStyle>color"`
+	Scale   float64  `xml:"IconStyle>scale"`
+	Icon    string   `xml:"IconStyle>Icon>href"`
+}
+
+// kmlWriter writes reports as a KML document one placemark at a time.
+// Nothing is written until the first placemark or Close, so an error
+// before then can still be sent as an error response.
+type kmlWriter struct {
+	w       io.Writer
+	zip     *zip.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+// newKMLWriter writes KML to w, or a KMZ holding it when kmz is set.
+func newKMLWriter(w io.Writer, kmz bool) *kmlWriter {
+	kw := &kmlWriter{w: w}
+	if kmz {
+		kw.zip = zip.NewWriter(w)
+	}
+	return kw
+}
+
+func (kw *kmlWriter) start() error {
+	kw.started = true
+	if kw.zip != nil {
+		doc, err := kw.zip.Create("doc.kml")
+		if err != nil {
+			return err
+		}
+		kw.w = doc
+	}
+	if _, err := io.WriteString(kw.w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Storm reports</name>`); err != nil {
+		return err
+	}
+	kw.enc = xml.NewEncoder(kw.w)
+	for _, t := range kmlTypes {
+		for i := 0; i <= len(t.Buckets)+1; i++ {
+			style := kmlStyle{
+				ID:    kmlStyleID(t.Type, i),
+				Color: t.Color,
+				Scale: kmlScale + kmlScaleStep*float64(i),
+				Icon:  kmlIcon,
+			}
+			if err := kw.enc.Encode(style); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Write adds a report as a placemark.  Reports without a location are
+// skipped.
+func (kw *kmlWriter) Write(row StoredReport) error {
+	r := storedToReport(row)
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(r.Lat), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(r.Lon), 64)
+	if errLat != nil || errLon != nil {
+		return nil
+	}
+	if !kw.started {
+		if err := kw.start(); err != nil {
+			return err
+		}
+	}
+	p := kmlReportPlacemark(r, lat, lon)
+	// the report's Time is when it was stored, the timeline shows when
+	// it happened.
+	if row.ReportedTime.Valid {
+		p.TimeStamp = &kmlTimeStamp{When: row.ReportedTime.Time.UTC().Format(time.RFC3339)}
+	}
+	return kw.enc.Encode(p)
+}
+
+// Close ends the document.
+func (kw *kmlWriter) Close() error {
+	if !kw.started {
+		if err := kw.start(); err != nil {
+			return err
+		}
+	}
+	if err := kw.enc.Flush(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(kw.w, "</Document></kml>\n"); err != nil {
+		return err
+	}
+	if kw.zip != nil {
+		return kw.zip.Close()
+	}
+	return nil
+}
+
+func kmlReportPlacemark(r Report, lat, lon float64) kmlPlacemark {
+	p := kmlPlacemark{
+		Name:        kmlName(r),
+		Description: kmlDescription(r),
+		StyleURL:    "#" + kmlStyleID(r.Type, kmlBucket(r)),
+		Coordinates: strconv.FormatFloat(lon, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64),
+	}
+	if r.ID != 0 {
+		p.ID = "report-" + strconv.FormatInt(r.ID, 10)
+	}
+	for _, d := range []kmlData{
+		{Name: "type", Value: r.Type},
+		{Name: "magnitude", Value: r.VarCol},
+		{Name: "measurement", Value: r.Measurement},
+		{Name: "location", Value: r.Location},
+		{Name: "county", Value: r.County},
+		{Name: "state", Value: r.State},
+		{Name: "office", Value: r.Office},
+		{Name: "flags", Value: strings.Join(r.Flags, ",")},
+	} {
+		if d.Value != "" {
+			p.Data = append(p.Data, d)
+		}
+	}
+	return p
+}
+
+func kmlStyleID(rptType string, bucket int) string {
+	return rptType + "-" + strconv.Itoa(bucket)
+}
+
+// kmlBucket returns the icon size bucket of the report's magnitude, one
+// more than the number of thresholds it reaches, or zero when it is
+// unknown.
+func kmlBucket(r Report) int {
+	m, err := strconv.Atoi(r.VarCol)
+	if err != nil {
+		return 0
+	}
+	for _, t := range kmlTypes {
+		if t.Type != r.Type {
+			continue
+		}
+		bucket := 1
+		for _, b := range t.Buckets {
+			if m >= b {
+				bucket++
+			}
+		}
+		return bucket
+	}
+	return 0
+}
+
+// kmlName is the placemark label, the magnitude in the units SPC uses.
+func kmlName(r Report) string {
+	m, err := strconv.Atoi(r.VarCol)
+	// hail and wind stored as 0 are unknown.
+	if err != nil || (m == 0 && r.Type != "tornado") {
+		return r.Type
+	}
+	switch r.Type {
+	case "hail":
+		return fmt.Sprintf("%.2f in hail", float64(m)/100)
+	case "wind":
+		return fmt.Sprintf("%d mph wind", m)
+	case "tornado":
+		return fmt.Sprintf("EF%d tornado", m)
+	}
+	return r.Type
+}
+
+// kmlDescription is the balloon text, where the report is and its
+// remarks.
+func kmlDescription(r Report) string {
+	where := r.Location
+	if r.Distance > 0 {
+		where = strings.Join(strings.Fields(fmt.Sprintf("%d mi %s of %s", r.Distance, r.Direction, r.Location)), " ")
+	}
+	if r.County != "" {
+		where += ", " + r.County + " County"
+	}
+	if r.State != "" {
+		where += ", " + r.State
+	}
+	if r.Comments == "" {
+		return where
+	}
+	return where + "\n\n" + r.Comments
+}
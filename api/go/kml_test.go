@@ -0,0 +1,121 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type kmlDoc struct {
+	Styles     []kmlStyle     `xml:"Document>Style"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+func writeKML(t *testing.T, kmz bool, reports ...StoredReport) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	kw := newKMLWriter(&buf, kmz)
+	for _, r := range reports {
+		require.NoError(t, kw.Write(r))
+	}
+	require.NoError(t, kw.Close())
+	return buf.Bytes()
+}
+
+func TestKMLWriter(t *testing.T) {
+	reported := time.Date(2024, 5, 6, 21, 4, 0, 0, time.UTC)
+	hail := StoredReport{
+		ID: 12,
+		Report: database.Report{
+			RptType:          database.ReportTypeHail,
+			ReportedTime:     pgtype.Timestamptz{Time: reported, Valid: true},
+			CreatedAt:        pgtype.Timestamptz{Time: reported.Add(40 * time.Minute), Valid: true},
+			VarCol:           pgtype.Int4{Int32: 175, Valid: true},
+			DistFromLocation: 2,
+			Location:         "Norman",
+			County:           "Cleveland",
+			State:            pgtype.Text{String: "OK", Valid: true},
+			Latitude:         pgtype.Text{String: "35.22", Valid: true},
+			Longitude:        pgtype.Text{String: "-97.44", Valid: true},
+			Comments:         pgtype.Text{String: "Golf ball hail <broke> windows & siding.", Valid: true},
+		},
+	}
+	unlocated := StoredReport{Report: database.Report{RptType: database.ReportTypeWind, VarCol: pgtype.Int4{Int32: 60, Valid: true}}}
+
+	var doc kmlDoc
+	require.NoError(t, xml.Unmarshal(writeKML(t, false, hail, unlocated), &doc))
+	assert.Len(t, doc.Styles, 5+5+7)
+	require.Len(t, doc.Placemarks, 1, "reports without a location are skipped")
+	p := doc.Placemarks[0]
+	assert.Equal(t, "report-12", p.ID)
+	assert.Equal(t, "1.75 in hail", p.Name)
+	require.NotNil(t, p.TimeStamp)
+	assert.Equal(t, "2024-05-06T21:04:00Z", p.TimeStamp.When, "the time it was reported, not stored")
+	assert.Equal(t, "#hail-2", p.StyleURL)
+	assert.Equal(t, "-97.44,35.22", p.Coordinates)
+	assert.Equal(t, "2 mi of Norman, Cleveland County, OK\n\nGolf ball hail <broke> windows & siding.", p.Description)
+
+	t.Run("should wrap the document in a kmz", func(t *testing.T) {
+		b := writeKML(t, true, hail)
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 1)
+		assert.Equal(t, "doc.kml", zr.File[0].Name)
+		f, err := zr.File[0].Open()
+		require.NoError(t, err)
+		kml, err := io.ReadAll(f)
+		require.NoError(t, err)
+		var doc kmlDoc
+		require.NoError(t, xml.Unmarshal(kml, &doc))
+		assert.Len(t, doc.Placemarks, 1)
+	})
+
+	t.Run("should write an empty document when nothing matches", func(t *testing.T) {
+		var doc kmlDoc
+		require.NoError(t, xml.Unmarshal(writeKML(t, false), &doc))
+		assert.Empty(t, doc.Placemarks)
+	})
+}
+
+func Test_kmlBucket(t *testing.T) {
+	tests := []struct {
+		report Report
+		want   int
+	}{
+		{report: Report{Type: "hail", VarCol: "75"}, want: 1},
+		{report: Report{Type: "hail", VarCol: "300"}, want: 4},
+		{report: Report{Type: "wind", VarCol: "65"}, want: 2},
+		{report: Report{Type: "wind"}, want: 0},
+		{report: Report{Type: "tornado", VarCol: "0"}, want: 1},
+		{report: Report{Type: "tornado", VarCol: "5"}, want: 6},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, kmlBucket(tt.report), "%s %s", tt.report.Type, tt.report.VarCol)
+	}
+}
+
+func Test_kmlName(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Report
+		want string
+	}{
+		{name: "should give hail in inches", r: Report{Type: "hail", VarCol: "175"}, want: "1.75 in hail"},
+		{name: "should leave out an unknown hail size", r: Report{Type: "hail", VarCol: "0"}, want: "hail"},
+		{name: "should leave out an unknown wind speed", r: Report{Type: "wind", VarCol: "0"}, want: "wind"},
+		{name: "should keep EF0", r: Report{Type: "tornado", VarCol: "0"}, want: "EF0 tornado"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, kmlName(tt.r))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import "time"
+
+// ClimatologyBaseline is the distribution of a period's report count over
+// the years of history before this one that cover it.
+type ClimatologyBaseline struct {
+	Years      int       `json:"years"`
+	Mean       float64   `json:"mean"`
+	StdDev     float64   `json:"stddev"`
+	P10        float64   `json:"p10"`
+	P25        float64   `json:"p25"`
+	P50        float64   `json:"p50"`
+	P75        float64   `json:"p75"`
+	P90        float64   `json:"p90"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// Anomaly compares a count with its baseline.
+type Anomaly struct {
+	// ZScore is the number of standard deviations the count is from the
+	// mean, null when the count never varies.
+	ZScore *float64 `json:"z_score"`
+	// Ratio is the count over the mean, null when the mean is zero.
+	Ratio *float64 `json:"ratio"`
+}
+
+// ClimatologyComparison is a period's report count next to its
+// climatological baseline.
+type ClimatologyComparison struct {
+	State  string `json:"state"`
+	Type   string `json:"type"`
+	Period string `json:"period"`
+	// Start and End bound the period, End is exclusive.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Complete is false while the period is still going, the count will
+	// only grow.
+	Complete bool  `json:"complete"`
+	Count    int64 `json:"count"`
+	// Baseline and Anomaly are null when there is no history for the
+	// state and type.
+	Baseline *ClimatologyBaseline `json:"baseline"`
+	Anomaly  *Anomaly             `json:"anomaly"`
+}
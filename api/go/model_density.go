@@ -0,0 +1,66 @@
+package api
+
+import (
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// FeatureCollection is a GeoJSON FeatureCollection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature.
+type Feature struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	// Geometry is a PolygonGeometry or a LineStringGeometry.
+	Geometry   any `json:"geometry"`
+	Properties any `json:"properties"`
+}
+
+// PolygonGeometry is a GeoJSON Polygon.
+type PolygonGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates geo.Polygon `json:"coordinates"`
+}
+
+// Density is the number of reports in each cell of a grid.
+type Density struct {
+	// Cell is grid or hex.
+	Cell string `json:"cell"`
+	// Size is the width of a grid cell, or the distance from the center
+	// of a hexagon to its corners, in degrees.
+	Size  float64       `json:"size"`
+	Cells []DensityCell `json:"cells"`
+}
+
+// DensityCell is the reports binned into one cell.
+type DensityCell struct {
+	// ID is the cell's grid x/y, hex q/r, or tile z/x/y.
+	ID     string    `json:"id"`
+	Center geo.Point `json:"center"`
+	Count  int64     `json:"count"`
+	// MaxMagnitude is the largest magnitude of each report type in the
+	// cell, in the units the v1 endpoints use.
+	MaxMagnitude map[string]int32 `json:"max_magnitude"`
+
+	outline geo.Polygon
+}
+
+// featureCollection turns cells into GeoJSON.
+func featureCollection(cells []DensityCell) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(cells))}
+	for _, c := range cells {
+		fc.Features = append(fc.Features, Feature{
+			Type:     "Feature",
+			ID:       c.ID,
+			Geometry: PolygonGeometry{Type: "Polygon", Coordinates: c.outline},
+			Properties: map[string]any{
+				"count":         c.Count,
+				"max_magnitude": c.MaxMagnitude,
+			},
+		})
+	}
+	return fc
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// StormEvent is reports of the same type close together in space and
+// time, most often one storm reported from several towns.
+type StormEvent struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Start and End are the times of the first and last reports.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Centroid is the mean position of the reports.
+	Centroid geo.Point `json:"centroid"`
+	// MaxMagnitude is the largest magnitude of the reports, in the units
+	// the v1 endpoints use, null when none has one.
+	MaxMagnitude *int32   `json:"max_magnitude"`
+	States       []string `json:"states"`
+	ReportIDs    []int64  `json:"report_ids"`
+}
+
+// StormEvents is a page of events, newest first.
+type StormEvents struct {
+	Events []StormEvent `json:"events"`
+}
+
+// EventRebuild is the outcome of clustering every report again.
+type EventRebuild struct {
+	Events  int `json:"events"`
+	Reports int `json:"reports"`
+}
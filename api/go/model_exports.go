@@ -0,0 +1,28 @@
+package api
+
+import (
+	"time"
+)
+
+// ExportJob is a bulk export being built in the background.
+type ExportJob struct {
+	ID string `json:"id"`
+	// Format is the file format, shapefile or parquet.
+	Format string `json:"format"`
+	// Status is pending, running, complete or failed.
+	Status string `json:"status"`
+	// Rows is the number of reports in the file once it is complete.
+	Rows      *int64 `json:"rows,omitempty"`
+	SizeBytes *int64 `json:"size_bytes,omitempty"`
+	// Error says why a failed export failed.
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt is when a finished export is deleted.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// StatusURL is where to poll the export.
+	StatusURL string `json:"status_url"`
+	// DownloadURL is where to get the file once the export is complete.
+	DownloadURL string `json:"download_url,omitempty"`
+}
@@ -0,0 +1,30 @@
+package api
+
+import "encoding/json"
+
+// GraphQLRequest is a GraphQL operation, the body of a POST to
+// /api/v1/graphql and the payload of a subscribe message.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// GraphQLSocketMessage is a message of the graphql-transport-ws protocol,
+// see https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+type GraphQLSocketMessage struct {
+	// Type is connection_init, connection_ack, ping, pong, subscribe,
+	// next, error or complete.
+	Type string `json:"type"`
+	// ID is the operation a subscribe, next, error or complete message is
+	// about, it is chosen by the client.
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Office is a National Weather Service Weather Forecast Office.
+type Office struct {
+	ID    string  `json:"id"`
+	City  *string `json:"city"`
+	State *string `json:"state"`
+}
@@ -9,6 +9,8 @@ type Reports struct {
 }
 
 type Report struct {
+	// Report type, hail, wind, or tornado.
+	Type string `json:"Type,omitempty"`
 	// Date and time the report was generated in UTC time. The date format is YYYY-MM-DD and the time format is HH:MM:SS.SSSZ using 24 hour mode (3PM = 15, 3AM = 03).
 	Time   time.Time `json:"Time,omitempty"`
 	VarCol string    `json:"Size,omitempty"`
@@ -30,6 +32,15 @@ type Report struct {
 	Comments string `json:"Comments,omitempty"`
 	// Reporting weather office.
 	Office string `json:"Office,omitempty"`
+	// Id of the stored report, used to review it.
+	ID int64 `json:"Id,omitempty"`
+	// Data quality flags raised when the report was ingested.
+	Flags []string `json:"Flags,omitempty"`
+	// Review status of a flagged report, accepted or rejected.
+	Review string `json:"Review,omitempty"`
+	// How a wind report's speed was arrived at, measured, estimated or
+	// unknown when there is no speed.
+	Measurement string `json:"Measurement,omitempty"`
 }
 
 func (r Reports) ToHailReports() HailReports {
@@ -47,6 +58,9 @@ func (r Reports) ToHailReports() HailReports {
 			Lon:       rpt.Lon,
 			Comments:  rpt.Comments,
 			Office:    rpt.Office,
+			ID:        rpt.ID,
+			Flags:     rpt.Flags,
+			Review:    rpt.Review,
 		})
 	}
 	return hrs
@@ -56,17 +70,21 @@ func (r Reports) ToWindReports() WindReports {
 	var wrs WindReports
 	for _, rpt := range r.Reports {
 		wrs.Reports = append(wrs.Reports, WindReport{
-			Time:      rpt.Time,
-			Speed:     rpt.VarCol,
-			Direction: rpt.Direction,
-			Distance:  rpt.Distance,
-			Location:  rpt.Location,
-			County:    rpt.County,
-			State:     rpt.State,
-			Lat:       rpt.Lat,
-			Lon:       rpt.Lon,
-			Comments:  rpt.Comments,
-			Office:    rpt.Office,
+			Time:        rpt.Time,
+			Speed:       rpt.VarCol,
+			Direction:   rpt.Direction,
+			Distance:    rpt.Distance,
+			Location:    rpt.Location,
+			County:      rpt.County,
+			State:       rpt.State,
+			Lat:         rpt.Lat,
+			Lon:         rpt.Lon,
+			Comments:    rpt.Comments,
+			Office:      rpt.Office,
+			ID:          rpt.ID,
+			Flags:       rpt.Flags,
+			Review:      rpt.Review,
+			Measurement: rpt.Measurement,
 		})
 	}
 	return wrs
@@ -87,7 +105,26 @@ func (r Reports) ToTornadoReports() TornadoReports {
 			Lon:       rpt.Lon,
 			Comments:  rpt.Comments,
 			Office:    rpt.Office,
+			ID:        rpt.ID,
+			Flags:     rpt.Flags,
+			Review:    rpt.Review,
 		})
 	}
 	return trs
 }
+
+func (r Reports) ToStormReports() StormReports {
+	var srs StormReports
+	for _, rpt := range r.Reports {
+		one := Reports{Reports: []Report{rpt}}
+		switch rpt.Type {
+		case "hail":
+			srs.HailReports = append(srs.HailReports, one.ToHailReports().Reports...)
+		case "wind":
+			srs.WindReports = append(srs.WindReports, one.ToWindReports().Reports...)
+		case "tornado":
+			srs.TornadoReports = append(srs.TornadoReports, one.ToTornadoReports().Reports...)
+		}
+	}
+	return srs
+}
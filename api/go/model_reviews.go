@@ -0,0 +1,37 @@
+package api
+
+import (
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/quality"
+)
+
+// ReviewRequest accepts or rejects a flagged report.
+type ReviewRequest struct {
+	// Status is accepted or rejected.  Rejected reports are hidden from
+	// every report endpoint.
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Review is the outcome of reviewing a report.
+type Review struct {
+	ReportID   int64     `json:"report_id"`
+	Status     string    `json:"status"`
+	Note       string    `json:"note,omitempty"`
+	KeyID      string    `json:"key_id"`
+	ReviewedAt time.Time `json:"reviewed_at"`
+}
+
+// FlaggedReport is a report in the review queue with the reasons it was
+// flagged.
+type FlaggedReport struct {
+	Report Report         `json:"report"`
+	Flags  []quality.Flag `json:"flags"`
+}
+
+// ReviewQueue is a page of reports with the same review status.
+type ReviewQueue struct {
+	Status  string          `json:"status"`
+	Reports []FlaggedReport `json:"reports"`
+}
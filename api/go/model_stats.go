@@ -0,0 +1,11 @@
+package api
+
+// StatsCounts is the number of reports in each group.
+type StatsCounts struct {
+	GroupBy []string `json:"group_by"`
+	// Total is the number of reports in every group, including groups
+	// past the limit.
+	Total int64 `json:"total"`
+	// Counts has a key for each group by column, and count.
+	Counts []map[string]any `json:"counts"`
+}
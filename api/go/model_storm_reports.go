@@ -1,9 +1,9 @@
 package api
 
 type StormReports struct {
-	HailReports *HailReport `json:"HailReports,omitempty"`
+	HailReports []HailReport `json:"HailReports,omitempty"`
 
-	TornadoReports *TornadoReport `json:"TornadoReports,omitempty"`
+	TornadoReports []TornadoReport `json:"TornadoReports,omitempty"`
 
-	WindReports *WindReport `json:"WindReports,omitempty"`
+	WindReports []WindReport `json:"WindReports,omitempty"`
 }
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// Swath is the area a cluster of hail or wind reports probably covers,
+// the reports' hull buffered by a distance.
+type Swath struct {
+	// ID is the type and the first report id, e.g. hail-123.
+	ID    string    `json:"id"`
+	Type  string    `json:"type"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// MaxMagnitude is the largest magnitude of the reports, in the units
+	// the v1 endpoints use, null when none has one.
+	MaxMagnitude    *int32  `json:"max_magnitude"`
+	Reports         int     `json:"reports"`
+	ReportIDs       []int64 `json:"report_ids"`
+	AreaSquareMiles float64 `json:"area_sq_miles"`
+
+	outline geo.Polygon
+}
+
+// swathFeatureCollection turns swaths into GeoJSON.
+func swathFeatureCollection(swaths []Swath) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(swaths))}
+	for _, s := range swaths {
+		fc.Features = append(fc.Features, Feature{
+			Type:       "Feature",
+			ID:         s.ID,
+			Geometry:   PolygonGeometry{Type: "Polygon", Coordinates: s.outline},
+			Properties: s,
+		})
+	}
+	return fc
+}
@@ -27,4 +27,10 @@ type TornadoReport struct {
 	Comments string `json:"Comments,omitempty"`
 	// Reporting weather office.
 	Office string `json:"Office,omitempty"`
+	// Id of the stored report, used to review it.
+	ID int64 `json:"Id,omitempty"`
+	// Data quality flags raised when the report was ingested.
+	Flags []string `json:"Flags,omitempty"`
+	// Review status of a flagged report, accepted or rejected.
+	Review string `json:"Review,omitempty"`
 }
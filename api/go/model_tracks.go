@@ -0,0 +1,47 @@
+package api
+
+import (
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// LineStringGeometry is a GeoJSON LineString.
+type LineStringGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates []geo.Point `json:"coordinates"`
+}
+
+// TornadoTrack is the path of a tornado reconstructed from its reports.
+type TornadoTrack struct {
+	// ID is the first report id, e.g. tornado-123.
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// MaxRating is the highest EF rating reported, null when none is.
+	MaxRating   *int32  `json:"max_rating"`
+	LengthMiles float64 `json:"length_miles"`
+	// Bearing is the direction from the start of the track to its end in
+	// degrees clockwise from north, Direction is its compass point.
+	Bearing   float64 `json:"bearing"`
+	Direction string  `json:"direction"`
+	Reports   int     `json:"reports"`
+	// ReportIDs are the contributing reports in time order.
+	ReportIDs []int64 `json:"report_ids"`
+
+	path []geo.Point
+}
+
+// trackFeatureCollection turns tracks into GeoJSON.
+func trackFeatureCollection(tracks []TornadoTrack) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: make([]Feature, 0, len(tracks))}
+	for _, t := range tracks {
+		fc.Features = append(fc.Features, Feature{
+			Type:       "Feature",
+			ID:         t.ID,
+			Geometry:   LineStringGeometry{Type: "LineString", Coordinates: t.path},
+			Properties: t,
+		})
+	}
+	return fc
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"time"
+)
+
+type UsageRollup struct {
+	// Truncated sha256 of the api key the usage belongs to.
+	KeyID string `json:"key_id"`
+	// Start of the hour, in UTC, the usage was aggregated into.
+	Hour time.Time `json:"hour"`
+	// Route template that was called, e.g. /api/v1/report/hail.
+	Route string `json:"route"`
+	// Number of requests made in the hour.
+	Requests int64 `json:"requests"`
+	// Number of report rows returned across all requests.
+	Rows int64 `json:"rows"`
+	// Number of response body bytes sent across all requests.
+	Bytes int64 `json:"bytes"`
+	// Average request latency in milliseconds.
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	// Slowest request latency in milliseconds.
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+type AccountUsage struct {
+	KeyID string        `json:"key_id"`
+	From  time.Time     `json:"from"`
+	To    time.Time     `json:"to"`
+	Usage []UsageRollup `json:"usage"`
+}
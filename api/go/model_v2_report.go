@@ -0,0 +1,46 @@
+package api
+
+import (
+	"time"
+)
+
+// ReportsV2 is the v2 response of every report endpoint.
+type ReportsV2 struct {
+	// Units is the unit system magnitudes and distances are in, metric or
+	// imperial.
+	Units   string     `json:"units"`
+	Reports []ReportV2 `json:"reports"`
+}
+
+// ReportV2 is a report of any type with typed values.  Values that aren't
+// known are null rather than zero.
+type ReportV2 struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Time of the event in UTC.
+	Time time.Time `json:"time"`
+	// Magnitude is the hail size, wind speed or tornado EF rating.
+	Magnitude *Quantity `json:"magnitude"`
+	// Measurement is how a wind report's speed was arrived at, null for
+	// other types.
+	Measurement *string `json:"measurement"`
+	// Distance and Direction place the report relative to Location.
+	Distance  Quantity `json:"distance"`
+	Direction string   `json:"direction"`
+	Location  string   `json:"location"`
+	County    string   `json:"county"`
+	State     string   `json:"state"`
+	Lat       *float64 `json:"lat"`
+	Lon       *float64 `json:"lon"`
+	Comments  string   `json:"comments"`
+	// Office is the id of the issuing Weather Forecast Office.
+	Office *string  `json:"office"`
+	Flags  []string `json:"flags"`
+	Review *string  `json:"review"`
+}
+
+// Quantity is a value and its unit.
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
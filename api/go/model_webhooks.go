@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookRequest registers an endpoint to be sent reports that land
+// inside an area.
+type WebhookRequest struct {
+	// URL is the http or https endpoint reports are POSTed to.
+	URL string `json:"url"`
+	// Area is a GeoJSON Polygon, MultiPolygon, Feature or FeatureCollection.
+	Area json.RawMessage `json:"area"`
+	// Types limits the report types sent, all types are sent when empty.
+	Types []string `json:"types,omitempty"`
+	// MinHailSize skips hail reports smaller than this, in hundredths of an inch.
+	MinHailSize *int32 `json:"min_hail_size,omitempty"`
+	// MinWindSpeed skips wind reports slower than this, in mph.
+	MinWindSpeed *int32 `json:"min_wind_speed,omitempty"`
+}
+
+// Webhook is a registered endpoint.
+type Webhook struct {
+	ID           int64           `json:"id"`
+	URL          string          `json:"url"`
+	Area         json.RawMessage `json:"area"`
+	Types        []string        `json:"types,omitempty"`
+	MinHailSize  *int32          `json:"min_hail_size,omitempty"`
+	MinWindSpeed *int32          `json:"min_wind_speed,omitempty"`
+	// Secret signs every payload, it is only returned when the webhook is
+	// created.
+	Secret  string `json:"secret,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// ConsecutiveFailures counts deliveries that failed every attempt, the
+	// webhook is disabled once it reaches the limit.
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt to deliver a payload.
+type WebhookDelivery struct {
+	DeliveryID  string    `json:"delivery_id"`
+	EventID     int64     `json:"event_id"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	LatencyMs   float64   `json:"latency_ms"`
+	Succeeded   bool      `json:"succeeded"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// WebhookPayload is the body POSTed to a webhook.  Retries of a delivery
+// send the same body, receivers can use DeliveryID to drop duplicates.
+type WebhookPayload struct {
+	Event      string    `json:"event"`
+	DeliveryID string    `json:"delivery_id"`
+	EventID    int64     `json:"event_id"`
+	WebhookID  int64     `json:"webhook_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Report     Report    `json:"report"`
+}
@@ -0,0 +1,29 @@
+package api
+
+// SocketRequest is a message sent by a websocket client.
+type SocketRequest struct {
+	// Action is subscribe or unsubscribe.  Subscribing with an id that is
+	// already in use replaces that subscription's filter.
+	Action string `json:"action"`
+	// ID names the subscription, it is chosen by the client.
+	ID string `json:"id"`
+	// Filter takes the same params as the report endpoints' query string,
+	// e.g. {"type": "hail,wind", "bbox": "-98,34,-96,36"}.
+	Filter map[string]string `json:"filter,omitempty"`
+}
+
+// SocketMessage is a message sent to a websocket client.
+type SocketMessage struct {
+	// Type is subscribed, unsubscribed, report, dropped or error.
+	Type string `json:"type"`
+	// ID is the subscription a subscribed, unsubscribed or error message
+	// is about.
+	ID string `json:"id,omitempty"`
+	// Subscriptions lists every subscription a report matched.
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	Report        *Report  `json:"report,omitempty"`
+	// Dropped is how many reports were skipped because the connection
+	// couldn't keep up.
+	Dropped int64  `json:"dropped,omitempty"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+
+	openapispec "github.com/jason-costello/weather/accesssvc/api/open-api-spec-file"
+)
+
+// LoadSpec parses and validates the embedded OpenAPI specification.
+func LoadSpec() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapispec.YAML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	if err := doc.Validate(openapi3.NewLoader().Context); err != nil {
+		return nil, fmt.Errorf("openapi spec is invalid: %w", err)
+	}
+	return doc, nil
+}
+
+// OpenAPIValidator returns middleware that rejects requests that don't
+// match the spec with a 400.  When validateResponses is set responses
+// are buffered and checked as well, anything that doesn't match the spec
+// is replaced with a 500.  Response validation is meant for tests.
+func OpenAPIValidator(doc *openapi3.T, validateResponses bool, logger *slog.Logger) (echo.MiddlewareFunc, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build openapi router: %w", err)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route, pathParams, err := router.FindRoute(req)
+			if err != nil {
+				// not part of the api, let echo decide what to do with it.
+				return next(c)
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					// key auth middleware has already checked the key.
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			}
+			if err := validateQueryNames(route, req); err != nil {
+				return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: err.Error()})
+			}
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: requestErrorMessage(err)})
+			}
+
+			// a websocket takes over the connection, there is no response
+			// to buffer.
+			if !validateResponses || c.IsWebSocket() {
+				return next(c)
+			}
+
+			res := c.Response()
+			original := res.Writer
+			recorder := &bufferedResponse{header: original.Header()}
+			res.Writer = recorder
+			err = next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			res.Writer = original
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+
+			if verr := openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 recorder.status,
+				Header:                 original.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			}); verr != nil {
+				logger.Error("response does not match openapi spec", "path", req.URL.Path, "status", recorder.status, "error", verr)
+				original.Header().Del(echo.HeaderContentLength)
+				original.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				original.WriteHeader(http.StatusInternalServerError)
+				return json.NewEncoder(original).Encode(ApiResponse{Code: 500, Message: "response failed validation: " + verr.Error()})
+			}
+
+			original.WriteHeader(recorder.status)
+			_, werr := original.Write(recorder.body.Bytes())
+			return werr
+		}
+	}, nil
+}
+
+// validateQueryNames rejects query params the operation doesn't declare,
+// kin-openapi only checks the params that are documented.
+func validateQueryNames(route *routers.Route, req *http.Request) error {
+	declared := make(map[string]bool)
+	for _, params := range []openapi3.Parameters{route.PathItem.Parameters, route.Operation.Parameters} {
+		for _, p := range params {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInQuery {
+				declared[p.Value.Name] = true
+			}
+		}
+	}
+	for name := range req.URL.Query() {
+		if !declared[name] {
+			return fmt.Errorf("unknown query param %q", name)
+		}
+	}
+	return nil
+}
+
+// requestErrorMessage trims the kin-openapi error down to something a
+// client can act on.
+func requestErrorMessage(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) && reqErr.Parameter != nil {
+		return fmt.Sprintf("invalid %s param %q: %v", reqErr.Parameter.In, reqErr.Parameter.Name, reqErr.Err)
+	}
+	return err.Error()
+}
+
+// bufferedResponse holds a response until it has been validated.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+// Flush does nothing, streamed responses are held like any other until
+// they have been validated.
+func (b *bufferedResponse) Flush() {}
+
+func init() {
+	openapi3filter.RegisterBodyDecoder(ndjsonContentType, ndjsonBodyDecoder)
+}
+
+// ndjsonBodyDecoder decodes newline delimited JSON as an array with an
+// item per line, so NDJSON responses are documented and validated as an
+// array of what each line holds.
+func ndjsonBodyDecoder(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+	items := []any{}
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+		}
+		items = append(items, v)
+	}
+}
+
+// GetOpenAPISpec serves the specification as JSON.
+func (s ServerAndDB) GetOpenAPISpec(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, s.specJSON)
+}
+
+// GetDocs serves Swagger UI pointed at the specification.
+func (s ServerAndDB) GetDocs(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUI)
+}
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>StormSync API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+  window.onload = () => {
+    window.ui = SwaggerUIBundle({ url: '/api/v1/openapi.json', dom_id: '#swagger-ui' });
+  };
+</script>
+</body>
+</html>
+`
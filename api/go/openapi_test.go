@@ -0,0 +1,289 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRouter(t *testing.T) ServerAndDB {
+	t.Helper()
+	return NewRouter(RouterConfig{
+		ROKey:             "rokey",
+		RWKey:             "rwkey",
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ValidateResponses: true,
+	})
+}
+
+func TestLoadSpec(t *testing.T) {
+	_, err := LoadSpec()
+	assert.NoError(t, err)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	spec, err := LoadSpec()
+	if err != nil {
+		t.Fatal("unable to load spec: ", err)
+	}
+	s := testRouter(t)
+	for _, r := range s.Web.Routes() {
+		item := spec.Paths.Find(echoPathToOpenAPI(r.Path))
+		if !assert.NotNil(t, item, "route %s %s missing from spec", r.Method, r.Path) {
+			continue
+		}
+		assert.NotNil(t, item.GetOperation(r.Method), "operation %s %s missing from spec", r.Method, r.Path)
+	}
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "should reject a state that isn't two letters",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/hail?state=FLA",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject params that aren't in the spec",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/wind?from_date=2024-05-01",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a limit out of range",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/tornado?limit=0",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an unknown report type",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/all?type=hail,snow",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an office that isn't a known office",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/hail?office=XYZ",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should only accept measurement on wind reports",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/hail?measurement=measured",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject unknown units",
+			method:     http.MethodGet,
+			target:     "/api/v2/report/hail?units=kelvin",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an unknown group by column",
+			method:     http.MethodGet,
+			target:     "/api/v1/stats/counts?group-by=state,planet",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a zoom past the pre-aggregated zooms",
+			method:     http.MethodGet,
+			target:     "/api/v1/tiles/11/0/0",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should require an export format",
+			method:     http.MethodPost,
+			target:     "/api/v1/exports?state=OK",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an unknown report format",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/hail?format=shp",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a vector tile that isn't mvt",
+			method:     http.MethodGet,
+			target:     "/api/v1/tiles/hail/4/3/6.png",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a vector tile past the deepest zoom",
+			method:     http.MethodGet,
+			target:     "/api/v1/tiles/all/17/0/0.mvt",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a density cell size that is too small",
+			method:     http.MethodGet,
+			target:     "/api/v1/density?cell=hex&size=0.001",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an unknown quality",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/all?quality=bogus",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject an event filter on magnitude",
+			method:     http.MethodGet,
+			target:     "/api/v1/events?type=hail&size-greater-than=100",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject a min-reports of 0",
+			method:     http.MethodGet,
+			target:     "/api/v1/events?min-reports=0",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should require the day of swaths",
+			method:     http.MethodGet,
+			target:     "/api/v1/swaths?type=hail",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should not build tornado swaths",
+			method:     http.MethodGet,
+			target:     "/api/v1/swaths?date=2024-05-06&type=tornado",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should require a start for tornado tracks",
+			method:     http.MethodGet,
+			target:     "/api/v1/tornado/tracks?state=OK",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should limit tornado tracks to a month",
+			method:     http.MethodGet,
+			target:     "/api/v1/tornado/tracks?from-date=2024-04-01&to-date=2024-06-01",
+			key:        "rokey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should not accept the read only key on the event rebuild",
+			method:     http.MethodPost,
+			target:     "/api/v1/admin/events/rebuild",
+			key:        "rokey",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should reject a review that isn't accepted or rejected",
+			method:     http.MethodPost,
+			target:     "/api/v1/admin/reviews/12",
+			key:        "rwkey",
+			body:       `{"status":"maybe"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should not accept the read only key on review routes",
+			method:     http.MethodGet,
+			target:     "/api/v1/admin/reviews",
+			key:        "rokey",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should check the key before validating",
+			method:     http.MethodGet,
+			target:     "/api/v1/report/hail?state=FLA",
+			key:        "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should reject a maint request without a body",
+			method:     http.MethodPost,
+			target:     "/api/v1/maint/report",
+			key:        "rwkey",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should accept a valid maint request and response",
+			method:     http.MethodPost,
+			target:     "/api/v1/maint/report",
+			key:        "rwkey",
+			body:       `{"dates":["2024-05-09"]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should not accept the read only key on maint routes",
+			method:     http.MethodPost,
+			target:     "/api/v1/maint/report",
+			key:        "rokey",
+			body:       `{"dates":["2024-05-09"]}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should serve the spec without a key",
+			method:     http.MethodGet,
+			target:     "/api/v1/openapi.json",
+			wantStatus: http.StatusOK,
+		},
+	}
+	s := testRouter(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, body)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.key != "" {
+				req.Header.Set("X-Api-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			s.Web.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// echoPathToOpenAPI turns /webhooks/:id into /webhooks/{id}.
+func echoPathToOpenAPI(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,662 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/nws"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+// BBox is a lon/lat bounding box in WGS84 degrees.
+type BBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// Contains reports whether the point is inside the box, edges included.
+func (b BBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// ReportFilter holds every filter the report endpoints accept.  The zero
+// value matches all reports.
+type ReportFilter struct {
+	// Types limits the report types returned, empty means all types.
+	Types []database.ReportType
+	// From is the inclusive lower bound on reported_time.
+	From time.Time
+	// To is the exclusive upper bound on reported_time.
+	To    time.Time
+	State string
+	// Office is the id of the issuing Weather Forecast Office.
+	Office    string
+	County    string
+	Location  string
+	Direction string
+	Distance  *int32
+	// Comments matches reports whose comments contain the value.
+	Comments string
+	// Measurement limits wind reports to one of the nws.Wind measurement
+	// types, setting it leaves out every other report type.
+	Measurement string
+	BBox        *BBox
+	// MinMagnitude and MaxMagnitude are exclusive bounds on var_col.
+	MinMagnitude *int32
+	MaxMagnitude *int32
+	// AfterID is the exclusive lower bound on id, and orders the reports
+	// by id, the order they were stored in.  Streams use it to replay what
+	// a reconnecting client missed.
+	AfterID int64
+	// Quality is QualityClean or QualityFlagged, empty means any quality.
+	Quality string
+	// Review limits the reports to a review status, empty means every
+	// report that hasn't been rejected.
+	Review string
+	// Significant limits the reports to the ones worth alerting on, see
+	// isSignificant.
+	Significant bool
+	// Newest orders the reports newest first, so a limit keeps the most
+	// recent.
+	Newest bool
+	Limit  int
+	Offset int
+}
+
+// Thresholds of a significant report, SPC's significant severe hail and
+// wind.  Every tornado is significant.
+const (
+	significantHail = 200 // hundredths of an inch
+	significantWind = 75  // mph, 65 knots
+)
+
+// isSignificant reports whether a report is one partners alert on.
+func isSignificant(t database.ReportType, v pgtype.Int4) bool {
+	switch t {
+	case database.ReportTypeTornado:
+		return true
+	case database.ReportTypeHail:
+		return v.Valid && v.Int32 >= significantHail
+	case database.ReportTypeWind:
+		return v.Valid && v.Int32 >= significantWind
+	}
+	return false
+}
+
+// Values of ReportFilter.Quality.
+const (
+	// QualityClean is reports without quality flags, or whose flags were
+	// reviewed and accepted.
+	QualityClean = "clean"
+	// QualityFlagged is reports with quality flags.
+	QualityFlagged = "flagged"
+)
+
+// Values of ReportFilter.Review, and of a report's review status.
+const (
+	ReviewPending  = "pending"
+	ReviewAccepted = "accepted"
+	ReviewRejected = "rejected"
+)
+
+// StoredReport is a report as it was stored, with its id and quality
+// flags.
+type StoredReport struct {
+	database.Report
+	ID    int64
+	Flags []string
+	// Review is ReviewAccepted, ReviewRejected or empty when the report
+	// hasn't been reviewed.
+	Review string
+	// Measurement is how a wind report's speed was arrived at, empty for
+	// other types.
+	Measurement string
+}
+
+// magnitudeParams maps a report type to the names of the query params
+// used to filter on its var_col, as documented in the spec.
+var magnitudeParams = map[database.ReportType][2]string{
+	database.ReportTypeHail:    {"size-greater-than", "size-less-than"},
+	database.ReportTypeWind:    {"speed-greater-than", "speed-less-than"},
+	database.ReportTypeTornado: {"f-scale-greater-than", "f-scale-less-than"},
+}
+
+// reportFilterParams are the query params ParseReportFilter reads for
+// every report type, type is only read when the endpoint serves them all.
+var reportFilterParams = []string{
+	"date", "from-date", "to-date", "state", "office", "county", "location", "direction", "comments",
+	"measurement", "quality", "distance", "bbox", "limit", "offset",
+}
+
+const maxReportLimit = 10000
+
+// CheckReportFilterNames rejects query params ParseReportFilter would
+// ignore.  The routes get this from the spec in validateQueryNames, it is
+// for filters that don't come through the api such as the export command.
+func CheckReportFilterNames(qp url.Values, reportType database.ReportType) ApiResponse {
+	known := slices.Clone(reportFilterParams)
+	if reportType == "" {
+		known = append(known, "type")
+	}
+	if names, ok := magnitudeParams[reportType]; ok {
+		known = append(known, names[:]...)
+	}
+	for name := range qp {
+		if !slices.Contains(known, name) {
+			return ApiResponse{Code: 400, Message: fmt.Sprintf("unknown query param %q", name)}
+		}
+	}
+	return ApiResponse{}
+}
+
+// ParseReportFilter builds a ReportFilter from the query params of a
+// report endpoint.  reportType is the type served by the endpoint, or
+// empty for endpoints that serve every type and accept a type param.
+func ParseReportFilter(qp url.Values, reportType database.ReportType) (ReportFilter, ApiResponse) {
+	var f ReportFilter
+	badRequest := func(format string, a ...any) (ReportFilter, ApiResponse) {
+		return ReportFilter{}, ApiResponse{Code: 400, Message: fmt.Sprintf(format, a...)}
+	}
+
+	if reportType != "" {
+		f.Types = []database.ReportType{reportType}
+	} else if v := qp.Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			rt := database.ReportType(strings.ToLower(strings.TrimSpace(t)))
+			if _, ok := magnitudeParams[rt]; !ok {
+				return badRequest("type %q not valid, use hail, wind, or tornado", t)
+			}
+			f.Types = append(f.Types, rt)
+		}
+	}
+
+	if v := qp.Get("date"); v != "" {
+		day, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return badRequest("date value not valid format, use YYYY-MM-DD")
+		}
+		f.From, f.To = day, day.Add(24*time.Hour)
+	}
+	if v := qp.Get("from-date"); v != "" {
+		t, _, err := parseDateParam(v)
+		if err != nil {
+			return badRequest("from-date value not valid format, use YYYY-MM-DD or RFC3339")
+		}
+		f.From = t
+	}
+	if v := qp.Get("to-date"); v != "" {
+		t, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return badRequest("to-date value not valid format, use YYYY-MM-DD or RFC3339")
+		}
+		// to-date is inclusive, To is exclusive.
+		if dateOnly {
+			f.To = t.Add(24 * time.Hour)
+		} else {
+			f.To = t.Add(time.Microsecond)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return badRequest("from-date must be before to-date")
+	}
+
+	if v := qp.Get("state"); v != "" {
+		if len(v) != 2 {
+			return badRequest("state must be a two letter abbreviation")
+		}
+		f.State = strings.ToUpper(v)
+	}
+	if v := qp.Get("office"); v != "" {
+		f.Office = strings.ToUpper(v)
+		if !nws.IsOffice(f.Office) {
+			return badRequest("office %q is not a known weather forecast office id", v)
+		}
+	}
+	f.County = qp.Get("county")
+	f.Location = qp.Get("location")
+	f.Direction = strings.ToUpper(qp.Get("direction"))
+	f.Comments = qp.Get("comments")
+
+	if v := qp.Get("measurement"); v != "" {
+		f.Measurement = strings.ToLower(v)
+		if !nws.IsWindMeasurement(f.Measurement) {
+			return badRequest("measurement must be %s, %s or %s", nws.WindMeasured, nws.WindEstimated, nws.WindUnknown)
+		}
+	}
+
+	switch v := qp.Get("quality"); v {
+	case "", QualityClean, QualityFlagged:
+		f.Quality = v
+	default:
+		return badRequest("quality must be %s or %s", QualityClean, QualityFlagged)
+	}
+
+	if v := qp.Get("distance"); v != "" {
+		d, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || d < 0 {
+			return badRequest("distance must be a positive whole number of miles")
+		}
+		d32 := int32(d)
+		f.Distance = &d32
+	}
+
+	if v := qp.Get("bbox"); v != "" {
+		b, err := parseBBox(v)
+		if err != nil {
+			return badRequest("bbox %s", err)
+		}
+		f.BBox = &b
+	}
+
+	if names, ok := magnitudeParams[reportType]; ok {
+		for i, name := range names {
+			v := qp.Get(name)
+			if v == "" {
+				continue
+			}
+			m, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return badRequest("%s must be a whole number", name)
+			}
+			m32 := int32(m)
+			if i == 0 {
+				f.MinMagnitude = &m32
+			} else {
+				f.MaxMagnitude = &m32
+			}
+		}
+	}
+
+	if v := qp.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxReportLimit {
+			return badRequest("limit must be between 1 and %d", maxReportLimit)
+		}
+		f.Limit = l
+	}
+	if v := qp.Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return badRequest("offset must be zero or greater")
+		}
+		f.Offset = o
+	}
+
+	return f, ApiResponse{}
+}
+
+// parseDateParam accepts either YYYY-MM-DD or an RFC3339 timestamp and
+// reports which of the two it was.
+func parseDateParam(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t.UTC(), false, err
+}
+
+// parseBBox parses minLon,minLat,maxLon,maxLat.
+func parseBBox(v string) (BBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("must be minLon,minLat,maxLon,maxLat")
+	}
+	var vals [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("value %q is not a number", p)
+		}
+		vals[i] = f
+	}
+	b := BBox{MinLon: vals[0], MinLat: vals[1], MaxLon: vals[2], MaxLat: vals[3]}
+	if b.MinLon > b.MaxLon || b.MinLat > b.MaxLat {
+		return BBox{}, fmt.Errorf("min values must be less than max values")
+	}
+	if b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return BBox{}, fmt.Errorf("values out of range")
+	}
+	return b, nil
+}
+
+// MatchesMessage reports whether a published report would be returned by
+// the filter.  A report is published as it is stored, before it can be
+// reviewed, so its flags alone decide its quality.
+func (f ReportFilter) MatchesMessage(m pubsub.Message) bool {
+	switch f.Quality {
+	case QualityClean:
+		if len(m.Flags) > 0 {
+			return false
+		}
+	case QualityFlagged:
+		if len(m.Flags) == 0 {
+			return false
+		}
+	}
+	return f.Matches(m.Report)
+}
+
+// Matches reports whether a newly stored report would be returned by
+// the filter.  Limit, offset and AfterID are ignored, and so is quality,
+// so a flagged report can match a clean filter, use MatchesMessage when
+// the report's flags are known.
+func (f ReportFilter) Matches(r database.InsertReportParams) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, r.RptType) {
+		return false
+	}
+	if !f.From.IsZero() && r.ReportedTime.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !r.ReportedTime.Time.Before(f.To) {
+		return false
+	}
+	if f.State != "" && r.State.String != f.State {
+		return false
+	}
+	if f.Office != "" && r.NwsOffice.String != f.Office {
+		return false
+	}
+	if f.County != "" && !strings.EqualFold(r.County, f.County) {
+		return false
+	}
+	if f.Location != "" && !strings.EqualFold(r.Location, f.Location) {
+		return false
+	}
+	if f.Direction != "" && r.HeadingFromLocation != f.Direction {
+		return false
+	}
+	if f.Distance != nil && r.DistFromLocation != *f.Distance {
+		return false
+	}
+	if f.Comments != "" && !strings.Contains(strings.ToLower(r.Comments.String), strings.ToLower(f.Comments)) {
+		return false
+	}
+	if f.Measurement != "" && (r.RptType != database.ReportTypeWind || nws.WindMeasurement(r.VarCol.Int32, r.Comments.String) != f.Measurement) {
+		return false
+	}
+	if f.BBox != nil {
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(r.Latitude.String), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(r.Longitude.String), 64)
+		if latErr != nil || lonErr != nil || !f.BBox.Contains(lat, lon) {
+			return false
+		}
+	}
+	if f.MinMagnitude != nil && (!r.VarCol.Valid || r.VarCol.Int32 <= *f.MinMagnitude) {
+		return false
+	}
+	if f.MaxMagnitude != nil && (!r.VarCol.Valid || r.VarCol.Int32 >= *f.MaxMagnitude) {
+		return false
+	}
+	if f.Significant && !isSignificant(r.RptType, r.VarCol) {
+		return false
+	}
+	return true
+}
+
+const (
+	reportColumns = `id,
+       rpt_type,
+       reported_time,
+       created_at,
+       var_col,
+       dist_from_location,
+       heading_from_location,
+       county,
+       "state",
+       latitude,
+       longitude,
+       event_location,
+       comments,
+       nws_office,
+       location,
+       coalesce((select array_agg(fl.flag order by fl.flag) from report_flags fl where fl.report_id = reports.id), '{}'),
+       coalesce((select rv.status from report_reviews rv where rv.report_id = reports.id), ''),
+       coalesce(wind_measurement, '')`
+
+	flaggedSQL  = `exists (select 1 from report_flags fl where fl.report_id = reports.id)`
+	reviewedSQL = `exists (select 1 from report_reviews rv where rv.report_id = reports.id and rv.status = `
+
+	// latitude and longitude are stored as text, only cast the values
+	// that are actually numbers so a bad row can't fail the whole query.
+	latitudeSQL  = `(case when latitude ~ '^\s*-?[0-9]+(\.[0-9]+)?\s*$' then latitude::double precision end)`
+	longitudeSQL = `(case when longitude ~ '^\s*-?[0-9]+(\.[0-9]+)?\s*$' then longitude::double precision end)`
+)
+
+// where returns the sql where clause, without the where keyword, and its
+// args for the filter.
+func (f ReportFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(f.Types) > 0 {
+		types := make([]string, len(f.Types))
+		for i, t := range f.Types {
+			types[i] = string(t)
+		}
+		conds = append(conds, "rpt_type::text = any("+arg(types)+")")
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "reported_time >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "reported_time < "+arg(f.To))
+	}
+	if f.State != "" {
+		conds = append(conds, `"state" = `+arg(f.State))
+	}
+	if f.Office != "" {
+		conds = append(conds, "nws_office = "+arg(f.Office))
+	}
+	if f.County != "" {
+		conds = append(conds, "lower(county) = lower("+arg(f.County)+")")
+	}
+	if f.Location != "" {
+		conds = append(conds, "lower(location) = lower("+arg(f.Location)+")")
+	}
+	if f.Direction != "" {
+		conds = append(conds, "heading_from_location = "+arg(f.Direction))
+	}
+	if f.Distance != nil {
+		conds = append(conds, "dist_from_location = "+arg(*f.Distance))
+	}
+	if f.Comments != "" {
+		conds = append(conds, "comments ilike '%' || "+arg(f.Comments)+" || '%'")
+	}
+	if f.Measurement != "" {
+		conds = append(conds, "wind_measurement = "+arg(f.Measurement))
+	}
+	if f.BBox != nil {
+		conds = append(conds,
+			latitudeSQL+" between "+arg(f.BBox.MinLat)+" and "+arg(f.BBox.MaxLat),
+			longitudeSQL+" between "+arg(f.BBox.MinLon)+" and "+arg(f.BBox.MaxLon))
+	}
+	if f.MinMagnitude != nil {
+		conds = append(conds, "var_col > "+arg(*f.MinMagnitude))
+	}
+	if f.MaxMagnitude != nil {
+		conds = append(conds, "var_col < "+arg(*f.MaxMagnitude))
+	}
+	if f.AfterID > 0 {
+		conds = append(conds, "id > "+arg(f.AfterID))
+	}
+	if f.Significant {
+		conds = append(conds, "(rpt_type = 'tornado' or (rpt_type = 'hail' and var_col >= "+arg(significantHail)+
+			") or (rpt_type = 'wind' and var_col >= "+arg(significantWind)+"))")
+	}
+
+	switch f.Review {
+	case ReviewPending:
+		conds = append(conds, flaggedSQL, "not exists (select 1 from report_reviews rv where rv.report_id = reports.id)")
+	case ReviewAccepted, ReviewRejected:
+		conds = append(conds, reviewedSQL+arg(f.Review)+")")
+	default:
+		// rejected reports are only visible to the review queue.
+		conds = append(conds, "not "+reviewedSQL+"'"+ReviewRejected+"')")
+	}
+	switch f.Quality {
+	case QualityClean:
+		conds = append(conds, "(not "+flaggedSQL+" or "+reviewedSQL+"'"+ReviewAccepted+"'))")
+	case QualityFlagged:
+		conds = append(conds, flaggedSQL)
+	}
+
+	return strings.Join(conds, "\n  and "), args
+}
+
+// QueryReports returns the reports matching the filter ordered by
+// reported time.
+func QueryReports(ctx context.Context, db database.DBTX, f ReportFilter) ([]StoredReport, error) {
+	var items []StoredReport
+	err := EachReport(ctx, db, f, func(r StoredReport) error {
+		items = append(items, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// EachReport calls fn with each report matching the filter, ordered by
+// reported time, without holding them all in memory.  It stops at the
+// first error fn returns.
+func EachReport(ctx context.Context, db database.DBTX, f ReportFilter, fn func(StoredReport) error) error {
+	query, args := f.selectQuery()
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		i, err := scanStoredReport(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachReportBatch calls fn with the reports matching the filter, ordered
+// by reported time, batch reports at a time.  They are fetched from a
+// cursor so only one batch is held at once, however many there are.  The
+// batch is reused, fn must not keep it.
+func EachReportBatch(ctx context.Context, db TxDB, f ReportFilter, batch int, fn func([]StoredReport) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// the transaction only reads, there is nothing to commit.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	query, args := f.selectQuery()
+	if _, err := tx.Exec(ctx, "declare reports_cursor no scroll cursor for "+query, args...); err != nil {
+		return err
+	}
+	fetch := "fetch forward " + strconv.Itoa(batch) + " from reports_cursor"
+	items := make([]StoredReport, 0, batch)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return err
+		}
+		items = items[:0]
+		for rows.Next() {
+			i, err := scanStoredReport(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			items = append(items, i)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		if err := fn(items); err != nil {
+			return err
+		}
+		if len(items) < batch {
+			return nil
+		}
+	}
+}
+
+// selectQuery returns the query for the reports matching the filter.
+func (f ReportFilter) selectQuery() (string, []any) {
+	where, args := f.where()
+	order := "\norder by reported_time, rpt_type, id"
+	switch {
+	case f.AfterID > 0:
+		order = "\norder by id"
+	case f.Newest:
+		order = "\norder by reported_time desc, rpt_type, id desc"
+	}
+	query := "select " + reportColumns + "\nfrom reports\nwhere " + where + order
+	if f.Limit > 0 {
+		query += " limit " + strconv.Itoa(f.Limit)
+	}
+	if f.Offset > 0 {
+		query += " offset " + strconv.Itoa(f.Offset)
+	}
+	return query, args
+}
+
+func scanStoredReport(row pgx.Row) (StoredReport, error) {
+	var i StoredReport
+	err := row.Scan(
+		&i.ID,
+		&i.RptType,
+		&i.ReportedTime,
+		&i.CreatedAt,
+		&i.VarCol,
+		&i.DistFromLocation,
+		&i.HeadingFromLocation,
+		&i.County,
+		&i.State,
+		&i.Latitude,
+		&i.Longitude,
+		&i.EventLocation,
+		&i.Comments,
+		&i.NwsOffice,
+		&i.Location,
+		&i.Flags,
+		&i.Review,
+		&i.Measurement,
+	)
+	return i, err
+}
+
+// ReportsVersion returns the number of reports matching the filter and
+// the newest time one of them was created or reviewed.  Reports are only
+// ever added or reviewed, so the pair changes whenever the result set
+// does and is cheap to compute without reading the rows.
+func ReportsVersion(ctx context.Context, db database.DBTX, f ReportFilter) (int64, time.Time, error) {
+	where, args := f.where()
+	var count int64
+	var lastModified pgtype.Timestamptz
+	if err := db.QueryRow(ctx, "select count(*), max(greatest(created_at, (select rv.reviewed_at from report_reviews rv where rv.report_id = reports.id)))\nfrom reports\nwhere "+where, args...).Scan(&count, &lastModified); err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, lastModified.Time, nil
+}
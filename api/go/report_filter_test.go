@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReportFilterNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		reportType database.ReportType
+		wantCode   int32
+	}{
+		{name: "should accept the filters", query: "type=hail&from-date=2020-01-01&state=OK&limit=10"},
+		{name: "should reject a name that isn't a filter", query: "type=hail&start=2020-01-01", wantCode: 400},
+		{name: "should accept the magnitude of the report type", query: "size-greater-than=100", reportType: database.ReportTypeHail},
+		{name: "should reject a magnitude without a report type", query: "size-greater-than=100", wantCode: 400},
+		{name: "should reject type when the type is fixed", query: "type=wind", reportType: database.ReportTypeHail, wantCode: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qp, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, CheckReportFilterNames(qp, tt.reportType).Code)
+		})
+	}
+}
+
+func TestReportFilter_selectQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		f         ReportFilter
+		wantOrder string
+	}{
+		{name: "should break ties on id so pages don't overlap", f: ReportFilter{Limit: 10, Offset: 10}, wantOrder: "order by reported_time, rpt_type, id limit 10 offset 10"},
+		{name: "should order the newest first", f: ReportFilter{Newest: true}, wantOrder: "order by reported_time desc, rpt_type, id desc"},
+		{name: "should replay in the order reports were stored", f: ReportFilter{AfterID: 5}, wantOrder: "order by id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := tt.f.selectQuery()
+			assert.True(t, strings.HasSuffix(query, "\n"+tt.wantOrder), query)
+		})
+	}
+}
@@ -1,151 +1,239 @@
 package api
 
 import (
+	"encoding/json"
+	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/nws"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	// cursorBatch is how many reports streamed responses fetch from the
+	// cursor at a time, they are flushed after each batch.
+	cursorBatch = 500
 )
 
-func (s ServerAndDB) getReportsByTypeByState(c echo.Context, state string, reportType database.ReportType) ([]database.Report, ApiResponse) {
-	rpts, err := s.DB.GetReportsByTypeByState(c.Request().Context(), database.GetReportsByTypeByStateParams{
-		State: pgtype.Text{
-			String: state,
-			Valid:  true,
-		},
-		RptType: reportType,
+// serveReports handles the report endpoints.  It parses the filters,
+// answers conditional requests with a 304, serves from the response cache
+// when it can, and otherwise queries and renders the reports.  KML, KMZ
+// and NDJSON are streamed instead, NDJSON as a line per report rendered
+// by line.  An empty reportType serves every type.
+func (s ServerAndDB) serveReports(c echo.Context, reportType database.ReportType, render func([]StoredReport) any, line func(StoredReport) any) error {
+	f, errResponse := ParseReportFilter(c.QueryParams(), reportType)
+	if errResponse.Code > 0 {
+		return c.JSON(int(errResponse.Code), errResponse)
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "json" && format != FormatKML && format != FormatKMZ {
+		return c.JSON(http.StatusBadRequest, ApiResponse{Code: 400, Message: "format must be json, kml or kmz"})
+	}
+
+	ndjson := format == "" && acceptsNDJSON(c.Request())
+	key := cacheKey(c)
+	if ndjson {
+		// only json bodies are cached, this gives ndjson its own etag.
+		key += "|" + ndjsonContentType
+	}
+	entry, generation, ok := s.Cache.get(key)
+	if !ok {
+		count, lastModified, err := ReportsVersion(c.Request().Context(), s.Conn, f)
+		if err != nil {
+			s.Logger.Error("failed to query report version", "error", err)
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
+		}
+		entry = &cacheEntry{
+			filter:       f,
+			etag:         reportsETag(key, count, lastModified),
+			lastModified: lastModified,
+		}
+	}
+
+	setCacheHeaders(c, f, entry.etag, entry.lastModified)
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if notModified(c.Request(), entry.etag, entry.lastModified) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	if format == FormatKML || format == FormatKMZ {
+		return s.streamKML(c, f, format == FormatKMZ)
+	}
+	if ndjson {
+		return s.streamNDJSON(c, f, line)
+	}
+
+	if !ok {
+		rpts, errResponse := s.getReportsByFilter(c, f)
+		if errResponse.Code > 0 {
+			return c.JSON(int(errResponse.Code), errResponse)
+		}
+		body, err := json.Marshal(render(rpts))
+		if err != nil {
+			return err
+		}
+		entry.body = body
+		entry.rows = len(rpts)
+		s.Cache.set(key, generation, entry)
+	}
+
+	setUsageRows(c, entry.rows)
+	return c.JSONBlob(http.StatusOK, entry.body)
+}
+
+// streamKML writes the reports matching the filter as KML, or KMZ, as
+// they are read from the database.
+func (s ServerAndDB) streamKML(c echo.Context, f ReportFilter, kmz bool) error {
+	res := c.Response()
+	contentType, filename := kmlContentType, "reports.kml"
+	if kmz {
+		contentType, filename = kmzContentType, "reports.kmz"
+	}
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	kw := newKMLWriter(res, kmz)
+	rows := 0
+	err := EachReport(c.Request().Context(), s.Conn, f, func(r StoredReport) error {
+		rows++
+		return kw.Write(r)
 	})
+	if err == nil {
+		err = kw.Close()
+	}
+	setUsageRows(c, rows)
 	if err != nil {
-		return nil, ApiResponse{
-			Code:    500,
-			Message: "error making query to database",
+		s.Logger.Error("failed to stream kml", "error", err, "rows", rows)
+		// once the document has started the status can't change.
+		if !res.Committed {
+			res.Header().Del(echo.HeaderContentDisposition)
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
 		}
 	}
-	return rpts, ApiResponse{}
+	return nil
 }
 
-func (s ServerAndDB) getReportsByTypeByCountyAndState(c echo.Context, county, state string, reportType database.ReportType) ([]database.Report, ApiResponse) {
-	rpts, err := s.DB.GetReportsByTypeByCountyAndState(c.Request().Context(), database.GetReportsByTypeByCountyAndStateParams{
-		County: county,
-		State: pgtype.Text{
-			String: state,
-			Valid:  true,
-		},
-		RptType: reportType,
+// streamNDJSON writes the reports matching the filter as newline
+// delimited JSON, read from a cursor and flushed a batch at a time so
+// memory stays flat however many match.
+func (s ServerAndDB) streamNDJSON(c echo.Context, f ReportFilter, line func(StoredReport) any) error {
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, ndjsonContentType)
+
+	enc := json.NewEncoder(res)
+	rows := 0
+	err := EachReportBatch(c.Request().Context(), s.Conn, f, cursorBatch, func(batch []StoredReport) error {
+		for _, r := range batch {
+			if err := enc.Encode(line(r)); err != nil {
+				return err
+			}
+		}
+		rows += len(batch)
+		res.Flush()
+		return nil
 	})
+	setUsageRows(c, rows)
 	if err != nil {
-		return nil, ApiResponse{
-			Code:    500,
-			Message: "error making query to database",
+		s.Logger.Error("failed to stream ndjson", "error", err, "rows", rows)
+		// once the first batch is out the status can't change.
+		if !res.Committed {
+			return c.JSON(500, ApiResponse{Code: 500, Message: "error making query to database"})
 		}
+		return nil
 	}
-	return rpts, ApiResponse{}
+	if !res.Committed {
+		// nothing matched, the body is empty.
+		res.WriteHeader(http.StatusOK)
+	}
+	return nil
 }
 
-func (s ServerAndDB) getReportsByDateAndType(c echo.Context, queryDate string, reportType database.ReportType) ([]database.Report, ApiResponse) {
-	qt, err := time.Parse("2024-01-02 15:04 -07:00", queryDate)
-	if err != nil {
-		return nil, ApiResponse{
-			Code:    400,
-			Message: "date value not valid format, use YYYY-MM-DD HH:MM +00:00 ",
+// acceptsNDJSON reports whether the request's Accept header asks for
+// NDJSON.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values(echo.HeaderAccept) {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), ndjsonContentType) {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	rpts, err := s.DB.GetAllReportsByTypeByDate(c.Request().Context(), database.GetAllReportsByTypeByDateParams{
-		ReportedTime: pgtype.Timestamptz{
-			Time:  qt,
-			Valid: true,
-		},
-		RptType: reportType,
-	})
+// getReportsByFilter returns the reports matching the filter.
+func (s ServerAndDB) getReportsByFilter(c echo.Context, f ReportFilter) ([]StoredReport, ApiResponse) {
+	rpts, err := QueryReports(c.Request().Context(), s.Conn, f)
 	if err != nil {
+		s.Logger.Error("failed to query reports", "error", err)
 		return nil, ApiResponse{
 			Code:    500,
 			Message: "error making query to database",
 		}
 	}
+	setUsageRows(c, len(rpts))
 	return rpts, ApiResponse{}
 }
 
-func dbToReportModel(rpts []database.Report) Reports {
+func dbToReportModel(rpts []StoredReport) Reports {
 	var reports Reports
 	for _, row := range rpts {
-		hr := Report{}
-		if row.CreatedAt.Valid {
-			hr.Time = row.CreatedAt.Time
-		}
-		if row.VarCol.Valid {
-			hr.VarCol = strconv.FormatInt(int64(row.VarCol.Int32), 10)
-		}
-		if row.Comments.Valid {
-			hr.Comments = row.Comments.String
-		}
-		if row.NwsOffice.Valid {
-			hr.Office = row.NwsOffice.String
-		}
-		hr.Direction = row.HeadingFromLocation
-		hr.Distance = row.DistFromLocation
-		hr.Location = row.Location
-		hr.County = row.County
-		hr.State = row.State.String
-		hr.Lat = row.Latitude.String
-		hr.Lon = row.Longitude.String
-		reports.Reports = append(reports.Reports, hr)
+		reports.Reports = append(reports.Reports, storedToReport(row))
 	}
 	return reports
 }
 
-func (s ServerAndDB) getReportsByState(c echo.Context, state string) ([]database.Report, ApiResponse) {
-	rpts, err := s.DB.GetReportsByState(c.Request().Context(), pgtype.Text{
-		String: state,
-		Valid:  true,
-	})
-	if err != nil {
-		return nil, ApiResponse{
-			Code:    500,
-			Message: "error making query to database",
-		}
+// storedToReport renders one stored report.
+func storedToReport(row StoredReport) Report {
+	hr := Report{}
+	hr.Type = string(row.RptType)
+	if row.CreatedAt.Valid {
+		hr.Time = row.CreatedAt.Time
 	}
-	return rpts, ApiResponse{}
+	// a wind speed of 0 is how UNK was stored before it was null.
+	if row.VarCol.Valid && (row.RptType != database.ReportTypeWind || row.VarCol.Int32 != 0) {
+		hr.VarCol = strconv.FormatInt(int64(row.VarCol.Int32), 10)
+	}
+	if row.Comments.Valid {
+		hr.Comments = row.Comments.String
+	}
+	if row.NwsOffice.Valid {
+		hr.Office = row.NwsOffice.String
+	}
+	hr.Direction = row.HeadingFromLocation
+	hr.Distance = row.DistFromLocation
+	hr.Location = row.Location
+	hr.County = row.County
+	hr.State = row.State.String
+	hr.Lat = row.Latitude.String
+	hr.Lon = row.Longitude.String
+	hr.ID = row.ID
+	hr.Flags = row.Flags
+	hr.Review = row.Review
+	hr.Measurement = row.Measurement
+	return hr
 }
 
-func (s ServerAndDB) getReportsByCountyAndState(c echo.Context, county, state string) ([]database.Report, ApiResponse) {
-	rpts, err := s.DB.GetReportsByCountyAndState(c.Request().Context(), database.GetReportsByCountyAndStateParams{
-		County: county,
-		State: pgtype.Text{
-			String: state,
-			Valid:  true,
-		},
-	})
-	if err != nil {
-		return nil, ApiResponse{
-			Code:    500,
-			Message: "error making query to database",
-		}
-	}
-	return rpts, ApiResponse{}
+// messageToReport renders a newly stored report for the stream,
+// websocket and webhook payloads.
+func messageToReport(msg pubsub.Message) Report {
+	return storedToReport(messageToStored(msg))
 }
 
-func (s ServerAndDB) getReportsByDate(c echo.Context, queryDate string) ([]database.Report, ApiResponse) {
-	qt, err := time.Parse("2024-01-02 15:04 -07:00", queryDate)
-	if err != nil {
-		return nil, ApiResponse{
-			Code:    400,
-			Message: "date value not valid format, use YYYY-MM-DD HH:MM +00:00 ",
-		}
+// messageToStored is a newly stored report as it would be read back.
+func messageToStored(msg pubsub.Message) StoredReport {
+	r := StoredReport{
+		Report: database.Report(msg.Report),
+		ID:     msg.ID,
+		Flags:  msg.Flags,
 	}
-
-	rpts, err := s.DB.GetAllReportsByDate(c.Request().Context(), pgtype.Timestamptz{
-		Time:  qt,
-		Valid: true,
-	})
-	if err != nil {
-		return nil, ApiResponse{
-			Code:    500,
-			Message: "error making query to database",
-		}
+	if r.RptType == database.ReportTypeWind {
+		r.Measurement = nws.WindMeasurement(r.VarCol.Int32, r.Comments.String)
 	}
-	return rpts, ApiResponse{}
+	return r
 }
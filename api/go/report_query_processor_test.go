@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_acceptsNDJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "application/json", want: false},
+		{accept: "application/x-ndjson", want: true},
+		{accept: "application/json;q=0.5, Application/X-NDJSON; q=1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/report/all", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			assert.Equal(t, tt.want, acceptsNDJSON(req))
+		})
+	}
+}
+
+func Test_ndjsonBodyDecoder(t *testing.T) {
+	got, err := ndjsonBodyDecoder(strings.NewReader("{\"Type\":\"hail\"}\n{\"Type\":\"wind\"}\n"), nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []any{map[string]any{"Type": "hail"}, map[string]any{"Type": "wind"}}, got)
+
+	_, err = ndjsonBodyDecoder(strings.NewReader("{\"Type\":"), nil, nil, nil)
+	assert.Error(t, err)
+}
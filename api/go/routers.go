@@ -1,23 +1,71 @@
 package api
 
 import (
+	"fmt"
 	"log/slog"
+	"strings"
 
+	"github.com/graphql-go/graphql"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/stormsync/database"
+	"google.golang.org/grpc"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
 )
 
 type RouterConfig struct {
-	ROKey  string
-	RWKey  string
-	DB     *database.Queries
-	Logger *slog.Logger
+	ROKey string
+	RWKey string
+	DB    *database.Queries
+	Conn  TxDB
+	Usage *UsageRecorder
+	// Cache holds rendered report responses, nil disables it.
+	Cache *ResponseCache
+	// Broker publishes reports as the consumer stores them, nil disables
+	// the streaming endpoints.
+	Broker *pubsub.Broker
+	// Webhooks delivers reports to registered webhooks, nil disables the
+	// webhook endpoints.
+	Webhooks *WebhookDispatcher
+	// Climatology compares counts with their baselines, nil disables the
+	// climatology endpoint.
+	Climatology *Climatology
+	// Tiles serves pre-aggregated tile cells, nil disables the tile
+	// endpoint.
+	Tiles *TileCells
+	// Exports builds bulk exports in the background, nil disables the
+	// export endpoints.
+	Exports *Exporter
+	// EventThresholds are the thresholds reports are clustered into
+	// events with, the zero value uses events.DefaultThresholds.
+	EventThresholds events.Thresholds
+	Logger          *slog.Logger
+	// ValidateResponses checks every response against the OpenAPI spec,
+	// it buffers responses so is meant for tests.
+	ValidateResponses bool
 }
 type ServerAndDB struct {
-	Web    *echo.Echo
-	DB     *database.Queries
-	Logger *slog.Logger
+	Web         *echo.Echo
+	DB          *database.Queries
+	Conn        TxDB
+	Usage       *UsageRecorder
+	Cache       *ResponseCache
+	Broker      *pubsub.Broker
+	Webhooks    *WebhookDispatcher
+	Climatology *Climatology
+	Tiles       *TileCells
+	Exports     *Exporter
+	// EventThresholds are what reports are clustered with by the admin
+	// rebuild and when a review rejects or restores a report.
+	EventThresholds events.Thresholds
+	Logger          *slog.Logger
+	// GRPC serves the ReportService, it shares the api keys and the
+	// report queries with Web.
+	GRPC     *grpc.Server
+	specJSON []byte
+	graphQL  *graphql.Schema
 }
 
 // NewRouter will setup the router and endpoints and
@@ -25,31 +73,145 @@ type ServerAndDB struct {
 // that provides DB access to the handlers.
 func NewRouter(config RouterConfig) ServerAndDB {
 	s := ServerAndDB{
-		Web:    nil,
-		DB:     config.DB,
-		Logger: config.Logger,
+		Web:         nil,
+		DB:          config.DB,
+		Conn:        config.Conn,
+		Usage:       config.Usage,
+		Cache:       config.Cache,
+		Broker:      config.Broker,
+		Webhooks:    config.Webhooks,
+		Climatology: config.Climatology,
+		Tiles:       config.Tiles,
+		Exports:     config.Exports,
+		Logger:      config.Logger,
+	}
+	s.EventThresholds = config.EventThresholds
+	if s.EventThresholds == (events.Thresholds{}) {
+		s.EventThresholds = events.DefaultThresholds
+	}
+	// the spec is embedded, failing to load it is a programming error
+	// that the tests catch.
+	spec, err := LoadSpec()
+	if err != nil {
+		panic(err)
+	}
+	if s.specJSON, err = spec.MarshalJSON(); err != nil {
+		panic(fmt.Errorf("failed to marshal openapi spec: %w", err))
+	}
+	schema, err := newGraphQLSchema(s)
+	if err != nil {
+		panic(fmt.Errorf("failed to build graphql schema: %w", err))
 	}
+	s.graphQL = &schema
+	validator, err := OpenAPIValidator(spec, config.ValidateResponses, config.Logger)
+	if err != nil {
+		panic(err)
+	}
+
+	validateKey := func(key string, c echo.Context) (bool, error) {
+		matchKey := config.ROKey
+		if requiresRWKey(c.Path()) {
+			matchKey = config.RWKey
+		}
+		if key != matchKey {
+			return false, nil
+		}
+		c.Set(apiKeyIDContextKey, apiKeyID(key))
+		return true, nil
+	}
+
 	e := echo.New()
 
 	e.GET("/api/v1/report/all", s.GetAllReports)
 	e.GET("/api/v1/report/hail", s.GetHailReports)
 	e.GET("/api/v1/report/tornado", s.GetTornadoReports)
 	e.GET("/api/v1/report/wind", s.GetWindReports)
+	e.GET("/api/v1/tornado/tracks", s.GetTornadoTracks)
+	e.GET("/api/v2/report/all", s.GetAllReportsV2)
+	e.GET("/api/v2/report/hail", s.GetHailReportsV2)
+	e.GET("/api/v2/report/tornado", s.GetTornadoReportsV2)
+	e.GET("/api/v2/report/wind", s.GetWindReportsV2)
+	e.GET("/api/v1/stats/counts", s.GetStatsCounts)
+	e.GET("/api/v1/stats/climatology", s.GetClimatology)
+	e.GET("/api/v1/density", s.GetDensity)
+	e.GET("/api/v1/events", s.GetEvents)
+	e.GET("/api/v1/swaths", s.GetSwaths)
 	e.POST("/api/v1/maint/report", s.AddReport)
+	e.GET("/api/v1/stream/reports", s.StreamReports)
+	e.POST("/api/v1/graphql", s.GraphQL)
+	// browsers can't set headers on a websocket handshake, and map
+	// libraries and feed readers don't set them on tile and feed requests,
+	// so on those routes the key may be passed in the query string instead.
+	queryKeyAuth := middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		KeyLookup: "header:X-Api-Key,query:" + apiKeyQueryParam,
+		Validator: validateKey,
+	})
+	e.GET("/api/v1/ws/reports", s.ReportsSocket, queryKeyAuth)
+	e.GET("/api/v1/graphql/ws", s.GraphQLSocket, queryKeyAuth)
+	e.GET("/api/v1/feeds/reports.atom", s.GetReportsAtom, queryKeyAuth)
+	e.GET("/api/v1/feeds/reports.rss", s.GetReportsRSS, queryKeyAuth)
+	e.GET("/api/v1/feeds/cap.atom", s.GetAlertsCAP, queryKeyAuth)
+	e.GET("/api/v1/tiles/:z/:x/:y", s.GetTile, queryKeyAuth)
+	// y is the tile row followed by .mvt, echo params can't have a suffix.
+	e.GET("/api/v1/tiles/:type/:z/:x/:y", s.GetReportTile, queryKeyAuth)
+	e.POST("/api/v1/webhooks", s.CreateWebhook)
+	e.GET("/api/v1/webhooks", s.ListWebhooks)
+	e.GET("/api/v1/webhooks/:id", s.GetWebhook)
+	e.DELETE("/api/v1/webhooks/:id", s.DeleteWebhook)
+	e.POST("/api/v1/webhooks/:id/enable", s.EnableWebhook)
+	e.GET("/api/v1/webhooks/:id/deliveries", s.ListWebhookDeliveries)
+	e.POST("/api/v1/exports", s.CreateExport)
+	e.GET("/api/v1/exports/:id", s.GetExport)
+	e.GET("/api/v1/exports/:id/download", s.DownloadExport)
+	e.GET("/api/v1/account/usage", s.GetAccountUsage)
+	e.GET("/api/v1/admin/usage/export", s.ExportUsage)
+	e.GET("/api/v1/admin/reviews", s.ListReviews)
+	e.POST("/api/v1/admin/reviews/:id", s.ReviewReport)
+	e.POST("/api/v1/admin/events/rebuild", s.RebuildEvents)
+	e.GET("/api/v1/openapi.json", s.GetOpenAPISpec)
+	e.GET("/api/v1/docs", s.GetDocs)
 
 	e.Use(middleware.Secure())
 	e.Use(middleware.Recover())
 
 	e.Use(middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
 		KeyLookup: "header:X-Api-Key",
-		Validator: func(key string, c echo.Context) (bool, error) {
-			matchKey := config.ROKey
-			if c.Path() == "/api/v1/maint/report" {
-				matchKey = config.RWKey
-			}
-			return key == matchKey, nil
+		Skipper: func(c echo.Context) bool {
+			return isPublicRoute(c.Path()) || acceptsQueryKey(c.Path())
 		},
+		Validator: validateKey,
 	}))
+	if s.Usage != nil {
+		e.Use(s.Usage.Middleware())
+	}
+	e.Use(validator)
 	s.Web = e
+	s.GRPC = newGRPCServer(s, config.ROKey)
 	return s
 }
+
+// apiKeyQueryParam carries the api key on routes that can't use the
+// X-Api-Key header.
+const apiKeyQueryParam = "api_key"
+
+// isPublicRoute reports whether the route can be called without a key.
+func isPublicRoute(path string) bool {
+	return path == "/api/v1/openapi.json" || path == "/api/v1/docs"
+}
+
+// acceptsQueryKey reports whether the route checks its own key, which may
+// be in the query string.
+func acceptsQueryKey(path string) bool {
+	switch path {
+	case "/api/v1/ws/reports", "/api/v1/graphql/ws", "/api/v1/tiles/:z/:x/:y", "/api/v1/tiles/:type/:z/:x/:y",
+		"/api/v1/feeds/reports.atom", "/api/v1/feeds/reports.rss", "/api/v1/feeds/cap.atom":
+		return true
+	}
+	return false
+}
+
+// requiresRWKey reports whether the route is a maintenance or admin
+// route that only the read/write key may call.
+func requiresRWKey(path string) bool {
+	return strings.HasPrefix(path, "/api/v1/maint/") || strings.HasPrefix(path, "/api/v1/admin/")
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// Limits on the swath params, distances are in miles.
+const (
+	defaultSwathBuffer = 5
+	minSwathBuffer     = 0.5
+	maxSwathBuffer     = 50
+	maxSwathRadius     = 100
+	maxSwathWindow     = 360 // minutes
+)
+
+// buildSwaths clusters the reports into events within t and outlines
+// each as the area within bufferMiles of its reports.  Reports without
+// coordinates are left out.
+func buildSwaths(rows []StoredReport, t events.Thresholds, bufferMiles float64) []Swath {
+	var rpts []events.Report
+	for _, row := range rows {
+		if r, ok := eventReport(row); ok {
+			rpts = append(rpts, r)
+		}
+	}
+
+	swaths := []Swath{}
+	for _, cluster := range events.Cluster(rpts, t) {
+		e := events.Summarize(cluster)
+		pts := make([]geo.Point, len(cluster))
+		for i, r := range cluster {
+			pts[i] = r.Point
+		}
+		outline := geo.BufferedHull(pts, bufferMiles)
+		swaths = append(swaths, Swath{
+			ID:              string(e.Type) + "-" + strconv.FormatInt(e.ReportIDs[0], 10),
+			Type:            string(e.Type),
+			Start:           e.Start,
+			End:             e.End,
+			MaxMagnitude:    e.MaxMagnitude,
+			Reports:         len(cluster),
+			ReportIDs:       e.ReportIDs,
+			AreaSquareMiles: outline[0].AreaSquareMiles(),
+			outline:         outline,
+		})
+	}
+	return swaths
+}
+
+// eventReport is what clustering needs of a stored report.
+func eventReport(row StoredReport) (events.Report, bool) {
+	return events.NewReport(row.ID, database.InsertReportParams{
+		RptType:      row.RptType,
+		ReportedTime: row.ReportedTime,
+		VarCol:       row.VarCol,
+		State:        row.State,
+		Latitude:     row.Latitude,
+		Longitude:    row.Longitude,
+		Comments:     row.Comments,
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+func swathReport(id int64, t database.ReportType, minutes int, lat, lon string, magnitude int32) StoredReport {
+	return StoredReport{
+		ID: id,
+		Report: database.Report{
+			RptType:      t,
+			ReportedTime: pgtype.Timestamptz{Time: time.Date(2024, 5, 6, 22, minutes, 0, 0, time.UTC), Valid: true},
+			VarCol:       pgtype.Int4{Int32: magnitude, Valid: true},
+			Latitude:     pgtype.Text{String: lat, Valid: true},
+			Longitude:    pgtype.Text{String: lon, Valid: true},
+		},
+	}
+}
+
+func Test_buildSwaths(t *testing.T) {
+	rows := []StoredReport{
+		swathReport(1, database.ReportTypeHail, 0, "35.22", "-97.44", 100),
+		swathReport(2, database.ReportTypeHail, 10, "35.22", "-97.34", 275),
+		swathReport(3, database.ReportTypeWind, 5, "35.22", "-97.40", 0),
+		swathReport(4, database.ReportTypeHail, 20, "", "", 400),
+	}
+	swaths := buildSwaths(rows, events.DefaultThresholds, 3)
+	require.Len(t, swaths, 2)
+
+	hail := swaths[0]
+	assert.Equal(t, "hail-1", hail.ID)
+	assert.Equal(t, []int64{1, 2}, hail.ReportIDs)
+	assert.Equal(t, int32(275), *hail.MaxMagnitude)
+	assert.True(t, hail.outline.Contains(geo.Point{Lon: -97.39, Lat: 35.24}))
+	assert.False(t, hail.outline.Contains(geo.Point{Lon: -97.39, Lat: 35.30}))
+	// a band 5.7 miles long and 6 wide with round ends.
+	assert.InDelta(t, 5.7*6+3.14*9, hail.AreaSquareMiles, 2)
+
+	wind := swaths[1]
+	assert.Equal(t, "wind-3", wind.ID)
+	assert.Nil(t, wind.MaxMagnitude, "a wind speed of 0 is unknown")
+
+	body, err := json.Marshal(swathFeatureCollection(swaths))
+	require.NoError(t, err)
+	var fc struct {
+		Features []struct {
+			Geometry struct {
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(body, &fc))
+	ring := fc.Features[0].Geometry.Coordinates[0]
+	assert.Equal(t, ring[0], ring[len(ring)-1])
+	assert.Equal(t, "hail", fc.Features[0].Properties["type"])
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+const (
+	// MaxTileZoom is the deepest zoom tiles are pre-aggregated for.
+	MaxTileZoom = 10
+	// tileCellZoom is how many zoom levels below a tile its cells are, a
+	// tile is 1<<tileCellZoom cells across.
+	tileCellZoom = 4
+)
+
+// TxDB is a connection that can start transactions, a *pgxpool.Pool or a
+// *pgx.Conn.
+type TxDB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// The report_tile_cells rows are rebuilt a convective day at a time, so a
+// rebuild is idempotent and can't double count.  A null $1 rebuilds every
+// day.  Cells are the tiles at zoom z + tileCellZoom, computed the same
+// way as geo.TileXY.
+const (
+	deleteTileCells = `delete from report_tile_cells where $1::date[] is null or day = any($1)`
+
+	insertTileCells = `
+insert into report_tile_cells (zoom, cell_x, cell_y, rpt_type, day, reports, max_magnitude)
+select z,
+       least(greatest(floor((lon + 180) / 360 * (1 << (z + 4)))::int, 0), (1 << (z + 4)) - 1),
+       least(greatest(floor((1 - ln(tan(radians(lat)) + 1 / cos(radians(lat))) / pi()) / 2 * (1 << (z + 4)))::int, 0), (1 << (z + 4)) - 1),
+       rpt_type,
+       day,
+       count(*),
+       max(mag)
+from (select ` + latitudeSQL + ` as lat,
+             ` + longitudeSQL + ` as lon,
+             rpt_type::text as rpt_type,
+             ((reported_time at time zone 'UTC') - interval '12 hours')::date as day,
+             ` + magnitudeSQL + ` as mag
+      from reports
+      where ` + notRejectedSQL + `) p
+         cross join generate_series(0, 10) z -- MaxTileZoom
+where lat between -85.05 and 85.05
+  and lon between -180 and 180
+  and ($1::date[] is null or day = any($1))
+group by 1, 2, 3, 4, 5`
+
+	getTileCells = `
+select cell_x, cell_y, rpt_type, sum(reports)::bigint, max(max_magnitude)
+from report_tile_cells
+where zoom = $1
+  and cell_x >= $2 and cell_x < $2 + 16
+  and cell_y >= $3 and cell_y < $3 + 16
+  and ($4::text[] is null or rpt_type = any($4))
+  and ($5::date is null or day >= $5)
+  and ($6::date is null or day <= $6)
+group by 1, 2, 3
+order by 1, 2`
+)
+
+// TileCells keeps the pre-aggregated tile cells up to date and serves
+// tiles from them.
+type TileCells struct {
+	db     TxDB
+	logger *slog.Logger
+}
+
+// NewTileCells creates tile cells backed by db.  Run must be started for
+// the cells to be built.
+func NewTileCells(db TxDB, logger *slog.Logger) *TileCells {
+	return &TileCells{db: db, logger: logger}
+}
+
+// Run rebuilds every day's cells now and every rebuildEvery, which picks
+// up reviews, and rebuilds the days of reports published on sub every
+// flushEvery.
+func (tc *TileCells) Run(ctx context.Context, sub *pubsub.Subscription, flushEvery, rebuildEvery time.Duration) {
+	defer sub.Close()
+	flush := time.NewTicker(flushEvery)
+	defer flush.Stop()
+	rebuild := time.NewTicker(rebuildEvery)
+	defer rebuild.Stop()
+
+	all := true
+	var dropped int64
+	days := map[time.Time]bool{}
+	for {
+		if all {
+			if err := tc.Rebuild(ctx, nil); err != nil {
+				tc.logger.Error("failed to rebuild tile cells", "error", err)
+			}
+			all = false
+			clear(days)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			// if we missed a message we can't know which day it was.
+			if d := sub.Dropped(); d != dropped {
+				dropped = d
+				all = true
+				continue
+			}
+			days[convectiveDay(msg.Report.ReportedTime.Time)] = true
+		case <-flush.C:
+			if len(days) == 0 {
+				continue
+			}
+			list := make([]time.Time, 0, len(days))
+			for d := range days {
+				list = append(list, d)
+			}
+			if err := tc.Rebuild(ctx, list); err != nil {
+				tc.logger.Error("failed to rebuild tile cells", "error", err, "days", len(list))
+				continue
+			}
+			clear(days)
+		case <-rebuild.C:
+			all = true
+		}
+	}
+}
+
+// Rebuild recomputes the cells of the given convective days, or every day
+// when days is nil.
+func (tc *TileCells) Rebuild(ctx context.Context, days []time.Time) error {
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, tc.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteTileCells, days); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, insertTileCells, days)
+		return err
+	})
+	if err == nil {
+		tc.logger.Debug("rebuilt tile cells", "days", len(days), "took", time.Since(start))
+	}
+	return err
+}
+
+// TileFilter limits the reports counted in a tile.
+type TileFilter struct {
+	Types []database.ReportType
+	// From and To are inclusive convective days, zero for no bound.
+	From time.Time
+	To   time.Time
+}
+
+// Tile returns the cells of tile z/x/y that have reports.
+func (tc *TileCells) Tile(ctx context.Context, z, x, y int, f TileFilter) ([]DensityCell, error) {
+	var types []string
+	for _, t := range f.Types {
+		types = append(types, string(t))
+	}
+	from := pgtype.Date{Time: f.From, Valid: !f.From.IsZero()}
+	to := pgtype.Date{Time: f.To, Valid: !f.To.IsZero()}
+	rows, err := tc.db.Query(ctx, getTileCells, z, x<<tileCellZoom, y<<tileCellZoom, types, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cells := []DensityCell{}
+	byCell := map[[2]int]int{}
+	cz := z + tileCellZoom
+	for rows.Next() {
+		var cx, cy int
+		var rptType string
+		var count int64
+		var mag *int32
+		if err := rows.Scan(&cx, &cy, &rptType, &count, &mag); err != nil {
+			return nil, err
+		}
+		i, ok := byCell[[2]int{cx, cy}]
+		if !ok {
+			sw, ne := geo.TileBounds(cz, cx, cy)
+			cells = append(cells, DensityCell{
+				ID:           strconv.Itoa(cz) + "/" + strconv.Itoa(cx) + "/" + strconv.Itoa(cy),
+				Center:       geo.Point{Lon: (sw.Lon + ne.Lon) / 2, Lat: (sw.Lat + ne.Lat) / 2},
+				MaxMagnitude: map[string]int32{},
+				outline:      geo.Box(sw, ne),
+			})
+			i = len(cells) - 1
+			byCell[[2]int{cx, cy}] = i
+		}
+		cells[i].Count += count
+		if mag != nil {
+			cells[i].MaxMagnitude[rptType] = *mag
+		}
+	}
+	return cells, rows.Err()
+}
+
+// convectiveDay returns the date of the convective day, 12Z to 12Z, that t
+// falls in.
+func convectiveDay(t time.Time) time.Time {
+	d := t.UTC().Add(-12 * time.Hour)
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// maxTrackRange is the longest time range tracks are built for at once.
+const maxTrackRange = 31 * 24 * time.Hour
+
+// buildTracks links the tornado reports into tracks.
+func buildTracks(rows []StoredReport, t events.TrackThresholds) []TornadoTrack {
+	var rpts []events.Report
+	for _, row := range rows {
+		if r, ok := eventReport(row); ok {
+			rpts = append(rpts, r)
+		}
+	}
+
+	tracks := []TornadoTrack{}
+	for _, track := range events.Tracks(rpts, t) {
+		ids := make([]int64, len(track.Reports))
+		for i, r := range track.Reports {
+			ids[i] = r.ID
+		}
+		tracks = append(tracks, TornadoTrack{
+			ID:          "tornado-" + strconv.FormatInt(ids[0], 10),
+			Start:       track.Start,
+			End:         track.End,
+			MaxRating:   track.MaxRating,
+			LengthMiles: math.Round(track.LengthMiles*10) / 10,
+			Bearing:     math.Round(track.Bearing),
+			Direction:   geo.CompassPoint(track.Bearing),
+			Reports:     len(ids),
+			ReportIDs:   ids,
+			path:        track.Path,
+		})
+	}
+	return tracks
+}
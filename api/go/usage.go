@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// apiKeyIDContextKey holds the hashed id of the api key used for the request.
+	apiKeyIDContextKey = "apiKeyID"
+	// usageRowsContextKey holds the number of report rows a handler returned.
+	usageRowsContextKey = "usageRows"
+
+	usageBufferSize = 4096
+)
+
+// UsageRecord is a single api request as seen by the usage recorder.
+type UsageRecord struct {
+	KeyID   string
+	Time    time.Time
+	Method  string
+	Route   string
+	Filters string
+	Status  int
+	Rows    int
+	Bytes   int64
+	Latency time.Duration
+}
+
+// UsageRecorder collects per-request usage, writes it to the api_usage
+// table in batches and keeps the api_usage_hourly rollups up to date.
+type UsageRecorder struct {
+	db      TxDB
+	logger  *slog.Logger
+	records chan UsageRecord
+}
+
+// NewUsageRecorder creates a recorder that writes to db.  Run must be
+// started for anything to be written.
+func NewUsageRecorder(db TxDB, logger *slog.Logger) *UsageRecorder {
+	return &UsageRecorder{
+		db:      db,
+		logger:  logger,
+		records: make(chan UsageRecord, usageBufferSize),
+	}
+}
+
+// apiKeyID returns a stable, non-reversible id for an api key so usage
+// can be attributed to a key without storing the key itself.
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// setUsageRows records how many report rows the handler is returning.
+func setUsageRows(c echo.Context, rows int) {
+	c.Set(usageRowsContextKey, rows)
+}
+
+// Record queues a usage record.  It never blocks the request; if the
+// buffer is full the record is dropped and a warning logged.
+func (u *UsageRecorder) Record(r UsageRecord) {
+	select {
+	case u.records <- r:
+	default:
+		u.logger.Warn("usage buffer full, dropping record", "key_id", r.KeyID, "route", r.Route)
+	}
+}
+
+// Middleware records usage for every authenticated request.  It must be
+// registered after the key auth middleware so the key id is available.
+func (u *UsageRecorder) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			keyID, _ := c.Get(apiKeyIDContextKey).(string)
+			if keyID == "" {
+				return nil
+			}
+			rows, _ := c.Get(usageRowsContextKey).(int)
+			filters := maps.Clone(c.QueryParams())
+			delete(filters, apiKeyQueryParam)
+			u.Record(UsageRecord{
+				KeyID:   keyID,
+				Time:    start.UTC(),
+				Method:  c.Request().Method,
+				Route:   c.Path(),
+				Filters: filters.Encode(),
+				Status:  c.Response().Status,
+				Rows:    rows,
+				Bytes:   c.Response().Size,
+				Latency: time.Since(start),
+			})
+			return nil
+		}
+	}
+}
+
+// Run flushes queued records every interval until ctx is cancelled.
+func (u *UsageRecorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []UsageRecord
+	for {
+		select {
+		case <-ctx.Done():
+			// use a fresh context so the last batch still makes it out.
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := u.flush(flushCtx, batch); err != nil {
+				u.logger.Error("failed to flush usage on shutdown", "error", err)
+			}
+			cancel()
+			return
+		case r := <-u.records:
+			batch = append(batch, r)
+		case <-ticker.C:
+			if err := u.flush(ctx, batch); err != nil {
+				u.logger.Error("failed to flush usage", "error", err, "records", len(batch))
+				// don't hold on to usage forever if the database stays down.
+				if len(batch) > 10*usageBufferSize {
+					u.logger.Warn("dropping unflushed usage records", "records", len(batch))
+					batch = batch[:0]
+				}
+				continue
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+const rollupUsage = `insert into api_usage_hourly (key_id, hour, route, requests, rows_returned, bytes_sent,
+                              total_latency_ms, max_latency_ms)
+select key_id,
+       date_trunc('hour', requested_at),
+       route,
+       count(*),
+       sum(rows_returned),
+       sum(bytes_sent),
+       sum(latency_ms),
+       max(latency_ms)
+from api_usage
+where requested_at >= $1
+group by 1, 2, 3
+on conflict (key_id, hour, route) do update
+    set requests         = excluded.requests,
+        rows_returned    = excluded.rows_returned,
+        bytes_sent       = excluded.bytes_sent,
+        total_latency_ms = excluded.total_latency_ms,
+        max_latency_ms   = excluded.max_latency_ms`
+
+// flush writes the batch and recomputes the rollups for every hour the
+// batch touched.  Both happen in one transaction, so a batch that failed
+// was never written and can be flushed again without counting it twice.
+func (u *UsageRecorder) flush(ctx context.Context, batch []UsageRecord) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	oldest := batch[0].Time
+	rows := make([][]any, 0, len(batch))
+	for _, r := range batch {
+		if r.Time.Before(oldest) {
+			oldest = r.Time
+		}
+		rows = append(rows, []any{
+			r.KeyID, r.Time, r.Method, r.Route, r.Filters, r.Status, r.Rows, r.Bytes,
+			float64(r.Latency.Microseconds()) / 1000,
+		})
+	}
+
+	return pgx.BeginFunc(ctx, u.db, func(tx pgx.Tx) error {
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"api_usage"},
+			[]string{"key_id", "requested_at", "method", "route", "filters", "status", "rows_returned", "bytes_sent", "latency_ms"},
+			pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("failed to write usage records: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, rollupUsage, oldest.Truncate(time.Hour)); err != nil {
+			return fmt.Errorf("failed to roll up usage: %w", err)
+		}
+		return nil
+	})
+}
+
+const getUsageRollups = `select key_id, hour, route, requests, rows_returned, bytes_sent, total_latency_ms, max_latency_ms
+from api_usage_hourly
+where ($1 = '' or key_id = $1)
+  and hour >= $2
+  and hour < $3
+order by key_id, hour, route`
+
+// Rollups returns the hourly usage for keyID between from and to.  An
+// empty keyID returns usage for every key.
+func (u *UsageRecorder) Rollups(ctx context.Context, keyID string, from, to time.Time) ([]UsageRollup, error) {
+	rows, err := u.db.Query(ctx, getUsageRollups, keyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []UsageRollup
+	for rows.Next() {
+		var r UsageRollup
+		var totalLatency float64
+		if err := rows.Scan(&r.KeyID, &r.Hour, &r.Route, &r.Requests, &r.Rows, &r.Bytes, &totalLatency, &r.MaxLatencyMs); err != nil {
+			return nil, err
+		}
+		if r.Requests > 0 {
+			r.AvgLatencyMs = totalLatency / float64(r.Requests)
+		}
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
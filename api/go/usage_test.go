@@ -0,0 +1,168 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseUsageRange(t *testing.T) {
+	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  string
+	}{
+		{
+			name:     "should default to the last 30 days",
+			wantFrom: tomorrow.AddDate(0, 0, -defaultUsageDays),
+			wantTo:   tomorrow,
+		},
+		{
+			name:     "should include the whole of the to date",
+			query:    "from=2024-05-01&to=2024-05-31",
+			wantFrom: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "should accept a single day",
+			query:    "from=2024-05-06&to=2024-05-06",
+			wantFrom: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "should reject a from that isn't a date",
+			query:   "from=05/01/2024",
+			wantErr: "from value not valid format, use YYYY-MM-DD",
+		},
+		{
+			name:    "should reject a to that isn't a date",
+			query:   "to=2024-5-1",
+			wantErr: "to value not valid format, use YYYY-MM-DD",
+		},
+		{
+			name:    "should reject a from after to",
+			query:   "from=2024-05-02&to=2024-05-01",
+			wantErr: "from must be on or before to",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/account/usage?"+tt.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			from, to, errResponse := parseUsageRange(c)
+			if tt.wantErr != "" {
+				assert.Equal(t, ApiResponse{Code: 400, Message: tt.wantErr}, errResponse)
+				return
+			}
+			assert.Zero(t, errResponse.Code)
+			assert.Equal(t, tt.wantFrom, from)
+			assert.Equal(t, tt.wantTo, to)
+		})
+	}
+}
+
+func Test_writeUsageCSV(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeUsageCSV(&b, []UsageRollup{{
+		KeyID:        "0123456789abcdef",
+		Hour:         time.Date(2024, 5, 6, 21, 0, 0, 0, time.FixedZone("CDT", -5*60*60)),
+		Route:        "/api/v1/report/hail",
+		Requests:     3,
+		Rows:         120,
+		Bytes:        40960,
+		AvgLatencyMs: 12.3456,
+		MaxLatencyMs: 30,
+	}}))
+	assert.Equal(t, "key_id,hour,route,requests,rows,bytes,avg_latency_ms,max_latency_ms\n"+
+		"0123456789abcdef,2024-05-07T02:00:00Z,/api/v1/report/hail,3,120,40960,12.346,30.000\n", b.String())
+
+	b.Reset()
+	require.NoError(t, writeUsageCSV(&b, nil))
+	assert.Equal(t, "key_id,hour,route,requests,rows,bytes,avg_latency_ms,max_latency_ms\n", b.String(), "the header is written without usage")
+}
+
+func TestUsageRecorder_Middleware(t *testing.T) {
+	u := NewUsageRecorder(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("X-Api-Key") != "" {
+				c.Set(apiKeyIDContextKey, apiKeyID(c.Request().Header.Get("X-Api-Key")))
+			}
+			return next(c)
+		}
+	})
+	e.Use(u.Middleware())
+	e.GET("/api/v1/report/:type", func(c echo.Context) error {
+		setUsageRows(c, 2)
+		return c.JSON(http.StatusOK, []int{1, 2})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/report/hail?state=OK&api_key=secret", nil)
+	req.Header.Set("X-Api-Key", "rokey")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	require.Len(t, u.records, 1)
+	r := <-u.records
+	assert.Equal(t, apiKeyID("rokey"), r.KeyID)
+	assert.Equal(t, http.MethodGet, r.Method)
+	assert.Equal(t, "/api/v1/report/:type", r.Route, "the route template, not the path")
+	assert.Equal(t, "state=OK", r.Filters, "the key isn't recorded")
+	assert.Equal(t, http.StatusOK, r.Status)
+	assert.Equal(t, 2, r.Rows)
+	assert.Positive(t, r.Bytes)
+
+	// requests without a key aren't anyone's usage.
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/report/hail", nil))
+	assert.Empty(t, u.records)
+}
+
+func TestUsageRoutes(t *testing.T) {
+	u := NewUsageRecorder(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s := NewRouter(RouterConfig{
+		ROKey:  "rokey",
+		RWKey:  "rwkey",
+		Usage:  u,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantRecord bool
+	}{
+		{name: "should reject the read only key on admin routes", key: "rokey", wantStatus: http.StatusUnauthorized},
+		{name: "should reject a wrong key", key: "wrong", wantStatus: http.StatusUnauthorized},
+		// the range is checked before the database is touched.
+		{name: "should accept the read/write key", key: "rwkey", wantStatus: http.StatusBadRequest, wantRecord: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/usage/export?from=2024-05-02&to=2024-05-01", nil)
+			req.Header.Set("X-Api-Key", tt.key)
+			rec := httptest.NewRecorder()
+			s.Web.ServeHTTP(rec, req)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if !tt.wantRecord {
+				assert.Empty(t, u.records)
+				return
+			}
+			require.Len(t, u.records, 1)
+			r := <-u.records
+			assert.Equal(t, apiKeyID("rwkey"), r.KeyID)
+			assert.Equal(t, "/api/v1/admin/usage/export", r.Route)
+			assert.Equal(t, http.StatusBadRequest, r.Status)
+		})
+	}
+}
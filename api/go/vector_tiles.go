@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+	"github.com/jason-costello/weather/accesssvc/mvt"
+)
+
+const (
+	// MaxVectorTileZoom is the deepest zoom report vector tiles are served
+	// for, past it maps overzoom.
+	MaxVectorTileZoom = 16
+	// reportClusterZoom is the first zoom reports aren't clustered at.
+	reportClusterZoom = 8
+	// reportTileBuffer is how far outside the tile, in tile units, points
+	// are included so symbols on the edge aren't cut in half.
+	reportTileBuffer = 64
+	// reportClusterSize is the width of the cells reports are clustered
+	// into, in tile units.  Cells line up with tile edges so a cluster is
+	// the same in every tile it shows up in.
+	reportClusterSize = 128
+	// reportTileLayerName is the name of the layer holding the reports.
+	reportTileLayerName = "reports"
+)
+
+// TilePoint is a report as drawn on a vector tile.
+type TilePoint struct {
+	ID          int64
+	Type        string
+	Time        time.Time
+	Magnitude   *int32
+	Measurement string
+	State       string
+	County      string
+	Location    string
+	Point       geo.Point
+}
+
+// QueryTilePoints returns the located reports matching the filter.  The
+// filter's limit and offset are ignored.
+func QueryTilePoints(ctx context.Context, db database.DBTX, f ReportFilter) ([]TilePoint, error) {
+	f.Limit, f.Offset = 0, 0
+	where, args := f.where()
+	query := `
+select id,
+       rpt_type::text,
+       reported_time,
+       ` + magnitudeSQL + `,
+       coalesce(wind_measurement, ''),
+       coalesce("state", ''),
+       county,
+       location,
+       ` + latitudeSQL + ` as lat,
+       ` + longitudeSQL + ` as lon
+from reports
+where ` + where + `
+  and ` + latitudeSQL + ` is not null
+  and ` + longitudeSQL + ` is not null
+order by reported_time, id`
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var points []TilePoint
+	for rows.Next() {
+		var p TilePoint
+		if err := rows.Scan(&p.ID, &p.Type, &p.Time, &p.Magnitude, &p.Measurement, &p.State, &p.County, &p.Location, &p.Point.Lat, &p.Point.Lon); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// TileCluster is the reports of one type in one cluster cell of a tile.
+type TileCluster struct {
+	// Cell is the cell's column and row, reportClusterSize tile units
+	// wide, counted from the tile's top left corner.
+	Cell  [2]int
+	Type  string
+	Count int
+	// SumX and SumY add up the reports' positions in tile units.
+	SumX, SumY   int64
+	First, Last  time.Time
+	MaxMagnitude *int32
+	// Report is the report when Count is 1.
+	Report TilePoint
+}
+
+// tileClusterSQL groups the located reports into the cells of a tile, it
+// is the sql version of geo.TilePoint.  Cells are floored so the points in
+// the buffer left of and above the tile fall in the cells next to it.
+// %[1]s to %[4]s are the args of the tile's zoom scale, x, y and the cell
+// size, %[5]s is the filter, and %[6]s and %[7]s are the tile extent and
+// geo.MaxMercatorLat.
+const tileClusterSQL = `
+with located as (select id, rpt_type::text as rpt_type, reported_time, ` + magnitudeSQL + ` as mag,
+                        coalesce(wind_measurement, '') as measurement, coalesce("state", '') as state, county, location,
+                        ` + latitudeSQL + ` as lat, ` + longitudeSQL + ` as lon
+                 from reports
+                 where %[5]s),
+     points as (select *,
+                       floor(((lon + 180) / 360 * %[1]s - %[2]s) * %[6]s)::int as px,
+                       floor(((1 - ln(tan(radians(mlat)) + 1 / cos(radians(mlat))) / pi()) / 2 * %[1]s - %[3]s) * %[6]s)::int as py
+                from located
+                         cross join lateral (select greatest(-%[7]s, least(%[7]s, lat)) as mlat) m
+                where lat is not null
+                  and lon is not null)
+select floor(px::double precision / %[4]s)::int,
+       floor(py::double precision / %[4]s)::int,
+       rpt_type,
+       count(*),
+       sum(px),
+       sum(py),
+       min(reported_time),
+       max(reported_time),
+       max(mag),
+       min(id),
+       min(measurement),
+       min(state),
+       min(county),
+       min(location),
+       min(lat),
+       min(lon)
+from points
+group by 1, 2, 3
+order by min(reported_time), min(id)`
+
+// QueryTileClusters returns the located reports matching the filter
+// grouped into the clusters of tile z/x/y, so low zoom tiles covering
+// years of reports are counted in the database rather than read.  The
+// filter's limit and offset are ignored.
+func QueryTileClusters(ctx context.Context, db database.DBTX, f ReportFilter, z, x, y int) ([]TileCluster, error) {
+	f.Limit, f.Offset = 0, 0
+	where, args := f.where()
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args)) + "::double precision"
+	}
+	query := fmt.Sprintf(tileClusterSQL, arg(float64(int(1)<<z)), arg(float64(x)), arg(float64(y)), arg(float64(reportClusterSize)), where,
+		arg(float64(mvt.DefaultExtent)), arg(geo.MaxMercatorLat))
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var clusters []TileCluster
+	for rows.Next() {
+		var c TileCluster
+		var count int64
+		p := &c.Report
+		if err := rows.Scan(&c.Cell[0], &c.Cell[1], &c.Type, &count, &c.SumX, &c.SumY, &c.First, &c.Last, &c.MaxMagnitude,
+			&p.ID, &p.Measurement, &p.State, &p.County, &p.Location, &p.Point.Lat, &p.Point.Lon); err != nil {
+			return nil, err
+		}
+		c.Count = int(count)
+		p.Type, p.Time, p.Magnitude = c.Type, c.First, c.MaxMagnitude
+		clusters = append(clusters, c)
+	}
+	return clusters, rows.Err()
+}
+
+// reportTileBBox returns the area of tile z/x/y whose reports are drawn on
+// it, the tile and its buffer.
+func reportTileBBox(z, x, y int) BBox {
+	buffer := float64(reportTileBuffer)
+	if z < reportClusterZoom {
+		// whole clusters, so they come out the same in every tile.
+		buffer = reportClusterSize
+	}
+	pad := buffer / mvt.DefaultExtent
+	sw := geo.TileCorner(z, float64(x)-pad, float64(y+1)+pad)
+	ne := geo.TileCorner(z, float64(x+1)+pad, float64(y)-pad)
+	return BBox{
+		MinLon: math.Max(sw.Lon, -180),
+		MinLat: sw.Lat,
+		MaxLon: math.Min(ne.Lon, 180),
+		MaxLat: ne.Lat,
+	}
+}
+
+// reportTileLayer draws the points on tile z/x/y from reportClusterZoom
+// on.
+func reportTileLayer(points []TilePoint, z, x, y int) mvt.Layer {
+	layer := mvt.Layer{Name: reportTileLayerName, Extent: mvt.DefaultExtent}
+	for _, p := range points {
+		fx, fy := geo.TilePoint(p.Point, z)
+		px, py := int(math.Floor((fx-float64(x))*mvt.DefaultExtent)), int(math.Floor((fy-float64(y))*mvt.DefaultExtent))
+		if inTileBuffer(px, py) {
+			layer.Features = append(layer.Features, reportFeature(p, px, py))
+		}
+	}
+	return layer
+}
+
+// clusterTileLayer draws the clusters of a tile below reportClusterZoom,
+// the rows of a cell are drawn as one cluster at their mean position.
+func clusterTileLayer(rows []TileCluster) mvt.Layer {
+	layer := mvt.Layer{Name: reportTileLayerName, Extent: mvt.DefaultExtent}
+	var order [][2]int
+	cells := map[[2]int][]TileCluster{}
+	for _, r := range rows {
+		if _, ok := cells[r.Cell]; !ok {
+			order = append(order, r.Cell)
+		}
+		cells[r.Cell] = append(cells[r.Cell], r)
+	}
+	for _, key := range order {
+		cell := cells[key]
+		var n int
+		var sumX, sumY int64
+		for _, r := range cell {
+			n += r.Count
+			sumX += r.SumX
+			sumY += r.SumY
+		}
+		px, py := int(sumX/int64(n)), int(sumY/int64(n))
+		if !inTileBuffer(px, py) {
+			continue
+		}
+		if n == 1 {
+			layer.Features = append(layer.Features, reportFeature(cell[0].Report, px, py))
+			continue
+		}
+		layer.Features = append(layer.Features, clusterFeature(cell, n, px, py))
+	}
+	return layer
+}
+
+// inTileBuffer reports whether a point in tile units is on the tile or in
+// its buffer.
+func inTileBuffer(px, py int) bool {
+	return px >= -reportTileBuffer && px < mvt.DefaultExtent+reportTileBuffer &&
+		py >= -reportTileBuffer && py < mvt.DefaultExtent+reportTileBuffer
+}
+
+// reportFeature draws a single report.  Times are unix seconds so map
+// styles can filter on them.
+func reportFeature(p TilePoint, px, py int) mvt.Feature {
+	props := map[string]any{
+		"type":     p.Type,
+		"time":     p.Time.Unix(),
+		"county":   p.County,
+		"location": p.Location,
+	}
+	if p.Magnitude != nil {
+		props["magnitude"] = *p.Magnitude
+	}
+	if p.Measurement != "" {
+		props["measurement"] = p.Measurement
+	}
+	if p.State != "" {
+		props["state"] = p.State
+	}
+	return mvt.Feature{ID: uint64(p.ID), X: px, Y: py, Properties: props}
+}
+
+// clusterFeature draws the n reports of a cell as one point with their
+// count, time range, and the count and largest magnitude of each type.
+func clusterFeature(cell []TileCluster, n, px, py int) mvt.Feature {
+	props := map[string]any{
+		"cluster":     true,
+		"point_count": n,
+	}
+	first, last := cell[0].First, cell[0].Last
+	for _, r := range cell {
+		if r.First.Before(first) {
+			first = r.First
+		}
+		if r.Last.After(last) {
+			last = r.Last
+		}
+		props["count_"+r.Type] = r.Count
+		if r.MaxMagnitude != nil {
+			props["max_"+r.Type] = *r.MaxMagnitude
+		}
+	}
+	props["time_min"] = first.Unix()
+	props["time_max"] = last.Unix()
+	return mvt.Feature{X: px, Y: py, Properties: props}
+}
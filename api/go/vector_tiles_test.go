@@ -0,0 +1,66 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+func Test_reportTileLayer(t *testing.T) {
+	mag := int32(175)
+	morning := time.Date(2024, 5, 6, 14, 0, 0, 0, time.UTC)
+	points := []TilePoint{
+		{ID: 1, Type: "hail", Time: morning, Magnitude: &mag, Point: geo.Point{Lon: -97.52, Lat: 35.47}},
+		{ID: 2, Type: "wind", Time: morning.Add(time.Hour), Point: geo.Point{Lon: -97.51, Lat: 35.48}},
+		{ID: 3, Type: "hail", Time: morning, Point: geo.Point{Lon: -95.99, Lat: 36.15}},
+		// far outside the tile and its buffer.
+		{ID: 4, Type: "tornado", Time: morning, Point: geo.Point{Lon: -80, Lat: 25}},
+	}
+
+	t.Run("should draw every report past the cluster zoom", func(t *testing.T) {
+		x, y := geo.TileXY(points[0].Point, 8)
+		layer := reportTileLayer(points, 8, x, y)
+		require.Len(t, layer.Features, 2)
+		assert.Equal(t, uint64(1), layer.Features[0].ID)
+		assert.Equal(t, mag, layer.Features[0].Properties["magnitude"])
+		assert.Equal(t, morning.Unix(), layer.Features[0].Properties["time"])
+		assert.NotContains(t, layer.Features[1].Properties, "magnitude")
+	})
+
+}
+
+func Test_clusterTileLayer(t *testing.T) {
+	mag := int32(175)
+	morning := time.Date(2024, 5, 6, 14, 0, 0, 0, time.UTC)
+	// the rows QueryTileClusters returns for an Oklahoma City hail and wind
+	// report in one cell, a Tulsa report in another, and a cell whose
+	// center is outside the buffer.
+	rows := []TileCluster{
+		{Cell: [2]int{3, 4}, Type: "hail", Count: 1, SumX: 400, SumY: 600, First: morning, Last: morning, MaxMagnitude: &mag},
+		{Cell: [2]int{10, 2}, Type: "hail", Count: 1, SumX: 1300, SumY: 300, First: morning, Last: morning,
+			Report: TilePoint{ID: 3, Type: "hail", Time: morning}},
+		{Cell: [2]int{3, 4}, Type: "wind", Count: 2, SumX: 820, SumY: 1220, First: morning.Add(time.Hour), Last: morning.Add(2 * time.Hour)},
+		{Cell: [2]int{40, 2}, Type: "tornado", Count: 1, SumX: 5200, SumY: 300, First: morning, Last: morning},
+	}
+
+	layer := clusterTileLayer(rows)
+	require.Len(t, layer.Features, 2)
+	cluster := layer.Features[0]
+	assert.Equal(t, 406, cluster.X)
+	assert.Equal(t, 606, cluster.Y)
+	assert.Equal(t, true, cluster.Properties["cluster"])
+	assert.Equal(t, 3, cluster.Properties["point_count"])
+	assert.Equal(t, 1, cluster.Properties["count_hail"])
+	assert.Equal(t, 2, cluster.Properties["count_wind"])
+	assert.Equal(t, mag, cluster.Properties["max_hail"])
+	assert.NotContains(t, cluster.Properties, "max_wind")
+	assert.Equal(t, morning.Unix(), cluster.Properties["time_min"])
+	assert.Equal(t, morning.Add(2*time.Hour).Unix(), cluster.Properties["time_max"])
+	// a cluster of one is drawn as the report.
+	assert.Equal(t, uint64(3), layer.Features[1].ID)
+	assert.Equal(t, 1300, layer.Features[1].X)
+}
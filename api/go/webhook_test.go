@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+)
+
+// okCounties covers Cleveland and McClain counties in Oklahoma.
+const okCounties = `{"type":"Polygon","coordinates":[[[-97.7,34.9],[-97.1,34.9],[-97.1,35.4],[-97.7,35.4],[-97.7,34.9]]]}`
+
+type memoryWebhooks struct {
+	mu         sync.Mutex
+	targets    []webhookTarget
+	deliveries []WebhookDelivery
+	failures   map[int64]int
+}
+
+func (m *memoryWebhooks) enabled(context.Context) ([]webhookTarget, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var enabled []webhookTarget
+	for _, t := range m.targets {
+		if t.Enabled {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled, nil
+}
+
+func (m *memoryWebhooks) logDelivery(_ context.Context, _ int64, d WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func (m *memoryWebhooks) recordResult(_ context.Context, id int64, succeeded bool, disableAfter int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if succeeded {
+		m.failures[id] = 0
+		return false, nil
+	}
+	m.failures[id]++
+	if m.failures[id] < disableAfter {
+		return false, nil
+	}
+	for i := range m.targets {
+		if m.targets[i].ID == id {
+			m.targets[i].Enabled = false
+		}
+	}
+	return true, nil
+}
+
+func (m *memoryWebhooks) log() []WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]WebhookDelivery(nil), m.deliveries...)
+}
+
+func hailReport(lat, lon string, size int32) database.InsertReportParams {
+	return database.InsertReportParams{
+		RptType:   database.ReportTypeHail,
+		VarCol:    pgtype.Int4{Int32: size, Valid: true},
+		Latitude:  pgtype.Text{String: lat, Valid: true},
+		Longitude: pgtype.Text{String: lon, Valid: true},
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+// startDispatcher delivers to url with client, nil keeps the client that
+// only connects to public addresses.
+func startDispatcher(t *testing.T, url string, client *http.Client) (*memoryWebhooks, *pubsub.Broker) {
+	t.Helper()
+	min := int32(100)
+	target, err := newWebhookTarget(Webhook{
+		ID:          1,
+		URL:         url,
+		Area:        json.RawMessage(okCounties),
+		MinHailSize: &min,
+		Enabled:     true,
+	}, "secret")
+	require.NoError(t, err)
+
+	store := &memoryWebhooks{targets: []webhookTarget{target}, failures: make(map[int64]int)}
+	d := newWebhookDispatcher(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if client != nil {
+		d.client = client
+	}
+	d.backoff = time.Millisecond
+	d.maxAttempts = 3
+	d.disableAfter = 2
+
+	broker := pubsub.NewBroker(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := broker.Subscribe(16)
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx, sub, 2)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return store, broker
+}
+
+func TestWebhookDispatcher_retries(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []WebhookPayload
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sig := r.Header.Get(webhookSignatureHeader)
+		ts, _, _ := strings.Cut(strings.TrimPrefix(sig, "t="), ",")
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if !assert.NoError(t, err) || !assert.Equal(t, SignWebhookPayload("secret", time.Unix(unix, 0), body), sig) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// fail the first attempt so the delivery is retried.
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p WebhookPayload
+		assert.NoError(t, json.Unmarshal(body, &p))
+		payloads = append(payloads, p)
+	}))
+	defer receiver.Close()
+
+	store, broker := startDispatcher(t, receiver.URL, receiver.Client())
+	broker.Publish(pubsub.Message{Report: hailReport("32.78", "-96.80", 175)}) // Dallas, outside the area.
+	broker.Publish(pubsub.Message{Report: hailReport("35.22", "-97.44", 50)})  // Norman but too small.
+	broker.Publish(pubsub.Message{Report: hailReport("35.22", "-97.44", 175)}) // Norman.
+
+	require.Eventually(t, func() bool { return len(store.log()) == 2 }, 5*time.Second, 5*time.Millisecond)
+	log := store.log()
+	assert.False(t, log[0].Succeeded)
+	assert.Equal(t, http.StatusServiceUnavailable, *log[0].StatusCode)
+	assert.True(t, log[1].Succeeded)
+	assert.Equal(t, 2, log[1].Attempt)
+	assert.Equal(t, log[0].DeliveryID, log[1].DeliveryID, "retries keep the delivery id")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, webhookEvent, payloads[0].Event)
+	assert.Equal(t, "35.22", payloads[0].Report.Lat)
+}
+
+func TestWebhookDispatcher_disables(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store, broker := startDispatcher(t, receiver.URL, receiver.Client())
+	for i := 0; i < 2; i++ {
+		broker.Publish(pubsub.Message{Report: hailReport("35.22", "-97.44", 175)})
+	}
+	require.Eventually(t, func() bool {
+		targets, _ := store.enabled(context.Background())
+		return len(targets) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// 2 deliveries of 3 attempts each.
+	assert.Len(t, store.log(), 6)
+	broker.Publish(pubsub.Message{Report: hailReport("35.22", "-97.44", 175)})
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, store.log(), 6, "a disabled webhook is not sent anything")
+}
+
+func TestWebhookDispatcher_refusesLocalAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	store, broker := startDispatcher(t, receiver.URL, nil)
+	broker.Publish(pubsub.Message{Report: hailReport("35.22", "-97.44", 175)})
+
+	require.Eventually(t, func() bool { return len(store.log()) == 3 }, 5*time.Second, 5*time.Millisecond)
+	for _, d := range store.log() {
+		assert.False(t, d.Succeeded)
+		assert.Contains(t, d.Error, errWebhookAddress.Error())
+	}
+	assert.False(t, called, "the loopback receiver is never connected to")
+}
+
+func Test_validateWebhookRequest(t *testing.T) {
+	lookup := lookupWebhookHost
+	t.Cleanup(func() { lookupWebhookHost = lookup })
+	lookupWebhookHost = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.1.2.3")}, nil
+		}
+		return lookup(ctx, network, host)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "public host", url: "https://hooks.example.com/storms"},
+		{name: "public address", url: "http://93.184.216.34:8080/storms"},
+		{name: "not http", url: "ftp://hooks.example.com/storms", want: "url must be an absolute http or https url"},
+		{name: "host resolves to a private address", url: "https://internal.example.com/storms", want: errWebhookAddress.Error()},
+		{name: "loopback", url: "http://127.0.0.1:9000/", want: errWebhookAddress.Error()},
+		{name: "ipv6 loopback", url: "http://[::1]/", want: errWebhookAddress.Error()},
+		{name: "private", url: "http://192.168.1.10/", want: errWebhookAddress.Error()},
+		{name: "link local metadata", url: "http://169.254.169.254/latest/meta-data", want: errWebhookAddress.Error()},
+		{name: "unspecified", url: "http://0.0.0.0/", want: errWebhookAddress.Error()},
+		{name: "this network", url: "http://0.1.2.3/", want: errWebhookAddress.Error()},
+		{name: "carrier grade nat", url: "http://100.100.1.1/", want: errWebhookAddress.Error()},
+		{name: "past carrier grade nat", url: "http://100.128.0.1/"},
+		{name: "ipv4 mapped loopback", url: "http://[::ffff:127.0.0.1]/", want: errWebhookAddress.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateWebhookRequest(context.Background(), WebhookRequest{URL: tt.url, Area: json.RawMessage(okCounties)})
+			assert.Equal(t, tt.want, got.Message)
+		})
+	}
+}
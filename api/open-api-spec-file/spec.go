@@ -0,0 +1,12 @@
+// Package openapispec embeds the StormSync OpenAPI specification so the
+// server can serve it and validate requests against it.
+package openapispec
+
+import (
+	_ "embed"
+)
+
+// YAML is the OpenAPI 3 specification for the api served by api/go.
+//
+//go:embed swagger.yaml
+var YAML []byte
@@ -0,0 +1,5 @@
+package reportspb
+
+// report.proto is read from the transformer module, which is where its Go
+// types live too.
+//go:generate sh -c "protoc -I . -I $(go list -m -f '{{.Dir}}' github.com/stormsync/transformer)/proto --go_out=. --go_opt=paths=source_relative,Mreport.proto=github.com/stormsync/transformer/proto --go-grpc_out=. --go-grpc_opt=paths=source_relative,Mreport.proto=github.com/stormsync/transformer/proto reports.proto"
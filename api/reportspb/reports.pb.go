@@ -0,0 +1,601 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        v4.25.3
+// source: reports.proto
+
+// ReportService queries the stored storm reports with the same filters as
+// the REST report endpoints, and streams new ones as they are ingested.
+// Every call needs the read only api key in the x-api-key metadata.
+
+package reportspb
+
+import (
+	proto "github.com/stormsync/transformer/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ReportQuery holds the filters of the REST report endpoints, they are
+// validated the same way.  Unset fields don't filter.
+type ReportQuery struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// types is any of hail, wind and tornado, every type when empty.
+	Types []string `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+	// date is a day, YYYY-MM-DD, in UTC.
+	Date string `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	// from_date and to_date bound the reported time, YYYY-MM-DD or RFC 3339.
+	FromDate  string `protobuf:"bytes,3,opt,name=from_date,json=fromDate,proto3" json:"from_date,omitempty"`
+	ToDate    string `protobuf:"bytes,4,opt,name=to_date,json=toDate,proto3" json:"to_date,omitempty"`
+	State     string `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	Office    string `protobuf:"bytes,6,opt,name=office,proto3" json:"office,omitempty"`
+	County    string `protobuf:"bytes,7,opt,name=county,proto3" json:"county,omitempty"`
+	Location  string `protobuf:"bytes,8,opt,name=location,proto3" json:"location,omitempty"`
+	Direction string `protobuf:"bytes,9,opt,name=direction,proto3" json:"direction,omitempty"`
+	Distance  *int32 `protobuf:"varint,10,opt,name=distance,proto3,oneof" json:"distance,omitempty"`
+	Comments  string `protobuf:"bytes,11,opt,name=comments,proto3" json:"comments,omitempty"`
+	// bbox is min_lon,min_lat,max_lon,max_lat.
+	Bbox        string `protobuf:"bytes,12,opt,name=bbox,proto3" json:"bbox,omitempty"`
+	Measurement string `protobuf:"bytes,13,opt,name=measurement,proto3" json:"measurement,omitempty"`
+	// quality is clean or flagged.
+	Quality string `protobuf:"bytes,14,opt,name=quality,proto3" json:"quality,omitempty"`
+	// magnitude bounds need exactly one type, like the REST endpoints they
+	// are in hundredths of an inch, mph or the EF rating.
+	MagnitudeGreaterThan *int32 `protobuf:"varint,15,opt,name=magnitude_greater_than,json=magnitudeGreaterThan,proto3,oneof" json:"magnitude_greater_than,omitempty"`
+	MagnitudeLessThan    *int32 `protobuf:"varint,16,opt,name=magnitude_less_than,json=magnitudeLessThan,proto3,oneof" json:"magnitude_less_than,omitempty"`
+	Limit                int32  `protobuf:"varint,17,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset               int32  `protobuf:"varint,18,opt,name=offset,proto3" json:"offset,omitempty"`
+}
+
+func (x *ReportQuery) Reset() {
+	*x = ReportQuery{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reports_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReportQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportQuery) ProtoMessage() {}
+
+func (x *ReportQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_reports_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportQuery.ProtoReflect.Descriptor instead.
+func (*ReportQuery) Descriptor() ([]byte, []int) {
+	return file_reports_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReportQuery) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *ReportQuery) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetFromDate() string {
+	if x != nil {
+		return x.FromDate
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetToDate() string {
+	if x != nil {
+		return x.ToDate
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetOffice() string {
+	if x != nil {
+		return x.Office
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetCounty() string {
+	if x != nil {
+		return x.County
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetDistance() int32 {
+	if x != nil && x.Distance != nil {
+		return *x.Distance
+	}
+	return 0
+}
+
+func (x *ReportQuery) GetComments() string {
+	if x != nil {
+		return x.Comments
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetBbox() string {
+	if x != nil {
+		return x.Bbox
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetMeasurement() string {
+	if x != nil {
+		return x.Measurement
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetQuality() string {
+	if x != nil {
+		return x.Quality
+	}
+	return ""
+}
+
+func (x *ReportQuery) GetMagnitudeGreaterThan() int32 {
+	if x != nil && x.MagnitudeGreaterThan != nil {
+		return *x.MagnitudeGreaterThan
+	}
+	return 0
+}
+
+func (x *ReportQuery) GetMagnitudeLessThan() int32 {
+	if x != nil && x.MagnitudeLessThan != nil {
+		return *x.MagnitudeLessThan
+	}
+	return 0
+}
+
+func (x *ReportQuery) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ReportQuery) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// Report is a stored report, the transformer message of its type plus
+// what the service adds to it.
+type Report struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Report:
+	//	*Report_Hail
+	//	*Report_Wind
+	//	*Report_Tornado
+	Report isReport_Report `protobuf_oneof:"report"`
+	// office is the id of the issuing Weather Forecast Office.
+	Office string `protobuf:"bytes,5,opt,name=office,proto3" json:"office,omitempty"`
+	// flags are the data quality flags raised when it was ingested.
+	Flags []string `protobuf:"bytes,6,rep,name=flags,proto3" json:"flags,omitempty"`
+	// review is accepted or rejected once a flagged report is reviewed.
+	Review string `protobuf:"bytes,7,opt,name=review,proto3" json:"review,omitempty"`
+	// measurement is how a wind speed was arrived at.
+	Measurement string `protobuf:"bytes,8,opt,name=measurement,proto3" json:"measurement,omitempty"`
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reports_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_reports_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_reports_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Report) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (m *Report) GetReport() isReport_Report {
+	if m != nil {
+		return m.Report
+	}
+	return nil
+}
+
+func (x *Report) GetHail() *proto.HailMsg {
+	if x, ok := x.GetReport().(*Report_Hail); ok {
+		return x.Hail
+	}
+	return nil
+}
+
+func (x *Report) GetWind() *proto.WindMsg {
+	if x, ok := x.GetReport().(*Report_Wind); ok {
+		return x.Wind
+	}
+	return nil
+}
+
+func (x *Report) GetTornado() *proto.TornadoMsg {
+	if x, ok := x.GetReport().(*Report_Tornado); ok {
+		return x.Tornado
+	}
+	return nil
+}
+
+func (x *Report) GetOffice() string {
+	if x != nil {
+		return x.Office
+	}
+	return ""
+}
+
+func (x *Report) GetFlags() []string {
+	if x != nil {
+		return x.Flags
+	}
+	return nil
+}
+
+func (x *Report) GetReview() string {
+	if x != nil {
+		return x.Review
+	}
+	return ""
+}
+
+func (x *Report) GetMeasurement() string {
+	if x != nil {
+		return x.Measurement
+	}
+	return ""
+}
+
+type isReport_Report interface {
+	isReport_Report()
+}
+
+type Report_Hail struct {
+	Hail *proto.HailMsg `protobuf:"bytes,2,opt,name=hail,proto3,oneof"`
+}
+
+type Report_Wind struct {
+	Wind *proto.WindMsg `protobuf:"bytes,3,opt,name=wind,proto3,oneof"`
+}
+
+type Report_Tornado struct {
+	Tornado *proto.TornadoMsg `protobuf:"bytes,4,opt,name=tornado,proto3,oneof"`
+}
+
+func (*Report_Hail) isReport_Report() {}
+
+func (*Report_Wind) isReport_Report() {}
+
+func (*Report_Tornado) isReport_Report() {}
+
+type ReportList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Reports []*Report `protobuf:"bytes,1,rep,name=reports,proto3" json:"reports,omitempty"`
+}
+
+func (x *ReportList) Reset() {
+	*x = ReportList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reports_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReportList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportList) ProtoMessage() {}
+
+func (x *ReportList) ProtoReflect() protoreflect.Message {
+	mi := &file_reports_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportList.ProtoReflect.Descriptor instead.
+func (*ReportList) Descriptor() ([]byte, []int) {
+	return file_reports_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReportList) GetReports() []*Report {
+	if x != nil {
+		return x.Reports
+	}
+	return nil
+}
+
+var File_reports_proto protoreflect.FileDescriptor
+
+var file_reports_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x14, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e, 0x63, 0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72,
+	0x74, 0x73, 0x2e, 0x76, 0x31, 0x1a, 0x0c, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0xd8, 0x04, 0x0a, 0x0b, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65, 0x12, 0x1b, 0x0a,
+	0x09, 0x66, 0x72, 0x6f, 0x6d, 0x5f, 0x64, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x66, 0x72, 0x6f, 0x6d, 0x44, 0x61, 0x74, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x74, 0x6f,
+	0x5f, 0x64, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x74, 0x6f, 0x44,
+	0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66,
+	0x69, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x69, 0x63,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x6f, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x18,
+	0x0a, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x88, 0x01, 0x01, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x12, 0x0a, 0x04, 0x62, 0x62, 0x6f, 0x78, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x62, 0x62, 0x6f, 0x78, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6d, 0x65, 0x61, 0x73, 0x75,
+	0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x71, 0x75, 0x61, 0x6c, 0x69, 0x74,
+	0x79, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x71, 0x75, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x12, 0x39, 0x0a, 0x16, 0x6d, 0x61, 0x67, 0x6e, 0x69, 0x74, 0x75, 0x64, 0x65, 0x5f, 0x67, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x72, 0x5f, 0x74, 0x68, 0x61, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x05,
+	0x48, 0x01, 0x52, 0x14, 0x6d, 0x61, 0x67, 0x6e, 0x69, 0x74, 0x75, 0x64, 0x65, 0x47, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x72, 0x54, 0x68, 0x61, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x33, 0x0a, 0x13, 0x6d,
+	0x61, 0x67, 0x6e, 0x69, 0x74, 0x75, 0x64, 0x65, 0x5f, 0x6c, 0x65, 0x73, 0x73, 0x5f, 0x74, 0x68,
+	0x61, 0x6e, 0x18, 0x10, 0x20, 0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x11, 0x6d, 0x61, 0x67, 0x6e,
+	0x69, 0x74, 0x75, 0x64, 0x65, 0x4c, 0x65, 0x73, 0x73, 0x54, 0x68, 0x61, 0x6e, 0x88, 0x01, 0x01,
+	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74,
+	0x18, 0x12, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x42, 0x0b,
+	0x0a, 0x09, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x42, 0x19, 0x0a, 0x17, 0x5f,
+	0x6d, 0x61, 0x67, 0x6e, 0x69, 0x74, 0x75, 0x64, 0x65, 0x5f, 0x67, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x72, 0x5f, 0x74, 0x68, 0x61, 0x6e, 0x42, 0x16, 0x0a, 0x14, 0x5f, 0x6d, 0x61, 0x67, 0x6e, 0x69,
+	0x74, 0x75, 0x64, 0x65, 0x5f, 0x6c, 0x65, 0x73, 0x73, 0x5f, 0x74, 0x68, 0x61, 0x6e, 0x22, 0x85,
+	0x02, 0x0a, 0x06, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x24, 0x0a, 0x04, 0x68, 0x61, 0x69,
+	0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x48, 0x61, 0x69, 0x6c, 0x4d, 0x73, 0x67, 0x48, 0x00, 0x52, 0x04, 0x68, 0x61, 0x69, 0x6c, 0x12,
+	0x24, 0x0a, 0x04, 0x77, 0x69, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x57, 0x69, 0x6e, 0x64, 0x4d, 0x73, 0x67, 0x48, 0x00, 0x52,
+	0x04, 0x77, 0x69, 0x6e, 0x64, 0x12, 0x2d, 0x0a, 0x07, 0x74, 0x6f, 0x72, 0x6e, 0x61, 0x64, 0x6f,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x54,
+	0x6f, 0x72, 0x6e, 0x61, 0x64, 0x6f, 0x4d, 0x73, 0x67, 0x48, 0x00, 0x52, 0x07, 0x74, 0x6f, 0x72,
+	0x6e, 0x61, 0x64, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x69, 0x63, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x69, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x66, 0x6c, 0x61,
+	0x67, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x76, 0x69, 0x65, 0x77, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x65,
+	0x61, 0x73, 0x75, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x6d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x42, 0x08, 0x0a, 0x06,
+	0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x44, 0x0a, 0x0a, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x4c, 0x69, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x07, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e,
+	0x63, 0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70,
+	0x6f, 0x72, 0x74, 0x52, 0x07, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x32, 0x88, 0x02, 0x0a,
+	0x0d, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x53,
+	0x0a, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x12, 0x21,
+	0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e, 0x63, 0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72,
+	0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x1a, 0x20, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e, 0x63, 0x2e, 0x72, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x4c,
+	0x69, 0x73, 0x74, 0x12, 0x52, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x70,
+	0x6f, 0x72, 0x74, 0x73, 0x12, 0x21, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e, 0x63,
+	0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x51, 0x75, 0x65, 0x72, 0x79, 0x1a, 0x1c, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73,
+	0x79, 0x6e, 0x63, 0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x30, 0x01, 0x12, 0x4e, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x73, 0x63,
+	0x72, 0x69, 0x62, 0x65, 0x12, 0x21, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73, 0x79, 0x6e, 0x63,
+	0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x51, 0x75, 0x65, 0x72, 0x79, 0x1a, 0x1c, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x6d, 0x73,
+	0x79, 0x6e, 0x63, 0x2e, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x30, 0x01, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6a, 0x61, 0x73, 0x6f, 0x6e, 0x2d, 0x63, 0x6f, 0x73, 0x74,
+	0x65, 0x6c, 0x6c, 0x6f, 0x2f, 0x77, 0x65, 0x61, 0x74, 0x68, 0x65, 0x72, 0x2f, 0x61, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x73, 0x76, 0x63, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x72, 0x65, 0x70, 0x6f, 0x72,
+	0x74, 0x73, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_reports_proto_rawDescOnce sync.Once
+	file_reports_proto_rawDescData = file_reports_proto_rawDesc
+)
+
+func file_reports_proto_rawDescGZIP() []byte {
+	file_reports_proto_rawDescOnce.Do(func() {
+		file_reports_proto_rawDescData = protoimpl.X.CompressGZIP(file_reports_proto_rawDescData)
+	})
+	return file_reports_proto_rawDescData
+}
+
+var file_reports_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_reports_proto_goTypes = []interface{}{
+	(*ReportQuery)(nil),      // 0: stormsync.reports.v1.ReportQuery
+	(*Report)(nil),           // 1: stormsync.reports.v1.Report
+	(*ReportList)(nil),       // 2: stormsync.reports.v1.ReportList
+	(*proto.HailMsg)(nil),    // 3: proto.HailMsg
+	(*proto.WindMsg)(nil),    // 4: proto.WindMsg
+	(*proto.TornadoMsg)(nil), // 5: proto.TornadoMsg
+}
+var file_reports_proto_depIdxs = []int32{
+	3, // 0: stormsync.reports.v1.Report.hail:type_name -> proto.HailMsg
+	4, // 1: stormsync.reports.v1.Report.wind:type_name -> proto.WindMsg
+	5, // 2: stormsync.reports.v1.Report.tornado:type_name -> proto.TornadoMsg
+	1, // 3: stormsync.reports.v1.ReportList.reports:type_name -> stormsync.reports.v1.Report
+	0, // 4: stormsync.reports.v1.ReportService.QueryReports:input_type -> stormsync.reports.v1.ReportQuery
+	0, // 5: stormsync.reports.v1.ReportService.StreamReports:input_type -> stormsync.reports.v1.ReportQuery
+	0, // 6: stormsync.reports.v1.ReportService.Subscribe:input_type -> stormsync.reports.v1.ReportQuery
+	2, // 7: stormsync.reports.v1.ReportService.QueryReports:output_type -> stormsync.reports.v1.ReportList
+	1, // 8: stormsync.reports.v1.ReportService.StreamReports:output_type -> stormsync.reports.v1.Report
+	1, // 9: stormsync.reports.v1.ReportService.Subscribe:output_type -> stormsync.reports.v1.Report
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_reports_proto_init() }
+func file_reports_proto_init() {
+	if File_reports_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_reports_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReportQuery); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reports_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Report); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reports_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReportList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_reports_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	file_reports_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*Report_Hail)(nil),
+		(*Report_Wind)(nil),
+		(*Report_Tornado)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_reports_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_reports_proto_goTypes,
+		DependencyIndexes: file_reports_proto_depIdxs,
+		MessageInfos:      file_reports_proto_msgTypes,
+	}.Build()
+	File_reports_proto = out.File
+	file_reports_proto_rawDesc = nil
+	file_reports_proto_goTypes = nil
+	file_reports_proto_depIdxs = nil
+}
@@ -0,0 +1,259 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v4.25.3
+// source: reports.proto
+
+// ReportService queries the stored storm reports with the same filters as
+// the REST report endpoints, and streams new ones as they are ingested.
+// Every call needs the read only api key in the x-api-key metadata.
+
+package reportspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	ReportService_QueryReports_FullMethodName  = "/stormsync.reports.v1.ReportService/QueryReports"
+	ReportService_StreamReports_FullMethodName = "/stormsync.reports.v1.ReportService/StreamReports"
+	ReportService_Subscribe_FullMethodName     = "/stormsync.reports.v1.ReportService/Subscribe"
+)
+
+// ReportServiceClient is the client API for ReportService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReportServiceClient interface {
+	// QueryReports returns the reports matching the query, ordered by time.
+	QueryReports(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (*ReportList, error)
+	// StreamReports sends the reports matching the query one at a time, for
+	// result sets too big for one message.
+	StreamReports(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (ReportService_StreamReportsClient, error)
+	// Subscribe sends every newly stored report that matches the query until
+	// the call is cancelled.  Limit and offset don't apply.  A subscriber
+	// that falls too far behind misses reports, the call then ends with
+	// DATA_LOSS so it can query the gap and subscribe again.
+	Subscribe(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (ReportService_SubscribeClient, error)
+}
+
+type reportServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReportServiceClient(cc grpc.ClientConnInterface) ReportServiceClient {
+	return &reportServiceClient{cc}
+}
+
+func (c *reportServiceClient) QueryReports(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (*ReportList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportList)
+	err := c.cc.Invoke(ctx, ReportService_QueryReports_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportServiceClient) StreamReports(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (ReportService_StreamReportsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportService_ServiceDesc.Streams[0], ReportService_StreamReports_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reportServiceStreamReportsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ReportService_StreamReportsClient interface {
+	Recv() (*Report, error)
+	grpc.ClientStream
+}
+
+type reportServiceStreamReportsClient struct {
+	grpc.ClientStream
+}
+
+func (x *reportServiceStreamReportsClient) Recv() (*Report, error) {
+	m := new(Report)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *reportServiceClient) Subscribe(ctx context.Context, in *ReportQuery, opts ...grpc.CallOption) (ReportService_SubscribeClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportService_ServiceDesc.Streams[1], ReportService_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reportServiceSubscribeClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ReportService_SubscribeClient interface {
+	Recv() (*Report, error)
+	grpc.ClientStream
+}
+
+type reportServiceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *reportServiceSubscribeClient) Recv() (*Report, error) {
+	m := new(Report)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReportServiceServer is the server API for ReportService service.
+// All implementations must embed UnimplementedReportServiceServer
+// for forward compatibility
+type ReportServiceServer interface {
+	// QueryReports returns the reports matching the query, ordered by time.
+	QueryReports(context.Context, *ReportQuery) (*ReportList, error)
+	// StreamReports sends the reports matching the query one at a time, for
+	// result sets too big for one message.
+	StreamReports(*ReportQuery, ReportService_StreamReportsServer) error
+	// Subscribe sends every newly stored report that matches the query until
+	// the call is cancelled.  Limit and offset don't apply.  A subscriber
+	// that falls too far behind misses reports, the call then ends with
+	// DATA_LOSS so it can query the gap and subscribe again.
+	Subscribe(*ReportQuery, ReportService_SubscribeServer) error
+	mustEmbedUnimplementedReportServiceServer()
+}
+
+// UnimplementedReportServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedReportServiceServer struct {
+}
+
+func (UnimplementedReportServiceServer) QueryReports(context.Context, *ReportQuery) (*ReportList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryReports not implemented")
+}
+func (UnimplementedReportServiceServer) StreamReports(*ReportQuery, ReportService_StreamReportsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamReports not implemented")
+}
+func (UnimplementedReportServiceServer) Subscribe(*ReportQuery, ReportService_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedReportServiceServer) mustEmbedUnimplementedReportServiceServer() {}
+
+// UnsafeReportServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReportServiceServer will
+// result in compilation errors.
+type UnsafeReportServiceServer interface {
+	mustEmbedUnimplementedReportServiceServer()
+}
+
+func RegisterReportServiceServer(s grpc.ServiceRegistrar, srv ReportServiceServer) {
+	s.RegisterService(&ReportService_ServiceDesc, srv)
+}
+
+func _ReportService_QueryReports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportServiceServer).QueryReports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportService_QueryReports_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportServiceServer).QueryReports(ctx, req.(*ReportQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportService_StreamReports_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReportQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReportServiceServer).StreamReports(m, &reportServiceStreamReportsServer{ServerStream: stream})
+}
+
+type ReportService_StreamReportsServer interface {
+	Send(*Report) error
+	grpc.ServerStream
+}
+
+type reportServiceStreamReportsServer struct {
+	grpc.ServerStream
+}
+
+func (x *reportServiceStreamReportsServer) Send(m *Report) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ReportService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReportQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReportServiceServer).Subscribe(m, &reportServiceSubscribeServer{ServerStream: stream})
+}
+
+type ReportService_SubscribeServer interface {
+	Send(*Report) error
+	grpc.ServerStream
+}
+
+type reportServiceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *reportServiceSubscribeServer) Send(m *Report) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ReportService_ServiceDesc is the grpc.ServiceDesc for ReportService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReportService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "stormsync.reports.v1.ReportService",
+	HandlerType: (*ReportServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryReports",
+			Handler:    _ReportService_QueryReports_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamReports",
+			Handler:       _ReportService_StreamReports_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _ReportService_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "reports.proto",
+}
@@ -0,0 +1,79 @@
+// Command export writes storm reports to a local directory as Parquet
+// files partitioned by year and type, for loading into pandas or DuckDB.
+//
+//	export -out ./reports -query 'type=hail&from-date=2020-01-01'
+//
+// The query takes the filters of the report endpoints, unknown names are
+// an error rather than ignored.  The database is
+// set with the same DB_* env vars as the server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	api "github.com/jason-costello/weather/accesssvc/api/go"
+)
+
+// dsnQuote escapes a value for a quoted keyword/value connection string.
+var dsnQuote = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func main() {
+	out := flag.String("out", "reports", "directory to write the partitions to")
+	query := flag.String("query", "", "report filters, as the query string of the report endpoints")
+	flag.Parse()
+
+	values, err := url.ParseQuery(*query)
+	if err != nil {
+		log.Fatal("query is not valid: ", err)
+	}
+	errResponse := api.CheckReportFilterNames(values, "")
+	if errResponse.Code > 0 {
+		log.Fatal("query is not valid: ", errResponse.Message)
+	}
+	f, errResponse := api.ParseReportFilter(values, "")
+	if errResponse.Code > 0 {
+		log.Fatal("query is not valid: ", errResponse.Message)
+	}
+
+	dsn := ""
+	for _, v := range []struct{ env, key string }{
+		{"DB_ADDRESS", "host"},
+		{"DB_USER", "user"},
+		{"DB_PASS", "password"},
+		{"DB_NAME", "dbname"},
+	} {
+		value := os.Getenv(v.env)
+		if value == "" {
+			log.Fatalf("%s is required.  Use env var %s", v.key, v.env)
+		}
+		dsn += fmt.Sprintf("%s='%s' ", v.key, dsnQuote.Replace(value))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		log.Fatal("no db ", err)
+	}
+	defer pool.Close()
+
+	if err := os.MkdirAll(*out, 0o750); err != nil {
+		log.Fatal(err)
+	}
+	start := time.Now()
+	rows, err := api.WriteReportParquet(ctx, pool, f, *out)
+	if err != nil {
+		log.Fatal("export failed: ", err)
+	}
+	log.Printf("wrote %d reports to %s in %s", rows, *out, time.Since(start).Round(time.Millisecond))
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"log"
 	"log/slog"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	slogenv "github.com/cbrewster/slog-env"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stormsync/database"
 
 	api "github.com/jason-costello/weather/accesssvc/api/go"
 	"github.com/jason-costello/weather/accesssvc/consumer"
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
 )
 
 func main() {
@@ -69,16 +74,53 @@ func main() {
 		log.Fatal("consumer topic is required.  Use env var CONSUMER_TOPIC")
 	}
 
+	// exports are built here and kept for a week, every instance needs to
+	// see the same directory.
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "stormsync-exports")
+	}
+
+	// internal services query reports over grpc on their own port.
+	grpcAddress := os.Getenv("GRPC_ADDRESS")
+	if grpcAddress == "" {
+		grpcAddress = "0.0.0.0:9090"
+	}
+
+	// reports this close in space and time are grouped into one storm
+	// event.
+	eventThresholds := events.DefaultThresholds
+	if v := os.Getenv("EVENT_RADIUS_MILES"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 {
+			log.Fatal("EVENT_RADIUS_MILES must be a positive number of miles")
+		}
+		eventThresholds.RadiusMiles = radius
+	}
+	if v := os.Getenv("EVENT_WINDOW"); v != "" {
+		window, err := time.ParseDuration(v)
+		if err != nil || window <= 0 {
+			log.Fatal("EVENT_WINDOW must be a positive duration like 30m")
+		}
+		eventThresholds.Window = window
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	conn, err := pgx.Connect(ctx, "user=postgres dbname=weather sslmode=disable")
+	// a pool rather than a single conn, the handlers and the usage
+	// recorder all hit the database concurrently.
+	pool, err := pgxpool.New(ctx, "user=postgres dbname=weather sslmode=disable")
 	if err != nil {
 		log.Fatal("no db", err)
 	}
-	defer conn.Close(ctx)
-	db := database.New(conn)
+	defer pool.Close()
+	db := database.New(pool)
 
-	consumer, err := consumer.NewConsumer(address, consumerTopic, user, pw, groupID, logger, db)
+	// reports stored by the consumer are published here for the
+	// parts of the api that react to new data.
+	broker := pubsub.NewBroker(1000)
+
+	consumer, err := consumer.NewConsumer(address, consumerTopic, user, pw, groupID, logger, pool, broker, eventThresholds)
 	if err != nil {
 		log.Fatal("unable to create consumer: ", err)
 	}
@@ -89,16 +131,53 @@ func main() {
 
 	logger.Info("Starting transform service")
 
+	usage := api.NewUsageRecorder(pool, logger)
+	go usage.Run(ctx, 30*time.Second)
+
+	cache := api.NewResponseCache(1000, 10*time.Minute)
+	go cache.Run(ctx, broker.Subscribe(256))
+
+	webhooks := api.NewWebhookDispatcher(pool, logger)
+	go webhooks.Run(ctx, broker.Subscribe(256), 4)
+
+	climatology := api.NewClimatology(pool, logger)
+	go climatology.Run(ctx, 24*time.Hour)
+
+	tiles := api.NewTileCells(pool, logger)
+	go tiles.Run(ctx, broker.Subscribe(256), time.Minute, 24*time.Hour)
+
+	exports := api.NewExporter(pool, exportDir, logger)
+	go exports.Run(ctx, 2)
+
 	rc := api.RouterConfig{
-		ROKey:  "rokey",
-		RWKey:  "rwkey",
-		DB:     db,
-		Logger: logger,
+		ROKey:           "rokey",
+		RWKey:           "rwkey",
+		DB:              db,
+		Conn:            pool,
+		Usage:           usage,
+		Cache:           cache,
+		Broker:          broker,
+		Webhooks:        webhooks,
+		Climatology:     climatology,
+		Tiles:           tiles,
+		Exports:         exports,
+		EventThresholds: eventThresholds,
+		Logger:          logger,
 	}
 	sdb := api.NewRouter(rc)
 	go func() {
 		sdb.Web.Logger.Fatal(sdb.Web.Start("0.0.0.0:8080"))
 	}()
+	grpcListener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		log.Fatal("unable to listen for grpc: ", err)
+	}
+	go func() {
+		if err := sdb.GRPC.Serve(grpcListener); err != nil {
+			log.Fatal("grpc server failed: ", err)
+		}
+	}()
+	defer sdb.GRPC.Stop()
 
 	for {
 		if err := consumer.GetMessage(ctx); err != nil {
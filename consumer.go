@@ -53,7 +53,7 @@ func (t *Transformer) subscribe(ctx context.Context) error {
 	fmt.Println("subscribing")
 	partitionList, err := t.consumer.Partitions(t.consumerTopic) // get all partitions on the given consumerTopic
 	if err != nil {
-		fmt.Errorf("failed retrieving partitionList for consumerTopic %s: %w", t.consumerTopic, err)
+		return fmt.Errorf("failed retrieving partitionList for consumerTopic %s: %w", t.consumerTopic, err)
 	}
 
 	initialOffset := sarama.OffsetOldest // get offset for the oldest message on the consumerTopic
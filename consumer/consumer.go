@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl/scram"
@@ -16,6 +17,11 @@ import (
 	"github.com/stormsync/database"
 	report "github.com/stormsync/transformer/proto"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/nws"
+	"github.com/jason-costello/weather/accesssvc/pubsub"
+	"github.com/jason-costello/weather/accesssvc/quality"
 )
 
 type Consumer struct {
@@ -26,10 +32,23 @@ type Consumer struct {
 	password string
 	logger   *slog.Logger
 	db       *database.Queries
+	conn     DB
+	quality  *quality.Engine
+	broker   *pubsub.Broker
+	events   events.Thresholds
+}
+
+// DB is the connection reports are stored with, a *pgxpool.Pool or a
+// *pgx.Conn.
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
-// NewConsumer generates a new kafka provider.
-func NewConsumer(address, topic, user, pw, groupID string, logger *slog.Logger, db *database.Queries) (*Consumer, error) {
+// NewConsumer generates a new kafka provider.  Every report stored is
+// checked by the default quality rules, clustered into storm events within
+// eventThresholds and published to broker, which may be nil.
+func NewConsumer(address, topic, user, pw, groupID string, logger *slog.Logger, conn DB, broker *pubsub.Broker, eventThresholds events.Thresholds) (*Consumer, error) {
 	mechanism, err := scram.Mechanism(scram.SHA256, user, pw)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create scram.Mechanism for auth: %w", err)
@@ -52,7 +71,11 @@ func NewConsumer(address, topic, user, pw, groupID string, logger *slog.Logger,
 		user:     user,
 		password: pw,
 		logger:   logger,
-		db:       db,
+		db:       database.New(conn),
+		conn:     conn,
+		quality:  quality.NewEngine(quality.DefaultRules(conn)...),
+		broker:   broker,
+		events:   eventThresholds,
 	}, nil
 
 }
@@ -62,14 +85,13 @@ func NewConsumer(address, topic, user, pw, groupID string, logger *slog.Logger,
 func (c *Consumer) ReadMessage(ctx context.Context) (kafka.Message, error) {
 	message, err := c.Reader.ReadMessage(ctx)
 	if err != nil {
-		fmt.Errorf("failed to read message: %w", err)
-		return kafka.Message{}, err
+		return kafka.Message{}, fmt.Errorf("failed to read message: %w", err)
 	}
 	if err := c.Reader.CommitMessages(ctx, message); err != nil {
-		fmt.Errorf("failed to commit message: %w", err)
+		c.logger.Error("failed to commit message", "error", err)
 	}
 
-	return message, err
+	return message, nil
 }
 
 // GetMessage pulls a message off of the topic, transforms it,
@@ -89,21 +111,42 @@ func (c *Consumer) GetMessage(ctx context.Context) error {
 
 	c.logger.Debug("report type found", "type", reportType.String())
 
-	irp, err := c.processMessage(reportType, msg.Value)
+	irp, suspect, err := c.processMessage(reportType, msg.Value)
+	var rejected *RejectedError
+	if errors.As(err, &rejected) {
+		// a bad report shouldn't stop the ones behind it.
+		c.logger.Warn("rejected report", "type", reportType.String(), "offset", msg.Offset, "problems", rejected.Problems)
+		return nil
+	}
+	if len(suspect) > 0 {
+		c.logger.Warn("storing suspect report", "type", irp.RptType, "state", irp.State.String, "problems", suspect)
+	}
+	flags, err := c.quality.Check(ctx, irp)
 	if err != nil {
-		return fmt.Errorf("failed to process message %s\n%s\nerror: %w", reportType, msg.Value, err)
+		// storing the report unflagged beats dropping it.
+		c.logger.Error("failed to check report quality", "type", irp.RptType, "error", err)
 	}
-	c.logger.Info("Inserting Record", "type", irp.RptType)
-	if _, err := c.db.InsertReport(ctx, []database.InsertReportParams{irp}); err != nil {
-		// quietly ignore duplicate key errors
-		// TODO - use upset style function in db to insert
-		//  or use MERGE on pg 15
-		if !strings.Contains(err.Error(), "duplicate key value violates") {
-			c.logger.Debug("failed to write message to database", "irp", fmt.Sprintf("%#+v", irp))
-			return fmt.Errorf("failed to insert into database: %w", err)
-		}
+	for _, p := range suspect {
+		flags = append(flags, quality.Flag{Name: quality.FlagCorrected, Detail: p.Field + ": " + p.Reason})
 	}
 
+	c.logger.Info("Inserting Record", "type", irp.RptType, "flags", len(flags))
+	id, err := c.storeReport(ctx, irp, flags)
+	if errors.Is(err, errDuplicateReport) {
+		return nil
+	}
+	if err != nil {
+		c.logger.Debug("failed to write message to database", "irp", fmt.Sprintf("%#+v", irp))
+		return fmt.Errorf("failed to insert into database: %w", err)
+	}
+
+	if c.broker != nil {
+		names := make([]string, len(flags))
+		for i, f := range flags {
+			names[i] = f.Name
+		}
+		c.broker.Publish(pubsub.Message{ID: id, Report: irp, Flags: names})
+	}
 	return nil
 }
 
@@ -141,11 +184,10 @@ func getReportTypeFromHeader(hdrs []kafka.Header) (collector.ReportType, error)
 }
 
 // processMessage performs the logic to get a msg off the topic and
-// get it unmarshaled and turned into a dbReport ready to insert into the database.
-func (c *Consumer) processMessage(rptType collector.ReportType, msg []byte) (database.InsertReportParams, error) {
-	var err error
-	var irp database.InsertReportParams
-
+// get it unmarshaled, validated and turned into a dbReport ready to insert
+// into the database.  Messages that can't be used return a RejectedError,
+// suspect lists what was odd about a report that can still be stored.
+func (c *Consumer) processMessage(rptType collector.ReportType, msg []byte) (irp database.InsertReportParams, suspect []Problem, err error) {
 	switch rptType {
 	case collector.Hail:
 		irp, err = processHailMessage(c.logger, msg)
@@ -156,8 +198,14 @@ func (c *Consumer) processMessage(rptType collector.ReportType, msg []byte) (dat
 	default:
 		err = fmt.Errorf("unknown report type %q", rptType.String())
 	}
+	if err != nil {
+		return irp, nil, &RejectedError{
+			RptType:  irp.RptType,
+			Problems: []Problem{{Field: "payload", Reason: err.Error()}},
+		}
+	}
 
-	return irp, err
+	return validateReport(irp, time.Now())
 }
 
 func processHailMessage(logger *slog.Logger, msg []byte) (database.InsertReportParams, error) {
@@ -169,12 +217,13 @@ func processHailMessage(logger *slog.Logger, msg []byte) (database.InsertReportP
 	hailMsg := report.HailMsg{}
 
 	if err := proto.Unmarshal(msg, &hailMsg); err != nil {
-		return irp, nil
+		return irp, fmt.Errorf("failed to unmarshal hail message: %w", err)
 	}
 	logger.Debug("unmarshalled hail message", "type", hailMsg.Type)
 
 	rptTime := time.Unix(hailMsg.Time, 0)
 
+	office, comments := nws.ParseOffice(hailMsg.GetRemarks())
 	irp = database.InsertReportParams{
 		RptType: database.ReportTypeHail,
 		ReportedTime: pgtype.Timestamptz{
@@ -206,13 +255,13 @@ func processHailMessage(logger *slog.Logger, msg []byte) (database.InsertReportP
 		},
 		EventLocation: nil,
 		Comments: pgtype.Text{
-			String: hailMsg.GetRemarks(),
+			String: comments,
 			Valid:  true,
 		},
 		Location: hailMsg.GetLocation(),
 		NwsOffice: pgtype.Text{
-			String: "",
-			Valid:  true,
+			String: office,
+			Valid:  office != "",
 		},
 	}
 
@@ -227,11 +276,12 @@ func processWindMessage(logger *slog.Logger, msg []byte) (database.InsertReportP
 	}
 	windMsg := report.WindMsg{}
 	if err := proto.Unmarshal(msg, &windMsg); err != nil {
-		return irp, err
+		return irp, fmt.Errorf("failed to unmarshal wind message: %w", err)
 	}
 	rptTime := time.Unix(windMsg.Time, 0)
 	logger.Debug("unmarshalled wind message", "type", windMsg.Type)
 
+	office, comments := nws.ParseOffice(windMsg.GetRemarks())
 	irp = database.InsertReportParams{
 		RptType: database.ReportTypeWind,
 		ReportedTime: pgtype.Timestamptz{
@@ -263,13 +313,13 @@ func processWindMessage(logger *slog.Logger, msg []byte) (database.InsertReportP
 		},
 		EventLocation: nil,
 		Comments: pgtype.Text{
-			String: windMsg.GetRemarks(),
+			String: comments,
 			Valid:  true,
 		},
 		Location: windMsg.GetLocation(),
 		NwsOffice: pgtype.Text{
-			String: "",
-			Valid:  true,
+			String: office,
+			Valid:  office != "",
 		},
 	}
 
@@ -283,11 +333,12 @@ func processTornadoMessage(logger *slog.Logger, msg []byte) (database.InsertRepo
 	}
 	tornadoMsg := report.TornadoMsg{}
 	if err := proto.Unmarshal(msg, &tornadoMsg); err != nil {
-		return irp, err
+		return irp, fmt.Errorf("failed to unmarshal tornado message: %w", err)
 	}
 	logger.Debug("unmarshalled tornado message", "type", tornadoMsg.Type)
 	rptTime := time.Unix(tornadoMsg.Time, 0)
 
+	office, comments := nws.ParseOffice(tornadoMsg.GetRemarks())
 	irp = database.InsertReportParams{
 		RptType: database.ReportTypeTornado,
 		ReportedTime: pgtype.Timestamptz{
@@ -319,13 +370,13 @@ func processTornadoMessage(logger *slog.Logger, msg []byte) (database.InsertRepo
 		},
 		EventLocation: nil,
 		Comments: pgtype.Text{
-			String: tornadoMsg.GetRemarks(),
+			String: comments,
 			Valid:  true,
 		},
 		Location: tornadoMsg.GetLocation(),
 		NwsOffice: pgtype.Text{
-			String: "",
-			Valid:  true,
+			String: office,
+			Valid:  office != "",
 		},
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"testing"
@@ -77,11 +78,11 @@ func Test_processTornadoMessage(t *testing.T) {
 				},
 				EventLocation: nil,
 				Comments: pgtype.Text{
-					String: "A tornado touched down in far eastern Jefferson county and moved through most of southern Madison county. EF0 tree damage was confirmed in Jefferson county with EF1 dam (TAE)",
+					String: "A tornado touched down in far eastern Jefferson county and moved through most of southern Madison county. EF0 tree damage was confirmed in Jefferson county with EF1 dam",
 					Valid:  true,
 				},
 				NwsOffice: pgtype.Text{
-					String: "",
+					String: "TAE",
 					Valid:  true,
 				},
 				Location: "Lamont",
@@ -91,7 +92,7 @@ func Test_processTornadoMessage(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := processTornadoMessage(tt.args.msg)
+			got, err := processTornadoMessage(slog.New(slog.NewTextHandler(io.Discard, nil)), tt.args.msg)
 			if err != nil {
 				err = errors.Unwrap(err)
 			}
@@ -100,6 +101,7 @@ func Test_processTornadoMessage(t *testing.T) {
 			// no the best hack, but that's how it's going for now
 			tt.want.ReportedTime.Time = time.Time{}
 			got.ReportedTime.Time = time.Time{}
+			got.CreatedAt = pgtype.Timestamptz{}
 			assert.Equal(t, tt.want, got)
 			assert.Equal(t, tt.wantErr, err)
 
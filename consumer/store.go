@@ -0,0 +1,92 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/events"
+	"github.com/jason-costello/weather/accesssvc/nws"
+	"github.com/jason-costello/weather/accesssvc/quality"
+)
+
+// errDuplicateReport is returned by storeReport for a report that is
+// already stored.
+var errDuplicateReport = errors.New("report already stored")
+
+const insertReport = `
+insert into reports (rpt_type, reported_time, created_at, var_col, dist_from_location, heading_from_location,
+                     county, state, latitude, longitude, event_location, comments, nws_office, location,
+                     wind_measurement)
+values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+on conflict do nothing
+returning id`
+
+const insertReportFlags = `
+insert into report_flags (report_id, flag, detail)
+select $1, f, d from unnest($2::text[], $3::text[]) as t(f, d)
+on conflict do nothing`
+
+// storeReport inserts the report and its flags together, so a report is
+// never visible without the flags raised for it, and places it in a storm
+// event.  It returns the report's id.
+func (c *Consumer) storeReport(ctx context.Context, irp database.InsertReportParams, flags []quality.Flag) (int64, error) {
+	var id int64
+	err := pgx.BeginFunc(ctx, c.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, insertReport,
+			irp.RptType, irp.ReportedTime, irp.CreatedAt, irp.VarCol, irp.DistFromLocation, irp.HeadingFromLocation,
+			irp.County, irp.State, irp.Latitude, irp.Longitude, irp.EventLocation, irp.Comments, irp.NwsOffice, irp.Location,
+			windMeasurement(irp),
+		).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errDuplicateReport
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert report: %w", err)
+		}
+		c.addToEvent(ctx, tx, id, irp)
+		if len(flags) == 0 {
+			return nil
+		}
+
+		names := make([]string, len(flags))
+		details := make([]string, len(flags))
+		for i, f := range flags {
+			names[i], details[i] = f.Name, f.Detail
+		}
+		if _, err := tx.Exec(ctx, insertReportFlags, id, names, details); err != nil {
+			return fmt.Errorf("failed to insert report flags: %w", err)
+		}
+		return nil
+	})
+	return id, err
+}
+
+// addToEvent places the report in a storm event.  It runs in a savepoint,
+// storing the report without an event beats dropping it.
+func (c *Consumer) addToEvent(ctx context.Context, tx pgx.Tx, id int64, irp database.InsertReportParams) {
+	rpt, ok := events.NewReport(id, irp)
+	if !ok {
+		return
+	}
+	err := pgx.BeginFunc(ctx, tx, func(tx pgx.Tx) error {
+		_, err := events.Add(ctx, tx, c.events, rpt)
+		return err
+	})
+	if err != nil {
+		c.logger.Error("failed to add report to an event", "id", id, "error", err)
+	}
+}
+
+// windMeasurement is how a wind report's speed was arrived at, it is null
+// for other report types.
+func windMeasurement(irp database.InsertReportParams) pgtype.Text {
+	if irp.RptType != database.ReportTypeWind {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: nws.WindMeasurement(irp.VarCol.Int32, irp.Comments.String), Valid: true}
+}
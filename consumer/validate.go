@@ -0,0 +1,162 @@
+package consumer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+)
+
+const (
+	// SPC's records start in 1950.
+	earliestReport = 1950
+	// reports are issued after the event, allow for clock skew upstream.
+	futureTolerance = time.Hour
+
+	minHailSize  = 25 // hundredths of an inch, smaller looks like inches
+	maxHailSize  = 800
+	maxWindSpeed = 200 // mph
+	maxEFScale   = 5
+)
+
+// states are the US states, DC and territories SPC issues reports for.
+var states = map[string]bool{
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true, "CT": true, "DE": true,
+	"FL": true, "GA": true, "HI": true, "ID": true, "IL": true, "IN": true, "IA": true, "KS": true,
+	"KY": true, "LA": true, "ME": true, "MD": true, "MA": true, "MI": true, "MN": true, "MS": true,
+	"MO": true, "MT": true, "NE": true, "NV": true, "NH": true, "NJ": true, "NM": true, "NY": true,
+	"NC": true, "ND": true, "OH": true, "OK": true, "OR": true, "PA": true, "RI": true, "SC": true,
+	"SD": true, "TN": true, "TX": true, "UT": true, "VT": true, "VA": true, "WA": true, "WV": true,
+	"WI": true, "WY": true, "DC": true, "PR": true, "VI": true, "GU": true, "AS": true, "MP": true,
+}
+
+// easternStates are the territories west of the date line, everywhere
+// else reports have a negative longitude.
+var easternStates = map[string]bool{"GU": true, "MP": true}
+
+// Problem is something wrong with a report.
+type Problem struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s %q: %s", p.Field, p.Value, p.Reason)
+}
+
+// RejectedError is returned for reports that can't be stored.
+type RejectedError struct {
+	RptType  database.ReportType
+	Problems []Problem
+}
+
+func (e *RejectedError) Error() string {
+	reasons := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		reasons = append(reasons, p.String())
+	}
+	return fmt.Sprintf("%s report rejected: %s", e.RptType, strings.Join(reasons, "; "))
+}
+
+// validateReport normalises the report and checks it is plausible.
+// Problems that make the report unusable are returned as a RejectedError,
+// ones that were corrected or only look odd are returned as suspect and
+// the report can still be stored.
+func validateReport(irp database.InsertReportParams, now time.Time) (database.InsertReportParams, []Problem, error) {
+	var rejected, suspect []Problem
+	reject := func(field, value, format string, a ...any) {
+		rejected = append(rejected, Problem{Field: field, Value: value, Reason: fmt.Sprintf(format, a...)})
+	}
+
+	reported := irp.ReportedTime.Time
+	switch {
+	case !irp.ReportedTime.Valid || reported.Unix() == 0:
+		reject("time", "", "is missing")
+	case reported.Year() < earliestReport:
+		reject("time", reported.UTC().Format(time.RFC3339), "is before %d", earliestReport)
+	case reported.After(now.Add(futureTolerance)):
+		reject("time", reported.UTC().Format(time.RFC3339), "is in the future")
+	}
+
+	state := strings.ToUpper(strings.TrimSpace(irp.State.String))
+	if !states[state] {
+		reject("state", irp.State.String, "is not a US state or territory")
+	}
+	irp.State.String, irp.State.Valid = state, state != ""
+
+	lat, err := parseCoordinate(irp.Latitude.String)
+	if err != nil {
+		reject("lat", irp.Latitude.String, "%s", err)
+	} else if lat < -90 || lat > 90 {
+		reject("lat", irp.Latitude.String, "must be between -90 and 90")
+	}
+	lon, err := parseCoordinate(irp.Longitude.String)
+	if err != nil {
+		reject("lon", irp.Longitude.String, "%s", err)
+	} else if lon < -180 || lon > 180 {
+		reject("lon", irp.Longitude.String, "must be between -180 and 180")
+	} else if lon > 0 && states[state] && !easternStates[state] {
+		// older SPC files give longitude as degrees west.
+		suspect = append(suspect, Problem{Field: "lon", Value: irp.Longitude.String, Reason: "was positive, assumed degrees west"})
+		lon = -lon
+	}
+	if len(rejected) == 0 {
+		irp.Latitude = formatCoordinate(lat)
+		irp.Longitude = formatCoordinate(lon)
+	}
+
+	magnitude := irp.VarCol.Int32
+	switch irp.RptType {
+	case database.ReportTypeHail:
+		// SPC sends 0 for UNK.
+		if magnitude == 0 {
+			irp.VarCol.Valid = false
+		} else if magnitude < minHailSize || magnitude > maxHailSize {
+			reject("size", strconv.Itoa(int(magnitude)), "must be between %d and %d hundredths of an inch", minHailSize, maxHailSize)
+		}
+	case database.ReportTypeWind:
+		if magnitude == 0 {
+			irp.VarCol.Valid = false
+		} else if magnitude < 0 || magnitude > maxWindSpeed {
+			reject("speed", strconv.Itoa(int(magnitude)), "must be between 1 and %d mph", maxWindSpeed)
+		}
+	case database.ReportTypeTornado:
+		// UNK is sent as 0 too, so it can't be told apart from EF0.
+		if magnitude < 0 || magnitude > maxEFScale {
+			reject("f_scale", strconv.Itoa(int(magnitude)), "must be between 0 and %d", maxEFScale)
+		}
+	default:
+		reject("type", string(irp.RptType), "is not hail, wind, or tornado")
+	}
+
+	if len(rejected) > 0 {
+		return irp, nil, &RejectedError{RptType: irp.RptType, Problems: rejected}
+	}
+	return irp, suspect, nil
+}
+
+// parseCoordinate reads a coordinate in decimal degrees, or hundredths of
+// a degree without a decimal point as some SPC files use.
+func parseCoordinate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("is missing")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("is not a number")
+	}
+	if !strings.Contains(s, ".") && math.Abs(v) >= 1000 {
+		v /= 100
+	}
+	return v, nil
+}
+
+func formatCoordinate(v float64) pgtype.Text {
+	return pgtype.Text{String: strconv.FormatFloat(v, 'f', -1, 64), Valid: true}
+}
@@ -0,0 +1,145 @@
+package consumer
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateReport(t *testing.T) {
+	now := time.Date(2024, 5, 21, 18, 0, 0, 0, time.UTC)
+	base := func(typ database.ReportType, magnitude int32) database.InsertReportParams {
+		return database.InsertReportParams{
+			RptType:      typ,
+			ReportedTime: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+			VarCol:       pgtype.Int4{Int32: magnitude, Valid: true},
+			State:        pgtype.Text{String: "FL", Valid: true},
+			Latitude:     pgtype.Text{String: "30.35", Valid: true},
+			Longitude:    pgtype.Text{String: "-83.83", Valid: true},
+		}
+	}
+	tests := []struct {
+		name         string
+		irp          func() database.InsertReportParams
+		want         func(database.InsertReportParams) database.InsertReportParams
+		wantSuspect  []string
+		wantRejected []string
+	}{
+		{
+			name: "should accept a valid hail report",
+			irp:  func() database.InsertReportParams { return base(database.ReportTypeHail, 175) },
+		},
+		{
+			name: "should normalise state and hundredths of a degree",
+			irp: func() database.InsertReportParams {
+				irp := base(database.ReportTypeTornado, 2)
+				irp.State.String = " fl"
+				irp.Latitude.String = "3035"
+				irp.Longitude.String = "-8383"
+				return irp
+			},
+			want: func(irp database.InsertReportParams) database.InsertReportParams {
+				irp.State.String = "FL"
+				irp.Latitude.String = "30.35"
+				irp.Longitude.String = "-83.83"
+				return irp
+			},
+		},
+		{
+			name: "should flip a positive longitude and flag it",
+			irp: func() database.InsertReportParams {
+				irp := base(database.ReportTypeHail, 100)
+				irp.Longitude.String = "83.83"
+				return irp
+			},
+			wantSuspect: []string{"lon"},
+		},
+		{
+			name: "should store an unknown wind speed as null",
+			irp:  func() database.InsertReportParams { return base(database.ReportTypeWind, 0) },
+			want: func(irp database.InsertReportParams) database.InsertReportParams {
+				irp.VarCol.Valid = false
+				return irp
+			},
+		},
+		{
+			name: "should reject a report without a time",
+			irp: func() database.InsertReportParams {
+				irp := base(database.ReportTypeHail, 100)
+				irp.ReportedTime.Time = time.Unix(0, 0)
+				return irp
+			},
+			wantRejected: []string{"time"},
+		},
+		{
+			name: "should reject a report from the future",
+			irp: func() database.InsertReportParams {
+				irp := base(database.ReportTypeHail, 100)
+				irp.ReportedTime.Time = now.Add(2 * time.Hour)
+				return irp
+			},
+			wantRejected: []string{"time"},
+		},
+		{
+			name: "should reject unknown states and bad coordinates",
+			irp: func() database.InsertReportParams {
+				irp := base(database.ReportTypeHail, 100)
+				irp.State.String = "ON"
+				irp.Latitude.String = "95"
+				irp.Longitude.String = ""
+				return irp
+			},
+			wantRejected: []string{"state", "lat", "lon"},
+		},
+		{
+			name:         "should reject hail sizes given in inches",
+			irp:          func() database.InsertReportParams { return base(database.ReportTypeHail, 2) },
+			wantRejected: []string{"size"},
+		},
+		{
+			name:         "should reject implausible wind speeds",
+			irp:          func() database.InsertReportParams { return base(database.ReportTypeWind, 350) },
+			wantRejected: []string{"speed"},
+		},
+		{
+			name:         "should reject an EF scale above 5",
+			irp:          func() database.InsertReportParams { return base(database.ReportTypeTornado, 6) },
+			wantRejected: []string{"f_scale"},
+		},
+	}
+	fields := func(problems []Problem) []string {
+		var f []string
+		for _, p := range problems {
+			f = append(f, p.Field)
+		}
+		return f
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, suspect, err := validateReport(tt.irp(), now)
+
+			var rejected *RejectedError
+			if errors.As(err, &rejected) {
+				assert.Equal(t, tt.wantRejected, fields(rejected.Problems))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Nil(t, tt.wantRejected, "report should have been rejected")
+			assert.Equal(t, tt.wantSuspect, fields(suspect))
+			if tt.want != nil {
+				assert.Equal(t, tt.want(tt.irp()), got)
+			}
+		})
+	}
+}
+
+func Test_processHailMessage_invalidPayload(t *testing.T) {
+	_, err := processHailMessage(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte{0xff, 0xff, 0xff})
+	assert.Error(t, err, "a payload that doesn't unmarshal must not become an empty report")
+}
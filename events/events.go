@@ -0,0 +1,179 @@
+// Package events groups reports of the same storm into events.  SPC lists
+// a hail core or a line of wind damage as many reports from the towns it
+// passed, an event is those reports together.
+package events
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// Thresholds decide which reports belong to the same event.  Two reports
+// of the same type are linked when they are within both thresholds of
+// each other, and an event is every report linked to another in it, so
+// an event can span more than the thresholds as a storm moves.
+type Thresholds struct {
+	RadiusMiles float64
+	Window      time.Duration
+}
+
+// DefaultThresholds links reports about as far apart as the towns one
+// storm is reported from.
+var DefaultThresholds = Thresholds{RadiusMiles: 10, Window: 30 * time.Minute}
+
+// near reports whether two reports are linked.
+func (t Thresholds) near(a, b Report) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	dt := a.Time.Sub(b.Time)
+	if dt < 0 {
+		dt = -dt
+	}
+	return dt <= t.Window && geo.DistanceMiles(a.Point, b.Point) <= t.RadiusMiles
+}
+
+// Report is what clustering needs of a stored report.
+type Report struct {
+	ID    int64
+	Type  database.ReportType
+	Time  time.Time
+	Point geo.Point
+	State string
+	// Magnitude is nil when it isn't known.
+	Magnitude *int32
+	// Remarks are the report's comments, tracks link reports by them.
+	Remarks string
+}
+
+// NewReport returns the report to cluster for a stored report, ok is
+// false when it has no time or coordinates and can't be placed in an
+// event.
+func NewReport(id int64, r database.InsertReportParams) (Report, bool) {
+	pt, ok := parsePoint(r.Latitude, r.Longitude)
+	if !ok || !r.ReportedTime.Valid {
+		return Report{}, false
+	}
+	rpt := Report{
+		ID:      id,
+		Type:    r.RptType,
+		Time:    r.ReportedTime.Time.UTC(),
+		Point:   pt,
+		State:   strings.ToUpper(r.State.String),
+		Remarks: r.Comments.String,
+	}
+	// a wind speed of 0 is how unknown speeds used to be stored.
+	if r.VarCol.Valid && (r.RptType != database.ReportTypeWind || r.VarCol.Int32 != 0) {
+		m := r.VarCol.Int32
+		rpt.Magnitude = &m
+	}
+	return rpt, true
+}
+
+// parsePoint reads the coordinates reports store as text, ok is false if
+// they aren't numbers.
+func parsePoint(latitude, longitude pgtype.Text) (geo.Point, bool) {
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(latitude.String), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(longitude.String), 64)
+	return geo.Point{Lon: lon, Lat: lat}, latErr == nil && lonErr == nil
+}
+
+// Event is a summary of the reports of one event.
+type Event struct {
+	Type database.ReportType
+	// Start and End are the times of the first and last reports.
+	Start time.Time
+	End   time.Time
+	// Centroid is the mean position of the reports.
+	Centroid geo.Point
+	// MaxMagnitude is nil when no report has a magnitude.
+	MaxMagnitude *int32
+	// States are the states reported from, sorted.
+	States    []string
+	ReportIDs []int64
+}
+
+// Summarize returns the event made of the reports, there must be at
+// least one.
+func Summarize(rpts []Report) Event {
+	e := Event{Type: rpts[0].Type, Start: rpts[0].Time, End: rpts[0].Time, States: []string{}}
+	for _, r := range rpts {
+		if r.Time.Before(e.Start) {
+			e.Start = r.Time
+		}
+		if r.Time.After(e.End) {
+			e.End = r.Time
+		}
+		e.Centroid.Lon += r.Point.Lon
+		e.Centroid.Lat += r.Point.Lat
+		if r.Magnitude != nil && (e.MaxMagnitude == nil || *r.Magnitude > *e.MaxMagnitude) {
+			m := *r.Magnitude
+			e.MaxMagnitude = &m
+		}
+		if r.State != "" && !slices.Contains(e.States, r.State) {
+			e.States = append(e.States, r.State)
+		}
+		e.ReportIDs = append(e.ReportIDs, r.ID)
+	}
+	e.Centroid.Lon /= float64(len(rpts))
+	e.Centroid.Lat /= float64(len(rpts))
+	sort.Strings(e.States)
+	slices.Sort(e.ReportIDs)
+	return e
+}
+
+// Cluster groups the reports into events, each event's reports are in
+// time order and the events are ordered by their first report.  Adding
+// the reports one at a time with Add gives the same events.
+func Cluster(rpts []Report, t Thresholds) [][]Report {
+	return cluster(rpts, t.Window, t.near)
+}
+
+// cluster groups reports linked to one another, directly or through
+// other reports.  Reports more than window apart are never linked.
+func cluster(rpts []Report, window time.Duration, linked func(a, b Report) bool) [][]Report {
+	sorted := slices.Clone(rpts)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	parent := make([]int, len(sorted))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range sorted {
+		// only reports within the window after i can link to it.
+		for j := i + 1; j < len(sorted) && sorted[j].Time.Sub(sorted[i].Time) <= window; j++ {
+			if linked(sorted[i], sorted[j]) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	var clusters [][]Report
+	byRoot := map[int]int{}
+	for i, r := range sorted {
+		root := find(i)
+		k, ok := byRoot[root]
+		if !ok {
+			k = len(clusters)
+			byRoot[root] = k
+			clusters = append(clusters, nil)
+		}
+		clusters[k] = append(clusters[k], r)
+	}
+	return clusters
+}
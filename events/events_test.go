@@ -0,0 +1,119 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+var start = time.Date(2024, 5, 6, 22, 0, 0, 0, time.UTC)
+
+func hail(id int64, minutes int, lon, lat float64, size int32) Report {
+	return Report{
+		ID:        id,
+		Type:      database.ReportTypeHail,
+		Time:      start.Add(time.Duration(minutes) * time.Minute),
+		Point:     geo.Point{Lon: lon, Lat: lat},
+		State:     "OK",
+		Magnitude: &size,
+	}
+}
+
+func ids(events [][]Report) [][]int64 {
+	var got [][]int64
+	for _, e := range events {
+		var eventIDs []int64
+		for _, r := range e {
+			eventIDs = append(eventIDs, r.ID)
+		}
+		got = append(got, eventIDs)
+	}
+	return got
+}
+
+func TestCluster(t *testing.T) {
+	wind := hail(9, 5, -97.45, 35.22, 60)
+	wind.Type = database.ReportTypeWind
+	tests := []struct {
+		name string
+		rpts []Report
+		want [][]int64
+	}{
+		{
+			name: "should group reports from neighbouring towns",
+			rpts: []Report{hail(1, 0, -97.44, 35.22, 100), hail(2, 10, -97.40, 35.30, 175)},
+			want: [][]int64{{1, 2}},
+		},
+		{
+			name: "should keep reports too far apart in separate events",
+			rpts: []Report{hail(1, 0, -97.44, 35.22, 100), hail(2, 10, -96.00, 36.15, 175)},
+			want: [][]int64{{1}, {2}},
+		},
+		{
+			name: "should keep reports too far apart in time in separate events",
+			rpts: []Report{hail(1, 0, -97.44, 35.22, 100), hail(2, 45, -97.44, 35.22, 175)},
+			want: [][]int64{{1}, {2}},
+		},
+		{
+			name: "should keep report types apart",
+			rpts: []Report{hail(1, 0, -97.44, 35.22, 100), wind},
+			want: [][]int64{{1}, {9}},
+		},
+		{
+			name: "should follow a storm further than the thresholds",
+			rpts: []Report{
+				hail(3, 50, -97.24, 35.22, 100),
+				hail(1, 0, -97.44, 35.22, 100),
+				hail(2, 25, -97.34, 35.22, 175),
+			},
+			want: [][]int64{{1, 2, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ids(Cluster(tt.rpts, DefaultThresholds)))
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	unknown := hail(3, 20, -97.30, 35.30, 0)
+	unknown.Magnitude, unknown.State = nil, "TX"
+	e := Summarize([]Report{hail(2, 10, -97.40, 35.20, 175), hail(1, 0, -97.50, 35.10, 100), unknown})
+
+	assert.Equal(t, database.ReportTypeHail, e.Type)
+	assert.Equal(t, start, e.Start)
+	assert.Equal(t, start.Add(20*time.Minute), e.End)
+	assert.InDelta(t, -97.40, e.Centroid.Lon, 1e-9)
+	assert.InDelta(t, 35.20, e.Centroid.Lat, 1e-9)
+	require.NotNil(t, e.MaxMagnitude)
+	assert.Equal(t, int32(175), *e.MaxMagnitude)
+	assert.Equal(t, []string{"OK", "TX"}, e.States)
+	assert.Equal(t, []int64{1, 2, 3}, e.ReportIDs)
+}
+
+func TestNewReport(t *testing.T) {
+	irp := database.InsertReportParams{
+		RptType:      database.ReportTypeWind,
+		ReportedTime: pgtype.Timestamptz{Time: start, Valid: true},
+		VarCol:       pgtype.Int4{Int32: 0, Valid: true},
+		State:        pgtype.Text{String: "ok", Valid: true},
+		Latitude:     pgtype.Text{String: " 35.22", Valid: true},
+		Longitude:    pgtype.Text{String: "-97.44", Valid: true},
+	}
+	r, ok := NewReport(7, irp)
+	require.True(t, ok)
+	assert.Equal(t, geo.Point{Lon: -97.44, Lat: 35.22}, r.Point)
+	assert.Equal(t, "OK", r.State)
+	assert.Nil(t, r.Magnitude, "a wind speed of 0 is unknown")
+
+	irp.Latitude = pgtype.Text{}
+	_, ok = NewReport(7, irp)
+	assert.False(t, ok)
+}
@@ -0,0 +1,316 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// DB is a connection that can start transactions, a *pgxpool.Pool or a
+// *pgx.Conn.
+type DB interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// lockID is the advisory lock held while events change, so two reports
+// stored at once can't each start an event the other belongs in.
+const lockID = 0x73746f726d // "storm"
+
+// notRejected leaves out the reports rejected in review, they aren't part
+// of any event.
+const notRejected = `not exists (select 1 from report_reviews rv where rv.report_id = r.id and rv.status = 'rejected')`
+
+const (
+	lockEvents = `select pg_advisory_xact_lock($1)`
+
+	// nearbyEventReports is the clustered reports of a type within the
+	// window of a report, the distance is checked in go.
+	nearbyEventReports = `
+select r.latitude, r.longitude, er.event_id
+from storm_event_reports er
+         join reports r on r.id = er.report_id
+where r.rpt_type = $1
+  and r.reported_time between $2 and $3
+  and ` + notRejected
+
+	insertEvent = `
+insert into storm_events (rpt_type, started_at, ended_at, latitude, longitude, max_magnitude, reports, states)
+values ($1, $2, $3, $4, $5, $6, $7, $8)
+returning id`
+
+	updateEvent = `
+update storm_events
+set started_at    = $2,
+    ended_at      = $3,
+    latitude      = $4,
+    longitude     = $5,
+    max_magnitude = $6,
+    reports       = $7,
+    states        = $8,
+    updated_at    = now()
+where id = $1`
+
+	mergeEvents = `update storm_event_reports set event_id = $1 where event_id = any($2)`
+
+	deleteEvents = `delete from storm_events where id = any($1)`
+
+	insertEventReport = `insert into storm_event_reports (report_id, event_id) values ($1, $2)`
+
+	removeEventReport = `delete from storm_event_reports where report_id = $1 returning event_id`
+
+	moveEventReports = `update storm_event_reports set event_id = $1 where report_id = any($2)`
+
+	eventReports = `
+select r.id, r.rpt_type, r.reported_time, r.latitude, r.longitude, r.state, r.var_col
+from storm_event_reports er
+         join reports r on r.id = er.report_id
+where er.event_id = $1
+  and ` + notRejected
+
+	// unclusteredReport is a report that isn't in an event.
+	unclusteredReport = `
+select r.id, r.rpt_type, r.reported_time, r.latitude, r.longitude, r.state, r.var_col
+from reports r
+where r.id = $1
+  and not exists (select 1 from storm_event_reports er where er.report_id = r.id)
+  and ` + notRejected
+
+	// allReports is every report that hasn't been rejected in review.
+	allReports = `
+select r.id, r.rpt_type, r.reported_time, r.latitude, r.longitude, r.state, r.var_col
+from reports r
+where ` + notRejected
+
+	clearEvents = `truncate storm_event_reports, storm_events`
+
+	nextEventIDs = `select nextval(pg_get_serial_sequence('storm_events', 'id')) from generate_series(1, $1)`
+)
+
+// Add places a newly stored report in an event, joining the events it
+// is near, merging them if there are several, or starting a new one.  It
+// returns the report's event.  db should be the transaction that stored
+// the report so the two are committed together.
+func Add(ctx context.Context, db database.DBTX, t Thresholds, r Report) (int64, error) {
+	if _, err := db.Exec(ctx, lockEvents, lockID); err != nil {
+		return 0, fmt.Errorf("failed to lock events: %w", err)
+	}
+	ids, err := nearbyEvents(ctx, db, t, r)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		id, err := createEvent(ctx, db, Summarize([]Report{r}))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := db.Exec(ctx, insertEventReport, r.ID, id); err != nil {
+			return 0, fmt.Errorf("failed to add report to event: %w", err)
+		}
+		return id, nil
+	}
+
+	// the oldest event takes in the rest.
+	slices.Sort(ids)
+	id, merged := ids[0], ids[1:]
+	if len(merged) > 0 {
+		if _, err := db.Exec(ctx, mergeEvents, id, merged); err != nil {
+			return 0, fmt.Errorf("failed to merge events: %w", err)
+		}
+		if _, err := db.Exec(ctx, deleteEvents, merged); err != nil {
+			return 0, fmt.Errorf("failed to delete merged events: %w", err)
+		}
+	}
+	if _, err := db.Exec(ctx, insertEventReport, r.ID, id); err != nil {
+		return 0, fmt.Errorf("failed to add report to event: %w", err)
+	}
+	members, err := queryReports(ctx, db, eventReports, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event reports: %w", err)
+	}
+	if err := saveEvent(ctx, db, id, Summarize(members)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Remove takes a report rejected in review out of its event.  The rest of
+// the event is clustered again since the report may have been what linked
+// them, the first cluster keeps the event and the others become events of
+// their own.  An event left without reports is deleted.  db should be the
+// transaction that stored the review.
+func Remove(ctx context.Context, db database.DBTX, t Thresholds, reportID int64) error {
+	if _, err := db.Exec(ctx, lockEvents, lockID); err != nil {
+		return fmt.Errorf("failed to lock events: %w", err)
+	}
+	var id int64
+	err := db.QueryRow(ctx, removeEventReport, reportID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove report from event: %w", err)
+	}
+	members, err := queryReports(ctx, db, eventReports, id)
+	if err != nil {
+		return fmt.Errorf("failed to read event reports: %w", err)
+	}
+	if len(members) == 0 {
+		if _, err := db.Exec(ctx, deleteEvents, []int64{id}); err != nil {
+			return fmt.Errorf("failed to delete event: %w", err)
+		}
+		return nil
+	}
+
+	clusters := Cluster(members, t)
+	if err := saveEvent(ctx, db, id, Summarize(clusters[0])); err != nil {
+		return err
+	}
+	for _, c := range clusters[1:] {
+		e := Summarize(c)
+		split, err := createEvent(ctx, db, e)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(ctx, moveEventReports, split, e.ReportIDs); err != nil {
+			return fmt.Errorf("failed to move reports to split event: %w", err)
+		}
+	}
+	return nil
+}
+
+// Restore places a report back in an event once its rejection has been
+// reversed.  It does nothing if the report is already in an event.
+func Restore(ctx context.Context, db database.DBTX, t Thresholds, reportID int64) error {
+	rpts, err := queryReports(ctx, db, unclusteredReport, reportID)
+	if err != nil {
+		return fmt.Errorf("failed to read report: %w", err)
+	}
+	if len(rpts) == 0 {
+		return nil
+	}
+	_, err = Add(ctx, db, t, rpts[0])
+	return err
+}
+
+// saveEvent updates event id to the summary of its reports.
+func saveEvent(ctx context.Context, db database.DBTX, id int64, e Event) error {
+	if _, err := db.Exec(ctx, updateEvent, id, e.Start, e.End, e.Centroid.Lat, e.Centroid.Lon, e.MaxMagnitude, len(e.ReportIDs), e.States); err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	return nil
+}
+
+// nearbyEvents returns the events with a report linked to r.
+func nearbyEvents(ctx context.Context, db database.DBTX, t Thresholds, r Report) ([]int64, error) {
+	rows, err := db.Query(ctx, nearbyEventReports, r.Type, r.Time.Add(-t.Window), r.Time.Add(t.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up nearby events: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var lat, lon pgtype.Text
+		var id int64
+		if err := rows.Scan(&lat, &lon, &id); err != nil {
+			return nil, err
+		}
+		// the query has already matched the type and time.
+		pt, ok := parsePoint(lat, lon)
+		if ok && geo.DistanceMiles(r.Point, pt) <= t.RadiusMiles && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+func createEvent(ctx context.Context, db database.DBTX, e Event) (int64, error) {
+	var id int64
+	err := db.QueryRow(ctx, insertEvent, e.Type, e.Start, e.End, e.Centroid.Lat, e.Centroid.Lon, e.MaxMagnitude, len(e.ReportIDs), e.States).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert event: %w", err)
+	}
+	return id, nil
+}
+
+// Rebuild clusters every stored report again, for reports stored before
+// events were kept or after the thresholds change.  It returns the number
+// of events and reports clustered.
+func Rebuild(ctx context.Context, db DB, t Thresholds) (events, reports int, err error) {
+	err = pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, lockEvents, lockID); err != nil {
+			return fmt.Errorf("failed to lock events: %w", err)
+		}
+		rpts, err := queryReports(ctx, tx, allReports)
+		if err != nil {
+			return fmt.Errorf("failed to read reports: %w", err)
+		}
+		clusters := Cluster(rpts, t)
+		if _, err := tx.Exec(ctx, clearEvents); err != nil {
+			return fmt.Errorf("failed to clear events: %w", err)
+		}
+		if len(clusters) == 0 {
+			return nil
+		}
+
+		rows, err := tx.Query(ctx, nextEventIDs, len(clusters))
+		if err != nil {
+			return fmt.Errorf("failed to allocate event ids: %w", err)
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return fmt.Errorf("failed to allocate event ids: %w", err)
+		}
+		var eventRows, memberRows [][]any
+		for i, c := range clusters {
+			e := Summarize(c)
+			eventRows = append(eventRows, []any{ids[i], string(e.Type), e.Start, e.End, e.Centroid.Lat, e.Centroid.Lon, e.MaxMagnitude, len(e.ReportIDs), e.States})
+			for _, r := range c {
+				memberRows = append(memberRows, []any{r.ID, ids[i]})
+			}
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"storm_events"},
+			[]string{"id", "rpt_type", "started_at", "ended_at", "latitude", "longitude", "max_magnitude", "reports", "states"},
+			pgx.CopyFromRows(eventRows)); err != nil {
+			return fmt.Errorf("failed to insert events: %w", err)
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"storm_event_reports"},
+			[]string{"report_id", "event_id"},
+			pgx.CopyFromRows(memberRows)); err != nil {
+			return fmt.Errorf("failed to insert event reports: %w", err)
+		}
+		events, reports = len(clusters), len(memberRows)
+		return nil
+	})
+	return events, reports, err
+}
+
+// queryReports runs a query of reports in the column order of
+// eventReports.
+func queryReports(ctx context.Context, db database.DBTX, query string, args ...any) ([]Report, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rpts []Report
+	for rows.Next() {
+		var id int64
+		var r database.InsertReportParams
+		if err := rows.Scan(&id, &r.RptType, &r.ReportedTime, &r.Latitude, &r.Longitude, &r.State, &r.VarCol); err != nil {
+			return nil, err
+		}
+		if rpt, ok := NewReport(id, r); ok {
+			rpts = append(rpts, rpt)
+		}
+	}
+	return rpts, rows.Err()
+}
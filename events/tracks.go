@@ -0,0 +1,105 @@
+package events
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// TrackThresholds decide which tornado reports are of the same tornado.
+// Reports are linked when they are in sequence, within RadiusMiles and
+// Window of each other, or when they have the same remarks and are
+// within the wider RemarksRadiusMiles and RemarksWindow, as offices often
+// repeat a long track's remarks on each report along it.
+type TrackThresholds struct {
+	RadiusMiles        float64
+	Window             time.Duration
+	RemarksRadiusMiles float64
+	RemarksWindow      time.Duration
+}
+
+// DefaultTrackThresholds allows for a fast moving tornado reported every
+// few towns, and a long track reported along its length.
+var DefaultTrackThresholds = TrackThresholds{
+	RadiusMiles:        15,
+	Window:             30 * time.Minute,
+	RemarksRadiusMiles: 60,
+	RemarksWindow:      3 * time.Hour,
+}
+
+// linked reports whether two tornado reports are of the same tornado.
+func (t TrackThresholds) linked(a, b Report) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	dt := a.Time.Sub(b.Time)
+	if dt < 0 {
+		dt = -dt
+	}
+	d := geo.DistanceMiles(a.Point, b.Point)
+	if dt <= t.Window && d <= t.RadiusMiles {
+		return true
+	}
+	ra := normalizeRemarks(a.Remarks)
+	return ra != "" && ra == normalizeRemarks(b.Remarks) && dt <= t.RemarksWindow && d <= t.RemarksRadiusMiles
+}
+
+// normalizeRemarks lowercases remarks and collapses their spacing so
+// copies of the same remarks compare equal.
+func normalizeRemarks(remarks string) string {
+	return strings.Join(strings.Fields(strings.ToLower(remarks)), " ")
+}
+
+// Track is the path of one tornado reconstructed from its reports.
+type Track struct {
+	// Reports are in time order.
+	Reports []Report
+	// Path is the reports' positions in time order, with a report at the
+	// same place as the one before it left out.
+	Path  []geo.Point
+	Start time.Time
+	End   time.Time
+	// LengthMiles is the length of the path.
+	LengthMiles float64
+	// Bearing is the direction from the start of the path to its end, in
+	// degrees clockwise from north.
+	Bearing float64
+	// MaxRating is the highest EF rating reported, nil when none is.
+	MaxRating *int32
+}
+
+// Tracks links the tornado reports into tracks, ordered by their first
+// report.  Reports of a tornado seen in one place can't show a path, so
+// tracks of a single place are left out.
+func Tracks(rpts []Report, t TrackThresholds) []Track {
+	var tracks []Track
+	for _, c := range cluster(rpts, max(t.Window, t.RemarksWindow), t.linked) {
+		track := newTrack(c)
+		if len(track.Path) >= 2 {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// newTrack builds the track of reports that are in time order.
+func newTrack(rpts []Report) Track {
+	t := Track{Reports: rpts, Start: rpts[0].Time, End: rpts[len(rpts)-1].Time}
+	for _, r := range rpts {
+		if n := len(t.Path); n == 0 || t.Path[n-1] != r.Point {
+			if n > 0 {
+				t.LengthMiles += geo.DistanceMiles(t.Path[n-1], r.Point)
+			}
+			t.Path = append(t.Path, r.Point)
+		}
+		if r.Magnitude != nil && (t.MaxRating == nil || *r.Magnitude > *t.MaxRating) {
+			m := *r.Magnitude
+			t.MaxRating = &m
+		}
+	}
+	if len(t.Path) >= 2 {
+		t.Bearing = geo.BearingDegrees(t.Path[0], t.Path[len(t.Path)-1])
+	}
+	return t
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+func tornado(id int64, minutes int, lon, lat float64, rating int32, remarks string) Report {
+	return Report{
+		ID:        id,
+		Type:      database.ReportTypeTornado,
+		Time:      start.Add(time.Duration(minutes) * time.Minute),
+		Point:     geo.Point{Lon: lon, Lat: lat},
+		Magnitude: &rating,
+		Remarks:   remarks,
+	}
+}
+
+func TestTracks(t *testing.T) {
+	const longTrack = "Long track tornado crossed I-35 north of Moore."
+	tests := []struct {
+		name string
+		rpts []Report
+		want [][]int64
+	}{
+		{
+			name: "should link reports in sequence in time order",
+			rpts: []Report{
+				tornado(2, 10, -97.30, 35.25, 2, ""),
+				tornado(1, 0, -97.50, 35.20, 1, ""),
+				tornado(3, 20, -97.10, 35.30, 3, ""),
+			},
+			want: [][]int64{{1, 2, 3}},
+		},
+		{
+			name: "should link reports with the same remarks further apart",
+			rpts: []Report{
+				tornado(1, 0, -97.50, 35.20, 1, longTrack),
+				tornado(2, 60, -96.90, 35.40, 4, "  long track tornado crossed I-35 NORTH of Moore."),
+			},
+			want: [][]int64{{1, 2}},
+		},
+		{
+			name: "should not link different remarks as far apart",
+			rpts: []Report{
+				tornado(1, 0, -97.50, 35.20, 1, longTrack),
+				tornado(2, 60, -96.90, 35.40, 4, "Barn destroyed."),
+				tornado(3, 70, -96.80, 35.40, 0, "Trees down."),
+			},
+			want: [][]int64{{2, 3}},
+		},
+		{
+			name: "should leave out a tornado reported from one place",
+			rpts: []Report{
+				tornado(1, 0, -97.50, 35.20, 1, ""),
+				tornado(2, 5, -97.50, 35.20, 1, ""),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int64
+			for _, track := range Tracks(tt.rpts, DefaultTrackThresholds) {
+				var trackIDs []int64
+				for _, r := range track.Reports {
+					trackIDs = append(trackIDs, r.ID)
+				}
+				got = append(got, trackIDs)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTracks_path(t *testing.T) {
+	tracks := Tracks([]Report{
+		tornado(1, 0, -97.50, 35.20, 1, ""),
+		tornado(2, 5, -97.50, 35.20, 2, ""),
+		tornado(3, 15, -97.40, 35.20, 3, ""),
+		tornado(4, 25, -97.40, 35.30, 2, ""),
+	}, DefaultTrackThresholds)
+	require.Len(t, tracks, 1)
+	track := tracks[0]
+
+	assert.Equal(t, []geo.Point{{Lon: -97.50, Lat: 35.20}, {Lon: -97.40, Lat: 35.20}, {Lon: -97.40, Lat: 35.30}}, track.Path)
+	// 0.1 degrees east then 0.1 north.
+	assert.InDelta(t, 5.65+6.91, track.LengthMiles, 0.05)
+	assert.InDelta(t, 39, track.Bearing, 1)
+	assert.Equal(t, int32(3), *track.MaxRating)
+	assert.Equal(t, start, track.Start)
+	assert.Equal(t, start.Add(25*time.Minute), track.End)
+}
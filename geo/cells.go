@@ -0,0 +1,97 @@
+package geo
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// MaxMercatorLat is the latitude where web mercator tiles end.
+const MaxMercatorLat = 85.0511287798
+
+// MarshalJSON writes the point as a GeoJSON position, [lon, lat].
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{p.Lon, p.Lat})
+}
+
+// TileXY returns the web mercator tile at zoom z containing pt, the same
+// numbering slippy maps use.
+func TileXY(pt Point, z int) (x, y int) {
+	fx, fy := TilePoint(pt, z)
+	last := int(1)<<z - 1
+	return min(max(int(math.Floor(fx)), 0), last), min(max(int(math.Floor(fy)), 0), last)
+}
+
+// TilePoint returns pt in tile units at zoom z, the integer part is the
+// tile containing pt and the fraction is its position within the tile.
+func TilePoint(pt Point, z int) (x, y float64) {
+	n := float64(int(1) << z)
+	lat := math.Max(-MaxMercatorLat, math.Min(MaxMercatorLat, pt.Lat)) * math.Pi / 180
+	return (pt.Lon + 180) / 360 * n, (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * n
+}
+
+// TileCorner is the inverse of TilePoint.
+func TileCorner(z int, x, y float64) Point {
+	n := float64(int(1) << z)
+	return Point{
+		Lon: x/n*360 - 180,
+		Lat: math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi,
+	}
+}
+
+// TileBounds returns the south west and north east corners of a tile.
+func TileBounds(z, x, y int) (sw, ne Point) {
+	return TileCorner(z, float64(x), float64(y+1)), TileCorner(z, float64(x+1), float64(y))
+}
+
+// Box returns the rectangle between two corners as a polygon.
+func Box(sw, ne Point) Polygon {
+	return Polygon{Ring{sw, {Lon: ne.Lon, Lat: sw.Lat}, ne, {Lon: sw.Lon, Lat: ne.Lat}, sw}}
+}
+
+// GridCell returns the cell of a size degree grid containing pt.
+func GridCell(pt Point, size float64) (x, y int) {
+	return int(math.Floor(pt.Lon / size)), int(math.Floor(pt.Lat / size))
+}
+
+// GridPolygon returns the outline of a grid cell.
+func GridPolygon(x, y int, size float64) Polygon {
+	return Box(Point{Lon: float64(x) * size, Lat: float64(y) * size}, Point{Lon: float64(x+1) * size, Lat: float64(y+1) * size})
+}
+
+// HexCell returns the axial coordinates of the pointy topped hexagon
+// containing pt, in a tiling of hexagons size degrees from center to
+// corner.  Degrees aren't square, so the hexagons stretch north to south
+// away from the equator, which is fine for binning.
+func HexCell(pt Point, size float64) (q, r int) {
+	fq := (math.Sqrt(3)/3*pt.Lon - pt.Lat/3) / size
+	fr := 2.0 / 3 * pt.Lat / size
+	fs := -fq - fr
+	rq, rr, rs := math.Round(fq), math.Round(fr), math.Round(fs)
+	dq, dr, ds := math.Abs(rq-fq), math.Abs(rr-fr), math.Abs(rs-fs)
+	switch {
+	case dq > dr && dq > ds:
+		rq = -rr - rs
+	case dr > ds:
+		rr = -rq - rs
+	}
+	return int(rq), int(rr)
+}
+
+// HexCenter returns the center of a hexagon.
+func HexCenter(q, r int, size float64) Point {
+	return Point{
+		Lon: size * math.Sqrt(3) * (float64(q) + float64(r)/2),
+		Lat: size * 1.5 * float64(r),
+	}
+}
+
+// HexPolygon returns the outline of a hexagon.
+func HexPolygon(q, r int, size float64) Polygon {
+	c := HexCenter(q, r, size)
+	ring := make(Ring, 0, 7)
+	for i := 0; i < 6; i++ {
+		angle := math.Pi / 180 * float64(60*i-30)
+		ring = append(ring, Point{Lon: c.Lon + size*math.Cos(angle), Lat: c.Lat + size*math.Sin(angle)})
+	}
+	return Polygon{append(ring, ring[0])}
+}
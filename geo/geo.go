@@ -0,0 +1,103 @@
+package geo
+
+import "math"
+
+// Contains reports whether the point is inside the ring, points exactly
+// on an edge may land either way.
+func (r Ring) Contains(pt Point) bool {
+	in := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > pt.Lat) != (b.Lat > pt.Lat) &&
+			pt.Lon < (b.Lon-a.Lon)*(pt.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Contains reports whether the point is inside the outer ring and not in
+// any of the holes.
+func (p Polygon) Contains(pt Point) bool {
+	if len(p) == 0 || !p[0].Contains(pt) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.Contains(pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether the point is inside any of the polygons.
+func (m MultiPolygon) Contains(pt Point) bool {
+	for _, p := range m {
+		if p.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the south west and north east corners of the area.
+func (m MultiPolygon) Bounds() (min, max Point) {
+	first := true
+	for _, p := range m {
+		for _, r := range p {
+			for _, pt := range r {
+				if first {
+					min, max, first = pt, pt, false
+					continue
+				}
+				min.Lon, min.Lat = minf(min.Lon, pt.Lon), minf(min.Lat, pt.Lat)
+				max.Lon, max.Lat = maxf(max.Lon, pt.Lon), maxf(max.Lat, pt.Lat)
+			}
+		}
+	}
+	return min, max
+}
+
+func minf(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxf(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// earthRadiusMiles is the mean radius of the earth.
+const earthRadiusMiles = 3958.8
+
+// DistanceMiles returns the great circle distance between two points.
+func DistanceMiles(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Sqrt(h))
+}
+
+// BearingDegrees returns the initial great circle bearing from a to b in
+// degrees clockwise from north, 0 to 360.
+func BearingDegrees(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+var compassPoints = [16]string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// CompassPoint returns the nearest of the 16 compass points to a bearing,
+// e.g. ENE for 70.
+func CompassPoint(bearing float64) string {
+	return compassPoints[int(math.Round(math.Mod(bearing, 360)/22.5))%16]
+}
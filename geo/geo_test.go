@@ -0,0 +1,139 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiPolygon_Contains(t *testing.T) {
+	// a square around central Oklahoma with a hole over Oklahoma City.
+	area, err := ParseGeoJSON([]byte(`{"type": "Feature", "properties": {}, "geometry": {
+		"type": "Polygon",
+		"coordinates": [
+			[[-99, 34], [-96, 34], [-96, 37], [-99, 37], [-99, 34]],
+			[[-97.8, 35.2], [-97.2, 35.2], [-97.2, 35.7], [-97.8, 35.7], [-97.8, 35.2]]
+		]}}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		pt   Point
+		want bool
+	}{
+		{name: "should contain Norman", pt: Point{Lon: -97.44, Lat: 35.12}, want: true},
+		{name: "should not contain a point in the hole", pt: Point{Lon: -97.5, Lat: 35.47}, want: false},
+		{name: "should not contain Dallas", pt: Point{Lon: -96.8, Lat: 32.78}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, area.Contains(tt.pt))
+		})
+	}
+
+	min, max := area.Bounds()
+	assert.Equal(t, Point{Lon: -99, Lat: 34}, min)
+	assert.Equal(t, Point{Lon: -96, Lat: 37}, max)
+}
+
+func TestParseGeoJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		geojson string
+		wantErr bool
+	}{
+		{name: "should parse a multipolygon", geojson: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`},
+		{name: "should parse a feature collection", geojson: `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}]}`},
+		{name: "should reject a point", geojson: `{"type":"Point","coordinates":[0,0]}`, wantErr: true},
+		{name: "should reject an open ring", geojson: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1]]]}`, wantErr: true},
+		{name: "should reject out of range positions", geojson: `{"type":"Polygon","coordinates":[[[0,0],[181,0],[1,1],[0,0]]]}`, wantErr: true},
+		{name: "should reject an empty collection", geojson: `{"type":"FeatureCollection","features":[]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGeoJSON([]byte(tt.geojson))
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestDistanceMiles(t *testing.T) {
+	okc := Point{Lon: -97.52, Lat: 35.47}
+	tulsa := Point{Lon: -95.99, Lat: 36.15}
+	assert.InDelta(t, 98, DistanceMiles(okc, tulsa), 2)
+	assert.Zero(t, DistanceMiles(okc, okc))
+}
+
+func TestTileXY(t *testing.T) {
+	tests := []struct {
+		name  string
+		pt    Point
+		z     int
+		wantX int
+		wantY int
+	}{
+		{name: "should put everything in the one tile at zoom 0", pt: Point{Lon: -97.5, Lat: 35.5}, z: 0, wantX: 0, wantY: 0},
+		{name: "should number tiles from the north west", pt: Point{Lon: -97.5, Lat: 35.5}, z: 4, wantX: 3, wantY: 6},
+		{name: "should clamp the poles", pt: Point{Lon: 179.9, Lat: -89}, z: 2, wantX: 3, wantY: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := TileXY(tt.pt, tt.z)
+			assert.Equal(t, tt.wantX, x)
+			assert.Equal(t, tt.wantY, y)
+
+			sw, ne := TileBounds(tt.z, x, y)
+			assert.True(t, Box(sw, ne).Contains(Point{Lon: tt.pt.Lon, Lat: max(-MaxMercatorLat+1e-9, tt.pt.Lat)}))
+		})
+	}
+}
+
+func TestHexCell(t *testing.T) {
+	for _, pt := range []Point{{Lon: -97.52, Lat: 35.47}, {Lon: -80.19, Lat: 25.76}, {Lon: 0.01, Lat: -0.01}, {Lon: -122.4, Lat: 47.6}} {
+		q, r := HexCell(pt, 0.5)
+		assert.True(t, HexPolygon(q, r, 0.5).Contains(pt), "%v not in hex %d,%d", pt, q, r)
+		assert.LessOrEqual(t, DistanceMiles(pt, HexCenter(q, r, 0.5)), DistanceMiles(Point{}, Point{Lon: 0.5}))
+	}
+}
+
+func TestHull(t *testing.T) {
+	hull := Hull([]Point{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0}})
+	assert.Equal(t, Ring{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}}, hull)
+	assert.InDelta(t, 4*milesPerDegreeLat*milesPerDegreeLat*math.Cos(0.8*math.Pi/180), hull.AreaSquareMiles(), 1e-6)
+}
+
+func TestBufferedHull(t *testing.T) {
+	norman := Point{Lon: -97.44, Lat: 35.22}
+	circle := BufferedHull([]Point{norman}, 5)
+	require.Len(t, circle, 1)
+	assert.Equal(t, circle[0][0], circle[0][len(circle[0])-1], "the ring should be closed")
+	assert.True(t, circle.Contains(norman))
+	// a 24 sided polygon has a little less area than its circle.
+	assert.InDelta(t, math.Pi*25, circle[0].AreaSquareMiles(), 2)
+
+	band := BufferedHull([]Point{norman, {Lon: -97.0, Lat: 35.22}}, 2)
+	assert.True(t, band.Contains(Point{Lon: -97.2, Lat: 35.24}))
+	assert.False(t, band.Contains(Point{Lon: -97.2, Lat: 35.3}), "points 5 miles off the line should be outside")
+}
+
+func TestCompassPoint(t *testing.T) {
+	norman := Point{Lon: -97.44, Lat: 35.22}
+	tests := []struct {
+		name string
+		to   Point
+		want string
+	}{
+		{name: "should point north", to: Point{Lon: -97.44, Lat: 36}, want: "N"},
+		{name: "should point east", to: Point{Lon: -97, Lat: 35.22}, want: "E"},
+		{name: "should point south west", to: Point{Lon: -98, Lat: 34.76}, want: "SW"},
+		{name: "should point north north west", to: Point{Lon: -97.6, Lat: 35.6}, want: "NNW"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CompassPoint(BearingDegrees(norman, tt.to)))
+		})
+	}
+	assert.Equal(t, "N", CompassPoint(359))
+}
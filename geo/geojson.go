@@ -0,0 +1,136 @@
+// Package geo has the small amount of geometry the service needs to match
+// reports against areas, it works directly on lon/lat degrees.
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Point is a position in degrees.
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// Ring is a closed line, the last point joins back to the first.
+type Ring []Point
+
+// Polygon is an outer ring followed by any holes.
+type Polygon []Ring
+
+// MultiPolygon is a set of polygons, a point inside any of them is inside
+// the area.
+type MultiPolygon []Polygon
+
+type geoJSON struct {
+	Type        string            `json:"type"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Geometry    *geoJSON          `json:"geometry"`
+	Geometries  []geoJSON         `json:"geometries"`
+	Features    []json.RawMessage `json:"features"`
+}
+
+// ParseGeoJSON reads the area described by a GeoJSON Polygon or
+// MultiPolygon, or a Feature, FeatureCollection or GeometryCollection
+// made of them.
+func ParseGeoJSON(data []byte) (MultiPolygon, error) {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("invalid geojson: %w", err)
+	}
+	area, err := g.area()
+	if err != nil {
+		return nil, err
+	}
+	if len(area) == 0 {
+		return nil, errors.New("geojson has no polygons")
+	}
+	return area, nil
+}
+
+func (g geoJSON) area() (MultiPolygon, error) {
+	switch g.Type {
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		p, err := toPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		return MultiPolygon{p}, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+		var m MultiPolygon
+		for _, pc := range coords {
+			p, err := toPolygon(pc)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, p)
+		}
+		return m, nil
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, errors.New("feature has no geometry")
+		}
+		return g.Geometry.area()
+	case "FeatureCollection":
+		var m MultiPolygon
+		for _, raw := range g.Features {
+			var f geoJSON
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return nil, fmt.Errorf("invalid feature: %w", err)
+			}
+			fm, err := f.area()
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, fm...)
+		}
+		return m, nil
+	case "GeometryCollection":
+		var m MultiPolygon
+		for _, geom := range g.Geometries {
+			gm, err := geom.area()
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, gm...)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported geojson type %q, areas must be polygons", g.Type)
+	}
+}
+
+func toPolygon(coords [][][]float64) (Polygon, error) {
+	if len(coords) == 0 {
+		return nil, errors.New("polygon has no rings")
+	}
+	p := make(Polygon, 0, len(coords))
+	for _, rc := range coords {
+		// geojson rings repeat the first position at the end.
+		if len(rc) < 4 {
+			return nil, errors.New("polygon rings need at least four positions")
+		}
+		r := make(Ring, 0, len(rc))
+		for _, pos := range rc {
+			if len(pos) < 2 {
+				return nil, errors.New("positions need a longitude and latitude")
+			}
+			if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+				return nil, fmt.Errorf("position %v is out of range", pos)
+			}
+			r = append(r, Point{Lon: pos[0], Lat: pos[1]})
+		}
+		p = append(p, r)
+	}
+	return p, nil
+}
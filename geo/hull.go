@@ -0,0 +1,85 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// milesPerDegreeLat is the length of a degree of latitude.
+const milesPerDegreeLat = earthRadiusMiles * math.Pi / 180
+
+// bufferSegments is how many sides the circle around each point has.
+const bufferSegments = 24
+
+// Hull returns the convex hull of the points as a closed counter-clockwise
+// ring, treating lon/lat as planar which is fine over the size of a storm.
+// Fewer than three distinct points give a degenerate ring.
+func Hull(pts []Point) Ring {
+	sorted := make([]Point, len(pts))
+	copy(sorted, pts)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Lon != sorted[j].Lon {
+			return sorted[i].Lon < sorted[j].Lon
+		}
+		return sorted[i].Lat < sorted[j].Lat
+	})
+	if len(sorted) < 2 {
+		return Ring(append(sorted, sorted...))
+	}
+
+	// Andrew's monotone chain, the lower hull then the upper.
+	cross := func(o, a, b Point) float64 {
+		return (a.Lon-o.Lon)*(b.Lat-o.Lat) - (a.Lat-o.Lat)*(b.Lon-o.Lon)
+	}
+	var hull Ring
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// the last point is the first again, closing the ring.
+	return hull
+}
+
+// BufferedHull returns the area within miles of the convex hull of the
+// points: a circle for one point, a band along a line of points, or the
+// hull with rounded corners.
+func BufferedHull(pts []Point, miles float64) Polygon {
+	var circles []Point
+	for _, p := range pts {
+		dLat := miles / milesPerDegreeLat
+		dLon := dLat / math.Max(math.Cos(p.Lat*math.Pi/180), 0.01)
+		for i := 0; i < bufferSegments; i++ {
+			angle := 2 * math.Pi * float64(i) / bufferSegments
+			circles = append(circles, Point{Lon: p.Lon + dLon*math.Cos(angle), Lat: p.Lat + dLat*math.Sin(angle)})
+		}
+	}
+	return Polygon{Hull(circles)}
+}
+
+// AreaSquareMiles returns the area inside the ring, approximating the
+// earth as flat at the ring's mean latitude.
+func (r Ring) AreaSquareMiles() float64 {
+	if len(r) < 3 {
+		return 0
+	}
+	var lat float64
+	for _, p := range r {
+		lat += p.Lat
+	}
+	scale := math.Cos(lat / float64(len(r)) * math.Pi / 180)
+	var sum float64
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		sum += (r[j].Lon + r[i].Lon) * (r[j].Lat - r[i].Lat)
+	}
+	return math.Abs(sum) / 2 * scale * milesPerDegreeLat * milesPerDegreeLat
+}
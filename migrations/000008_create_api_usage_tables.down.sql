@@ -0,0 +1,2 @@
+drop table if exists public.api_usage_hourly;
+drop table if exists public.api_usage;
@@ -0,0 +1,37 @@
+-- one row per api request, written in batches by the usage recorder.
+-- key_id is a truncated sha256 of the api key so the keys themselves
+-- never land in the database.
+create table if not exists public.api_usage
+(
+    id            bigint generated always as identity
+        constraint api_usage_id_pkey
+            primary key,
+    key_id        varchar(16)              not null,
+    requested_at  timestamp with time zone not null,
+    method        varchar(10)              not null,
+    route         varchar(255)             not null,
+    filters       text,
+    status        integer                  not null,
+    rows_returned integer                  not null default 0,
+    bytes_sent    bigint                   not null default 0,
+    latency_ms    double precision         not null default 0
+);
+
+create index if not exists api_usage_key_time_idx
+    on public.api_usage (key_id, requested_at);
+
+-- hourly rollups of api_usage, recomputed for the touched hours
+-- every time the recorder flushes.
+create table if not exists public.api_usage_hourly
+(
+    key_id           varchar(16)              not null,
+    hour             timestamp with time zone not null,
+    route            varchar(255)             not null,
+    requests         bigint                   not null,
+    rows_returned    bigint                   not null,
+    bytes_sent       bigint                   not null,
+    total_latency_ms double precision         not null,
+    max_latency_ms   double precision         not null,
+    constraint api_usage_hourly_pkey
+        primary key (key_id, hour, route)
+);
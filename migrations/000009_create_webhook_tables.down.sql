@@ -0,0 +1,2 @@
+drop table if exists public.webhook_deliveries;
+drop table if exists public.webhooks;
@@ -0,0 +1,46 @@
+-- endpoints customers registered to be told about reports inside an area.
+-- area is the geojson the customer sent, types and the minimums narrow
+-- which reports inside it are delivered.
+create table if not exists public.webhooks
+(
+    id                   bigint generated always as identity
+        constraint webhooks_id_pkey
+            primary key,
+    key_id               varchar(16)              not null,
+    url                  text                     not null,
+    secret               varchar(64)              not null,
+    area                 jsonb                    not null,
+    types                varchar(10)[]            not null default '{}',
+    min_hail_size        integer,
+    min_wind_speed       integer,
+    enabled              boolean                  not null default true,
+    consecutive_failures integer                  not null default 0,
+    disabled_at          timestamp with time zone,
+    created_at           timestamp with time zone not null default now()
+);
+
+create index if not exists webhooks_key_id_idx
+    on public.webhooks (key_id);
+
+-- one row per delivery attempt.
+create table if not exists public.webhook_deliveries
+(
+    id           bigint generated always as identity
+        constraint webhook_deliveries_id_pkey
+            primary key,
+    webhook_id   bigint                   not null
+        constraint webhook_deliveries_webhook_id_fkey
+            references public.webhooks
+            on delete cascade,
+    delivery_id  varchar(32)              not null,
+    event_id     bigint                   not null,
+    attempt      integer                  not null,
+    status_code  integer,
+    error        text,
+    latency_ms   double precision         not null default 0,
+    succeeded    boolean                  not null,
+    attempted_at timestamp with time zone not null default now()
+);
+
+create index if not exists webhook_deliveries_webhook_time_idx
+    on public.webhook_deliveries (webhook_id, attempted_at);
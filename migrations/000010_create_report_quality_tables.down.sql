@@ -0,0 +1,6 @@
+drop table if exists public.report_reviews;
+drop table if exists public.report_flags;
+alter table public.reports
+    drop constraint if exists reports_id_key;
+alter table public.reports
+    drop column if exists id;
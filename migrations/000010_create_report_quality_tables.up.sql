@@ -0,0 +1,41 @@
+-- reports are keyed on six columns, give them a single id that flags,
+-- reviews and api clients can refer to.  existing rows are numbered when
+-- the column is added.
+alter table public.reports
+    add column if not exists id bigint generated always as identity;
+
+alter table public.reports
+    add constraint reports_id_key
+        unique (id);
+
+-- quality flags raised at ingestion.
+create table if not exists public.report_flags
+(
+    report_id  bigint                   not null
+        constraint report_flags_report_id_fkey
+            references public.reports (id)
+            on delete cascade,
+    flag       varchar(40)              not null,
+    detail     text,
+    created_at timestamp with time zone not null default now(),
+    constraint report_flags_pkey
+        primary key (report_id, flag)
+);
+
+-- the outcome of an admin reviewing a report, rejected reports are left
+-- out of the api.
+create table if not exists public.report_reviews
+(
+    report_id   bigint                   not null
+        constraint report_reviews_pkey
+            primary key
+        constraint report_reviews_report_id_fkey
+            references public.reports (id)
+            on delete cascade,
+    status      varchar(10)              not null
+        constraint report_reviews_status_check
+            check (status in ('accepted', 'rejected')),
+    key_id      varchar(16),
+    note        text,
+    reviewed_at timestamp with time zone not null default now()
+);
@@ -0,0 +1,3 @@
+drop index if exists public.reports_wind_measurement_idx;
+alter table public.reports
+    drop column if exists wind_measurement;
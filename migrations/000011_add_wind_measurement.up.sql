@@ -0,0 +1,28 @@
+-- how a wind report's speed was arrived at, null for other report types.
+alter table public.reports
+    add column if not exists wind_measurement varchar(9)
+        constraint reports_wind_measurement_check
+            check (wind_measurement in ('measured', 'estimated', 'unknown'));
+
+-- a speed of 0 was stored for UNK, it isn't a real speed.
+update public.reports
+set var_col = null
+where rpt_type = 'wind'
+  and var_col = 0;
+
+-- classify what is already stored the same way the consumer does, see
+-- nws.WindMeasurement.
+update public.reports
+set wind_measurement = case
+                           when var_col is null then 'unknown'
+                           when comments ~* '\y(estimated|eg|not measured)\y|\yest\.?\s*(gust|wind|\d)'
+                               then 'estimated'
+                           when comments ~* '\y(measured|meas|mg|asos|awos|mesonet|anemometer|weatherflow)\y'
+                               then 'measured'
+                           else 'estimated'
+    end
+where rpt_type = 'wind';
+
+create index if not exists reports_wind_measurement_idx
+    on public.reports (wind_measurement)
+    where wind_measurement is not null;
@@ -0,0 +1,24 @@
+-- the climatological baseline of report counts per state and type,
+-- recomputed from the stored history by the api.  period_key is the
+-- month for monthly rows and month * 100 + day of the convective day for
+-- daily rows, e.g. 509 for May 9th.
+create table if not exists public.report_climatology
+(
+    period      varchar(5)               not null
+        constraint report_climatology_period_check
+            check (period in ('day', 'month')),
+    state       varchar(2)               not null,
+    rpt_type    varchar(10)              not null,
+    period_key  integer                  not null,
+    years       integer                  not null,
+    mean        double precision         not null,
+    stddev      double precision         not null,
+    p10         double precision         not null,
+    p25         double precision         not null,
+    p50         double precision         not null,
+    p75         double precision         not null,
+    p90         double precision         not null,
+    computed_at timestamp with time zone not null,
+    constraint report_climatology_pkey
+        primary key (period, state, rpt_type, period_key)
+);
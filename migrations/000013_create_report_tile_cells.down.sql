@@ -0,0 +1 @@
+drop table if exists public.report_tile_cells;
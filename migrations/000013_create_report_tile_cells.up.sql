@@ -0,0 +1,18 @@
+-- report counts pre-aggregated onto map tile cells.  The cells at zoom z
+-- are the web mercator tiles at zoom z + 4, so each tile is a 16x16 grid
+-- of cells.  Rows are per convective day so tiles can be filtered by date.
+create table if not exists public.report_tile_cells
+(
+    zoom          smallint    not null,
+    cell_x        integer     not null,
+    cell_y        integer     not null,
+    rpt_type      varchar(10) not null,
+    day           date        not null,
+    reports       integer     not null,
+    max_magnitude integer,
+    constraint report_tile_cells_pkey
+        primary key (zoom, cell_x, cell_y, rpt_type, day)
+);
+
+create index if not exists report_tile_cells_day_idx
+    on public.report_tile_cells (day);
@@ -0,0 +1,28 @@
+-- bulk exports built in the background.  id is random so the status url
+-- can't be guessed, query is the report filters as they were sent and
+-- is parsed again when the job runs.
+create table if not exists public.export_jobs
+(
+    id          varchar(32)              not null
+        constraint export_jobs_id_pkey
+            primary key,
+    key_id      varchar(16)              not null,
+    format      varchar(16)              not null,
+    query       text                     not null,
+    status      varchar(10)              not null default 'pending'
+        constraint export_jobs_status_check
+            check (status in ('pending', 'running', 'complete', 'failed')),
+    rows        bigint,
+    size_bytes  bigint,
+    error       text,
+    created_at  timestamp with time zone not null default now(),
+    started_at  timestamp with time zone,
+    finished_at timestamp with time zone,
+    expires_at  timestamp with time zone
+);
+
+create index if not exists export_jobs_status_idx
+    on public.export_jobs (status, created_at);
+
+create index if not exists export_jobs_key_id_idx
+    on public.export_jobs (key_id, created_at);
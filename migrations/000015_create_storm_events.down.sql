@@ -0,0 +1,2 @@
+drop table if exists public.storm_event_reports;
+drop table if exists public.storm_events;
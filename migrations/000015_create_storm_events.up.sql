@@ -0,0 +1,38 @@
+-- reports of the same storm grouped into events.  The consumer places
+-- each report in an event as it stores it, ids are by default so a
+-- rebuild can allocate them up front.
+create table if not exists public.storm_events
+(
+    id            bigint generated by default as identity
+        constraint storm_events_pkey
+            primary key,
+    rpt_type      varchar(10)              not null,
+    started_at    timestamp with time zone not null,
+    ended_at      timestamp with time zone not null,
+    latitude      double precision         not null,
+    longitude     double precision         not null,
+    max_magnitude integer,
+    reports       integer                  not null,
+    states        varchar(2)[]             not null,
+    updated_at    timestamp with time zone not null default now()
+);
+
+create index if not exists storm_events_started_at_idx
+    on public.storm_events (started_at);
+
+create table if not exists public.storm_event_reports
+(
+    report_id bigint not null
+        constraint storm_event_reports_pkey
+            primary key
+        constraint storm_event_reports_report_id_fkey
+            references public.reports (id)
+            on delete cascade,
+    event_id  bigint not null
+        constraint storm_event_reports_event_id_fkey
+            references public.storm_events (id)
+            on delete cascade
+);
+
+create index if not exists storm_event_reports_event_id_idx
+    on public.storm_event_reports (event_id);
@@ -0,0 +1,10 @@
+-- put the office back on the end of the remarks, where SPC sends it, for
+-- the reports the up migration split it off.  Reports stored by the
+-- consumer since never had it in their remarks.
+update public.reports r
+set comments = case when coalesce(r.comments, '') = '' then '' else r.comments || ' ' end || '(' || r.nws_office || ')'
+from public.nws_office_backfill b
+where b.report_id = r.id
+  and r.nws_office ~ '^[A-Z]{3}$';
+
+drop table if exists public.nws_office_backfill;
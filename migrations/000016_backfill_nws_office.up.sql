@@ -0,0 +1,38 @@
+-- split the office SPC appends to the remarks, e.g. "trees down. (TAE)",
+-- off the reports stored before the consumer did, the same way
+-- nws.ParseOffice does.  Remarks ending in anything but a known office,
+-- see nws.Offices, are left as they are.  The reports changed are kept in
+-- nws_office_backfill so the down migration only touches those.
+create table if not exists public.nws_office_backfill
+(
+    report_id bigint not null
+        constraint nws_office_backfill_pkey
+            primary key
+);
+
+with parsed as (select id, upper(substring(comments from '\(([A-Za-z]{3})\)\s*$')) as office
+                from public.reports
+                where comments ~ '\([A-Za-z]{3}\)\s*$'),
+     updated as (
+         update public.reports r
+             set nws_office = p.office,
+                 comments = regexp_replace(r.comments, '\s*\([A-Za-z]{3}\)\s*$', '')
+             from parsed p
+             where p.id = r.id
+               and p.office = any (array [
+                   'AKQ', 'ALY', 'BGM', 'BOX', 'BTV', 'BUF', 'CAE', 'CAR', 'CHS', 'CLE', 'CTP', 'GSP', 'GYX', 'ILM',
+                   'ILN', 'LWX', 'MHX', 'OKX', 'PBZ', 'PHI', 'RAH', 'RLX', 'RNK', 'ABQ', 'AMA', 'BMX', 'BRO', 'CRP',
+                   'EPZ', 'EWX', 'FFC', 'FWD', 'HGX', 'HUN', 'JAN', 'JAX', 'KEY', 'LCH', 'LIX', 'LUB', 'LZK', 'MAF',
+                   'MEG', 'MFL', 'MLB', 'MOB', 'MRX', 'OHX', 'OUN', 'SHV', 'SJT', 'SJU', 'TAE', 'TBW', 'TSA', 'ABR',
+                   'APX', 'ARX', 'BIS', 'BOU', 'CYS', 'DDC', 'DLH', 'DMX', 'DTX', 'DVN', 'EAX', 'FGF', 'FSD', 'GID',
+                   'GJT', 'GLD', 'GRB', 'GRR', 'ICT', 'ILX', 'IND', 'IWX', 'JKL', 'LBF', 'LMK', 'LOT', 'LSX', 'MKX',
+                   'MPX', 'MQT', 'OAX', 'PAH', 'PUB', 'RIW', 'SGF', 'TOP', 'UNR', 'BOI', 'BYZ', 'EKA', 'FGZ', 'GGW',
+                   'HNX', 'LKN', 'LOX', 'MFR', 'MSO', 'MTR', 'OTX', 'PDT', 'PIH', 'PQR', 'PSR', 'REV', 'SEW', 'SGX',
+                   'SLC', 'STO', 'TFX', 'TWC', 'VEF', 'AFC', 'AFG', 'AJK', 'GUM', 'HFO', 'PPG'
+                 ])
+             returning r.id)
+insert
+into public.nws_office_backfill (report_id)
+select id
+from updated
+on conflict do nothing;
@@ -0,0 +1,3 @@
+alter table public.export_jobs
+    drop column if exists heartbeat_at,
+    drop column if exists claimed_by;
@@ -0,0 +1,12 @@
+-- a running export is leased to the instance building it, which renews
+-- heartbeat_at while it works.  Another instance only takes the job over
+-- once the lease has expired.
+alter table public.export_jobs
+    add column if not exists claimed_by   varchar(64),
+    add column if not exists heartbeat_at timestamp with time zone;
+
+-- jobs running before leases were kept have no heartbeat, start them over.
+update public.export_jobs
+set status     = 'pending',
+    started_at = null
+where status = 'running';
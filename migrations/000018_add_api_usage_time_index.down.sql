@@ -0,0 +1 @@
+drop index if exists public.api_usage_requested_at_idx;
@@ -0,0 +1,4 @@
+-- the rollup after every flush reads api_usage from the oldest record of
+-- the batch, for every key.
+create index if not exists api_usage_requested_at_idx
+    on public.api_usage (requested_at);
@@ -0,0 +1,189 @@
+// Package mvt encodes Mapbox Vector Tiles, version 2.1 of the spec at
+// https://github.com/mapbox/vector-tile-spec.  Only point features are
+// supported, which is all the report tiles need.
+package mvt
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DefaultExtent is the number of units across a tile most renderers
+// expect.
+const DefaultExtent = 4096
+
+// Field numbers from vector_tile.proto.
+const (
+	tileLayers = 3
+
+	layerVersion  = 15
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+
+	featureID       = 1
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueDouble = 3
+	valueInt    = 4
+	valueBool   = 7
+
+	geomTypePoint = 1
+	cmdMoveTo     = 1
+)
+
+// Tile is a vector tile.
+type Tile struct {
+	Layers []Layer
+}
+
+// Layer is a named set of features in tile coordinates, 0 to Extent from
+// the top left corner of the tile.  Features may lie outside the tile so
+// symbols near an edge aren't clipped.
+type Layer struct {
+	Name     string
+	Extent   uint32
+	Features []Feature
+}
+
+// Feature is a point with properties.  Properties are strings, bools,
+// integers or floats, anything else is an error.
+type Feature struct {
+	// ID is optional, zero leaves it out.
+	ID         uint64
+	X, Y       int
+	Properties map[string]any
+}
+
+// Marshal encodes the tile.
+func (t Tile) Marshal() ([]byte, error) {
+	var b []byte
+	for _, l := range t.Layers {
+		layer, err := l.marshal()
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", l.Name, err)
+		}
+		b = protowire.AppendTag(b, tileLayers, protowire.BytesType)
+		b = protowire.AppendBytes(b, layer)
+	}
+	return b, nil
+}
+
+func (l Layer) marshal() ([]byte, error) {
+	extent := l.Extent
+	if extent == 0 {
+		extent = DefaultExtent
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, layerVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, 2)
+	b = protowire.AppendTag(b, layerName, protowire.BytesType)
+	b = protowire.AppendString(b, l.Name)
+	b = protowire.AppendTag(b, layerExtent, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(extent))
+
+	// keys and values are shared by every feature in the layer, features
+	// refer to them by index.
+	var keys []string
+	var values []any
+	keyIndex := map[string]uint64{}
+	valueIndex := map[any]uint64{}
+	for _, f := range l.Features {
+		var tags []byte
+		for k, v := range f.Properties {
+			v, err := normalize(v)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", k, err)
+			}
+			ki, ok := keyIndex[k]
+			if !ok {
+				ki = uint64(len(keys))
+				keyIndex[k] = ki
+				keys = append(keys, k)
+			}
+			vi, ok := valueIndex[v]
+			if !ok {
+				vi = uint64(len(values))
+				valueIndex[v] = vi
+				values = append(values, v)
+			}
+			tags = protowire.AppendVarint(tags, ki)
+			tags = protowire.AppendVarint(tags, vi)
+		}
+
+		var geom []byte
+		geom = protowire.AppendVarint(geom, cmdMoveTo|1<<3)
+		geom = protowire.AppendVarint(geom, protowire.EncodeZigZag(int64(f.X)))
+		geom = protowire.AppendVarint(geom, protowire.EncodeZigZag(int64(f.Y)))
+
+		var fb []byte
+		if f.ID != 0 {
+			fb = protowire.AppendTag(fb, featureID, protowire.VarintType)
+			fb = protowire.AppendVarint(fb, f.ID)
+		}
+		if len(tags) > 0 {
+			fb = protowire.AppendTag(fb, featureTags, protowire.BytesType)
+			fb = protowire.AppendBytes(fb, tags)
+		}
+		fb = protowire.AppendTag(fb, featureType, protowire.VarintType)
+		fb = protowire.AppendVarint(fb, geomTypePoint)
+		fb = protowire.AppendTag(fb, featureGeometry, protowire.BytesType)
+		fb = protowire.AppendBytes(fb, geom)
+
+		b = protowire.AppendTag(b, layerFeatures, protowire.BytesType)
+		b = protowire.AppendBytes(b, fb)
+	}
+
+	for _, k := range keys {
+		b = protowire.AppendTag(b, layerKeys, protowire.BytesType)
+		b = protowire.AppendString(b, k)
+	}
+	for _, v := range values {
+		b = protowire.AppendTag(b, layerValues, protowire.BytesType)
+		b = protowire.AppendBytes(b, marshalValue(v))
+	}
+	return b, nil
+}
+
+// normalize turns a property value into a string, bool, int64 or float64
+// so equal values share an entry in the layer's values.
+func normalize(v any) (any, error) {
+	switch v := v.(type) {
+	case string, bool, int64, float64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", v)
+}
+
+func marshalValue(v any) []byte {
+	var b []byte
+	switch v := v.(type) {
+	case string:
+		b = protowire.AppendTag(b, valueString, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case bool:
+		b = protowire.AppendTag(b, valueBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case int64:
+		b = protowire.AppendTag(b, valueInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case float64:
+		b = protowire.AppendTag(b, valueDouble, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	}
+	return b
+}
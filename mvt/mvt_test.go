@@ -0,0 +1,95 @@
+package mvt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fields decodes one level of a message into its fields, bytes fields as
+// []byte and everything else as uint64.
+func fields(t *testing.T, b []byte) map[protowire.Number][]any {
+	t.Helper()
+	out := map[protowire.Number][]any{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			out[num] = append(out[num], v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			out[num] = append(out[num], v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			require.GreaterOrEqual(t, n, 0)
+			out[num] = append(out[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return out
+}
+
+func packed(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+	var out []uint64
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		require.GreaterOrEqual(t, n, 0)
+		out = append(out, v)
+		b = b[n:]
+	}
+	return out
+}
+
+func TestTile_Marshal(t *testing.T) {
+	tile := Tile{Layers: []Layer{{
+		Name: "reports",
+		Features: []Feature{
+			{ID: 7, X: 10, Y: -3, Properties: map[string]any{"type": "hail"}},
+			{ID: 8, X: 4100, Y: 20, Properties: map[string]any{"type": "hail"}},
+		},
+	}}}
+	b, err := tile.Marshal()
+	require.NoError(t, err)
+
+	layers := fields(t, b)[tileLayers]
+	require.Len(t, layers, 1)
+	layer := fields(t, layers[0].([]byte))
+	assert.Equal(t, []any{uint64(2)}, layer[layerVersion])
+	assert.Equal(t, []any{[]byte("reports")}, layer[layerName])
+	assert.Equal(t, []any{uint64(DefaultExtent)}, layer[layerExtent])
+	assert.Equal(t, []any{[]byte("type")}, layer[layerKeys])
+	// both features share the one value.
+	require.Len(t, layer[layerValues], 1)
+	assert.Equal(t, "hail", string(fields(t, layer[layerValues][0].([]byte))[valueString][0].([]byte)))
+
+	require.Len(t, layer[layerFeatures], 2)
+	f := fields(t, layer[layerFeatures][0].([]byte))
+	assert.Equal(t, []any{uint64(7)}, f[featureID])
+	assert.Equal(t, []any{uint64(geomTypePoint)}, f[featureType])
+	assert.Equal(t, []uint64{0, 0}, packed(t, f[featureTags][0].([]byte)))
+	geom := packed(t, f[featureGeometry][0].([]byte))
+	require.Len(t, geom, 3)
+	assert.Equal(t, uint64(9), geom[0])
+	assert.Equal(t, int64(10), protowire.DecodeZigZag(geom[1]))
+	assert.Equal(t, int64(-3), protowire.DecodeZigZag(geom[2]))
+}
+
+func TestTile_MarshalRejectsUnsupportedValues(t *testing.T) {
+	_, err := Tile{Layers: []Layer{{
+		Name:     "reports",
+		Features: []Feature{{Properties: map[string]any{"flags": []string{"a"}}}},
+	}}}.Marshal()
+	assert.Error(t, err)
+}
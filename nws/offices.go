@@ -0,0 +1,181 @@
+// Package nws holds reference data about the National Weather Service
+// needed to make sense of the storm reports it issues.
+package nws
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Office is a Weather Forecast Office.
+type Office struct {
+	ID    string
+	City  string
+	State string
+}
+
+// Offices are the Weather Forecast Offices that issue local storm
+// reports, keyed by their three letter id.
+var Offices = map[string]Office{}
+
+func init() {
+	for _, o := range offices {
+		Offices[o.ID] = o
+	}
+}
+
+// IsOffice reports whether id is a known office.
+func IsOffice(id string) bool {
+	_, ok := Offices[id]
+	return ok
+}
+
+// officeSuffix matches the office SPC appends to every report's remarks,
+// e.g. "... trees down. (TAE)".
+var officeSuffix = regexp.MustCompile(`\s*\(([A-Za-z]{3})\)\s*$`)
+
+// ParseOffice splits the issuing office off the end of a report's
+// remarks.  If the remarks don't end with a known office they are
+// returned unchanged with an empty office.
+func ParseOffice(remarks string) (office, comments string) {
+	m := officeSuffix.FindStringSubmatchIndex(remarks)
+	if m == nil {
+		return "", remarks
+	}
+	id := strings.ToUpper(remarks[m[2]:m[3]])
+	if !IsOffice(id) {
+		return "", remarks
+	}
+	return id, remarks[:m[0]]
+}
+
+var offices = []Office{
+	// Eastern Region
+	{ID: "AKQ", City: "Wakefield", State: "VA"},
+	{ID: "ALY", City: "Albany", State: "NY"},
+	{ID: "BGM", City: "Binghamton", State: "NY"},
+	{ID: "BOX", City: "Boston", State: "MA"},
+	{ID: "BTV", City: "Burlington", State: "VT"},
+	{ID: "BUF", City: "Buffalo", State: "NY"},
+	{ID: "CAE", City: "Columbia", State: "SC"},
+	{ID: "CAR", City: "Caribou", State: "ME"},
+	{ID: "CHS", City: "Charleston", State: "SC"},
+	{ID: "CLE", City: "Cleveland", State: "OH"},
+	{ID: "CTP", City: "State College", State: "PA"},
+	{ID: "GSP", City: "Greenville-Spartanburg", State: "SC"},
+	{ID: "GYX", City: "Gray", State: "ME"},
+	{ID: "ILM", City: "Wilmington", State: "NC"},
+	{ID: "ILN", City: "Wilmington", State: "OH"},
+	{ID: "LWX", City: "Sterling", State: "VA"},
+	{ID: "MHX", City: "Newport/Morehead City", State: "NC"},
+	{ID: "OKX", City: "Upton", State: "NY"},
+	{ID: "PBZ", City: "Pittsburgh", State: "PA"},
+	{ID: "PHI", City: "Mount Holly", State: "NJ"},
+	{ID: "RAH", City: "Raleigh", State: "NC"},
+	{ID: "RLX", City: "Charleston", State: "WV"},
+	{ID: "RNK", City: "Blacksburg", State: "VA"},
+	// Southern Region
+	{ID: "ABQ", City: "Albuquerque", State: "NM"},
+	{ID: "AMA", City: "Amarillo", State: "TX"},
+	{ID: "BMX", City: "Birmingham", State: "AL"},
+	{ID: "BRO", City: "Brownsville", State: "TX"},
+	{ID: "CRP", City: "Corpus Christi", State: "TX"},
+	{ID: "EPZ", City: "El Paso", State: "TX"},
+	{ID: "EWX", City: "Austin/San Antonio", State: "TX"},
+	{ID: "FFC", City: "Peachtree City", State: "GA"},
+	{ID: "FWD", City: "Fort Worth", State: "TX"},
+	{ID: "HGX", City: "Houston", State: "TX"},
+	{ID: "HUN", City: "Huntsville", State: "AL"},
+	{ID: "JAN", City: "Jackson", State: "MS"},
+	{ID: "JAX", City: "Jacksonville", State: "FL"},
+	{ID: "KEY", City: "Key West", State: "FL"},
+	{ID: "LCH", City: "Lake Charles", State: "LA"},
+	{ID: "LIX", City: "New Orleans", State: "LA"},
+	{ID: "LUB", City: "Lubbock", State: "TX"},
+	{ID: "LZK", City: "Little Rock", State: "AR"},
+	{ID: "MAF", City: "Midland", State: "TX"},
+	{ID: "MEG", City: "Memphis", State: "TN"},
+	{ID: "MFL", City: "Miami", State: "FL"},
+	{ID: "MLB", City: "Melbourne", State: "FL"},
+	{ID: "MOB", City: "Mobile", State: "AL"},
+	{ID: "MRX", City: "Morristown", State: "TN"},
+	{ID: "OHX", City: "Nashville", State: "TN"},
+	{ID: "OUN", City: "Norman", State: "OK"},
+	{ID: "SHV", City: "Shreveport", State: "LA"},
+	{ID: "SJT", City: "San Angelo", State: "TX"},
+	{ID: "SJU", City: "San Juan", State: "PR"},
+	{ID: "TAE", City: "Tallahassee", State: "FL"},
+	{ID: "TBW", City: "Tampa Bay", State: "FL"},
+	{ID: "TSA", City: "Tulsa", State: "OK"},
+	// Central Region
+	{ID: "ABR", City: "Aberdeen", State: "SD"},
+	{ID: "APX", City: "Gaylord", State: "MI"},
+	{ID: "ARX", City: "La Crosse", State: "WI"},
+	{ID: "BIS", City: "Bismarck", State: "ND"},
+	{ID: "BOU", City: "Boulder", State: "CO"},
+	{ID: "CYS", City: "Cheyenne", State: "WY"},
+	{ID: "DDC", City: "Dodge City", State: "KS"},
+	{ID: "DLH", City: "Duluth", State: "MN"},
+	{ID: "DMX", City: "Des Moines", State: "IA"},
+	{ID: "DTX", City: "Detroit", State: "MI"},
+	{ID: "DVN", City: "Quad Cities", State: "IA"},
+	{ID: "EAX", City: "Kansas City/Pleasant Hill", State: "MO"},
+	{ID: "FGF", City: "Grand Forks", State: "ND"},
+	{ID: "FSD", City: "Sioux Falls", State: "SD"},
+	{ID: "GID", City: "Hastings", State: "NE"},
+	{ID: "GJT", City: "Grand Junction", State: "CO"},
+	{ID: "GLD", City: "Goodland", State: "KS"},
+	{ID: "GRB", City: "Green Bay", State: "WI"},
+	{ID: "GRR", City: "Grand Rapids", State: "MI"},
+	{ID: "ICT", City: "Wichita", State: "KS"},
+	{ID: "ILX", City: "Lincoln", State: "IL"},
+	{ID: "IND", City: "Indianapolis", State: "IN"},
+	{ID: "IWX", City: "Northern Indiana", State: "IN"},
+	{ID: "JKL", City: "Jackson", State: "KY"},
+	{ID: "LBF", City: "North Platte", State: "NE"},
+	{ID: "LMK", City: "Louisville", State: "KY"},
+	{ID: "LOT", City: "Chicago", State: "IL"},
+	{ID: "LSX", City: "St. Louis", State: "MO"},
+	{ID: "MKX", City: "Milwaukee/Sullivan", State: "WI"},
+	{ID: "MPX", City: "Twin Cities/Chanhassen", State: "MN"},
+	{ID: "MQT", City: "Marquette", State: "MI"},
+	{ID: "OAX", City: "Omaha/Valley", State: "NE"},
+	{ID: "PAH", City: "Paducah", State: "KY"},
+	{ID: "PUB", City: "Pueblo", State: "CO"},
+	{ID: "RIW", City: "Riverton", State: "WY"},
+	{ID: "SGF", City: "Springfield", State: "MO"},
+	{ID: "TOP", City: "Topeka", State: "KS"},
+	{ID: "UNR", City: "Rapid City", State: "SD"},
+	// Western Region
+	{ID: "BOI", City: "Boise", State: "ID"},
+	{ID: "BYZ", City: "Billings", State: "MT"},
+	{ID: "EKA", City: "Eureka", State: "CA"},
+	{ID: "FGZ", City: "Flagstaff", State: "AZ"},
+	{ID: "GGW", City: "Glasgow", State: "MT"},
+	{ID: "HNX", City: "Hanford", State: "CA"},
+	{ID: "LKN", City: "Elko", State: "NV"},
+	{ID: "LOX", City: "Los Angeles/Oxnard", State: "CA"},
+	{ID: "MFR", City: "Medford", State: "OR"},
+	{ID: "MSO", City: "Missoula", State: "MT"},
+	{ID: "MTR", City: "San Francisco Bay Area/Monterey", State: "CA"},
+	{ID: "OTX", City: "Spokane", State: "WA"},
+	{ID: "PDT", City: "Pendleton", State: "OR"},
+	{ID: "PIH", City: "Pocatello", State: "ID"},
+	{ID: "PQR", City: "Portland", State: "OR"},
+	{ID: "PSR", City: "Phoenix", State: "AZ"},
+	{ID: "REV", City: "Reno", State: "NV"},
+	{ID: "SEW", City: "Seattle", State: "WA"},
+	{ID: "SGX", City: "San Diego", State: "CA"},
+	{ID: "SLC", City: "Salt Lake City", State: "UT"},
+	{ID: "STO", City: "Sacramento", State: "CA"},
+	{ID: "TFX", City: "Great Falls", State: "MT"},
+	{ID: "TWC", City: "Tucson", State: "AZ"},
+	{ID: "VEF", City: "Las Vegas", State: "NV"},
+	// Alaska and Pacific Regions
+	{ID: "AFC", City: "Anchorage", State: "AK"},
+	{ID: "AFG", City: "Fairbanks", State: "AK"},
+	{ID: "AJK", City: "Juneau", State: "AK"},
+	{ID: "GUM", City: "Guam", State: "GU"},
+	{ID: "HFO", City: "Honolulu", State: "HI"},
+	{ID: "PPG", City: "Pago Pago", State: "AS"},
+}
@@ -0,0 +1,76 @@
+package nws
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOffice(t *testing.T) {
+	tests := []struct {
+		name         string
+		remarks      string
+		wantOffice   string
+		wantComments string
+	}{
+		{
+			name:         "should split the office off the remarks",
+			remarks:      "EF0 tree damage was confirmed in Jefferson county with EF1 dam (TAE)",
+			wantOffice:   "TAE",
+			wantComments: "EF0 tree damage was confirmed in Jefferson county with EF1 dam",
+		},
+		{
+			name:         "should accept trailing whitespace and lower case",
+			remarks:      "Quarter size hail. (oun) ",
+			wantOffice:   "OUN",
+			wantComments: "Quarter size hail.",
+		},
+		{
+			name:         "should parse remarks that are only the office",
+			remarks:      "(FWD)",
+			wantOffice:   "FWD",
+			wantComments: "",
+		},
+		{
+			name:         "should leave an unknown office in the remarks",
+			remarks:      "Large tree down (XYZ)",
+			wantOffice:   "",
+			wantComments: "Large tree down (XYZ)",
+		},
+		{
+			name:         "should ignore parentheses that aren't at the end",
+			remarks:      "Report relayed by (TAE) emergency manager",
+			wantOffice:   "",
+			wantComments: "Report relayed by (TAE) emergency manager",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			office, comments := ParseOffice(tt.remarks)
+			assert.Equal(t, tt.wantOffice, office)
+			assert.Equal(t, tt.wantComments, comments)
+		})
+	}
+}
+
+// TestOfficeBackfill checks the migration that parses the office out of
+// stored remarks knows the same offices ParseOffice does.
+func TestOfficeBackfill(t *testing.T) {
+	sql, err := os.ReadFile("../migrations/000016_backfill_nws_office.up.sql")
+	require.NoError(t, err)
+	var ids []string
+	for _, m := range regexp.MustCompile(`'([A-Z]{3})'`).FindAllStringSubmatch(string(sql), -1) {
+		ids = append(ids, m[1])
+	}
+	var want []string
+	for id := range Offices {
+		want = append(want, id)
+	}
+	slices.Sort(ids)
+	slices.Sort(want)
+	assert.Equal(t, want, ids)
+}
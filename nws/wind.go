@@ -0,0 +1,51 @@
+package nws
+
+import "regexp"
+
+// How a wind report's gust speed was arrived at.
+const (
+	WindMeasured  = "measured"
+	WindEstimated = "estimated"
+	// WindUnknown is a report without a speed, SPC lists these as UNK.
+	WindUnknown = "unknown"
+)
+
+// The remarks patterns are also used by the migrations that classify
+// stored reports, with \b written as postgres' \y.  "est" only counts
+// before a gust, wind or speed so the EST time zone isn't read as an
+// estimate.
+const (
+	estimatedPattern = `\b(estimated|eg|not measured)\b|\best\.?\s*(gust|wind|\d)`
+	measuredPattern  = `\b(measured|meas|mg|asos|awos|mesonet|anemometer|weatherflow)\b`
+)
+
+var (
+	// estimatedRemarks is checked first so "not measured" isn't read as
+	// measured.
+	estimatedRemarks = regexp.MustCompile(`(?i)` + estimatedPattern)
+	measuredRemarks  = regexp.MustCompile(`(?i)` + measuredPattern)
+)
+
+// WindMeasurement classifies a wind report's speed, in mph with 0 for
+// unknown, using the remarks the office attached to it.  The M and E
+// prefixes SPC puts on the magnitude don't survive the transformer, so
+// the remarks are all there is.  Gusts are estimated unless the remarks
+// say otherwise, as they are in SPC's own listings.
+func WindMeasurement(speed int32, remarks string) string {
+	switch {
+	case speed <= 0:
+		return WindUnknown
+	case estimatedRemarks.MatchString(remarks):
+		return WindEstimated
+	case measuredRemarks.MatchString(remarks):
+		return WindMeasured
+	default:
+		return WindEstimated
+	}
+}
+
+// IsWindMeasurement reports whether m is one of the wind measurement
+// types.
+func IsWindMeasurement(m string) bool {
+	return m == WindMeasured || m == WindEstimated || m == WindUnknown
+}
@@ -0,0 +1,92 @@
+package nws
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindMeasurement(t *testing.T) {
+	tests := []struct {
+		name    string
+		speed   int32
+		remarks string
+		want    string
+	}{
+		{
+			name:    "should be unknown without a speed",
+			speed:   0,
+			remarks: "Measured by ASOS.",
+			want:    WindUnknown,
+		},
+		{
+			name:    "should be measured when the remarks name an instrument",
+			speed:   58,
+			remarks: "Gust recorded by the KOKC ASOS.",
+			want:    WindMeasured,
+		},
+		{
+			name:    "should be measured when the remarks say so",
+			speed:   65,
+			remarks: "MEASURED GUST AT A HOME WEATHER STATION",
+			want:    WindMeasured,
+		},
+		{
+			name:    "should not read not measured as measured",
+			speed:   60,
+			remarks: "Gust not measured, several trees down.",
+			want:    WindEstimated,
+		},
+		{
+			name:    "should default to estimated",
+			speed:   50,
+			remarks: "Large tree down on power lines.",
+			want:    WindEstimated,
+		},
+		{
+			name:    "should not read the EST time zone as an estimate",
+			speed:   61,
+			remarks: "Measured by the KTLH ASOS at 4:15 PM EST.",
+			want:    WindMeasured,
+		},
+		{
+			name:    "should read est before a gust as an estimate",
+			speed:   70,
+			remarks: "Est. gust 70 mph, measured damage path 2 miles long.",
+			want:    WindEstimated,
+		},
+		{
+			name:    "should read est before a speed as an estimate",
+			speed:   60,
+			remarks: "EST 60MPH winds blew down a carport.",
+			want:    WindEstimated,
+		},
+		{
+			name:    "should not match words that contain a keyword",
+			speed:   50,
+			remarks: "Trees down along Mesonetwork Road.",
+			want:    WindEstimated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WindMeasurement(tt.speed, tt.remarks))
+		})
+	}
+}
+
+// TestWindMeasurementMigration checks the migration that classifies stored
+// reports uses the same patterns as WindMeasurement.
+func TestWindMeasurementMigration(t *testing.T) {
+	sql, err := os.ReadFile("../migrations/000011_add_wind_measurement.up.sql")
+	require.NoError(t, err)
+	var patterns []string
+	for _, m := range regexp.MustCompile(`comments ~\* '([^']*)'`).FindAllStringSubmatch(string(sql), -1) {
+		patterns = append(patterns, strings.ReplaceAll(m[1], `\y`, `\b`))
+	}
+	assert.Equal(t, []string{estimatedPattern, measuredPattern}, patterns)
+}
@@ -0,0 +1,352 @@
+// Package parquet writes Apache Parquet files with flat schemas, see
+// https://github.com/apache/parquet-format.  Every column is PLAIN encoded
+// in one snappy compressed data page per row group, which every reader
+// supports.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// Type is the type of a column.
+type Type int
+
+// Column types.
+const (
+	Int32 Type = iota
+	Int64
+	Double
+	// String is UTF-8 text.
+	String
+	// Timestamp is microseconds since the epoch, adjusted to UTC.
+	Timestamp
+)
+
+// Column is a column of the schema, optional columns can hold nulls.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// DefaultRowGroupSize is how many rows are buffered before a row group is
+// written.
+const DefaultRowGroupSize = 64 * 1024
+
+const magic = "PAR1"
+
+// Physical types, repetition types and other enums from parquet.thrift.
+const (
+	physicalInt32     = 1
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecSnappy = 1
+
+	pageTypeData = 0
+)
+
+// Writer writes rows to a Parquet file.  Rows are buffered and written a
+// row group at a time, Close writes the last row group and the footer.
+type Writer struct {
+	w            countingWriter
+	columns      []Column
+	rowGroupSize int
+
+	// the buffered row group, a slice of values per column.
+	values  [][]any
+	rows    int
+	total   int64
+	groups  []*thriftWriter
+	started bool
+}
+
+// NewWriter writes a file with the given columns to w.
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	return &Writer{
+		w:            countingWriter{w: w},
+		columns:      columns,
+		rowGroupSize: DefaultRowGroupSize,
+		values:       make([][]any, len(columns)),
+	}
+}
+
+// Write adds a row, a value per column.  Values are int32, int64,
+// float64, string and time.Time for the column types, or nil for null in
+// an optional column.
+func (pw *Writer) Write(row []any) error {
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("got %d values for %d columns", len(row), len(pw.columns))
+	}
+	for i, c := range pw.columns {
+		if err := checkValue(c, row[i]); err != nil {
+			return fmt.Errorf("column %s: %w", c.Name, err)
+		}
+	}
+	for i, v := range row {
+		pw.values[i] = append(pw.values[i], v)
+	}
+	pw.rows++
+	if pw.rows >= pw.rowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the footer.  It doesn't close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	if !pw.started {
+		if err := pw.start(); err != nil {
+			return err
+		}
+	}
+	footer := pw.footer()
+	if _, err := pw.w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(&pw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(&pw.w, magic)
+	return err
+}
+
+func (pw *Writer) start() error {
+	pw.started = true
+	_, err := io.WriteString(&pw.w, magic)
+	return err
+}
+
+var errValueType = errors.New("value type doesn't match the column type")
+
+func checkValue(c Column, v any) error {
+	if v == nil {
+		if !c.Optional {
+			return errors.New("null in a required column")
+		}
+		return nil
+	}
+	var ok bool
+	switch c.Type {
+	case Int32:
+		_, ok = v.(int32)
+	case Int64:
+		_, ok = v.(int64)
+	case Double:
+		_, ok = v.(float64)
+	case String:
+		_, ok = v.(string)
+	case Timestamp:
+		_, ok = v.(time.Time)
+	}
+	if !ok {
+		return errValueType
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group, a column chunk of one
+// data page per column.
+func (pw *Writer) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	if !pw.started {
+		if err := pw.start(); err != nil {
+			return err
+		}
+	}
+
+	var chunks []*thriftWriter
+	var groupSize int64
+	for i, c := range pw.columns {
+		offset := pw.w.n
+		page := encodePage(c, pw.values[i])
+		compressed := snappy.Encode(nil, page)
+
+		var dph thriftWriter
+		dph.i32(1, int32(pw.rows))
+		dph.i32(2, encodingPlain)
+		dph.i32(3, encodingRLE)
+		dph.i32(4, encodingRLE)
+		var ph thriftWriter
+		ph.i32(1, pageTypeData)
+		ph.i32(2, int32(len(page)))
+		ph.i32(3, int32(len(compressed)))
+		ph.structure(5, &dph)
+		header := ph.bytes()
+
+		if _, err := pw.w.Write(header); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(compressed); err != nil {
+			return err
+		}
+
+		var meta thriftWriter
+		meta.i32(1, physicalType(c.Type))
+		meta.i32List(2, []int32{encodingPlain, encodingRLE})
+		meta.stringList(3, []string{c.Name})
+		meta.i32(4, codecSnappy)
+		meta.i64(5, int64(pw.rows))
+		meta.i64(6, int64(len(header)+len(page)))
+		meta.i64(7, int64(len(header)+len(compressed)))
+		meta.i64(9, offset)
+		var chunk thriftWriter
+		chunk.i64(2, offset)
+		chunk.structure(3, &meta)
+		chunks = append(chunks, &chunk)
+		groupSize += int64(len(header) + len(page))
+	}
+
+	var group thriftWriter
+	group.structList(1, chunks)
+	group.i64(2, groupSize)
+	group.i64(3, int64(pw.rows))
+	pw.groups = append(pw.groups, &group)
+
+	pw.total += int64(pw.rows)
+	pw.rows = 0
+	for i := range pw.values {
+		pw.values[i] = pw.values[i][:0]
+	}
+	return nil
+}
+
+// encodePage returns a data page's contents, the definition levels of an
+// optional column followed by its non null values.
+func encodePage(c Column, values []any) []byte {
+	var buf bytes.Buffer
+	if c.Optional {
+		levels := definitionLevels(values)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(levels)))
+		buf.Write(levels)
+	}
+	var scratch [8]byte
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch v := v.(type) {
+		case int32:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(v))
+			buf.Write(scratch[:4])
+		case int64:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v))
+			buf.Write(scratch[:])
+		case float64:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(v))
+			buf.Write(scratch[:])
+		case string:
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(len(v)))
+			buf.Write(scratch[:4])
+			buf.WriteString(v)
+		case time.Time:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v.UnixMicro()))
+			buf.Write(scratch[:])
+		}
+	}
+	return buf.Bytes()
+}
+
+// definitionLevels encodes whether each value is set as a single
+// bit-packed run of the RLE/bit-packing hybrid, with a bit width of 1.
+func definitionLevels(values []any) []byte {
+	groups := (len(values) + 7) / 8
+	b := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, v := range values {
+		if v != nil {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(b, packed...)
+}
+
+func physicalType(t Type) int32 {
+	switch t {
+	case Int32:
+		return physicalInt32
+	case Int64, Timestamp:
+		return physicalInt64
+	case Double:
+		return physicalDouble
+	}
+	return physicalByteArray
+}
+
+func (pw *Writer) footer() []byte {
+	root := &thriftWriter{}
+	root.string(4, "schema")
+	root.i32(5, int32(len(pw.columns)))
+	schema := []*thriftWriter{root}
+	for _, c := range pw.columns {
+		el := &thriftWriter{}
+		el.i32(1, physicalType(c.Type))
+		repetition := int32(repetitionRequired)
+		if c.Optional {
+			repetition = repetitionOptional
+		}
+		el.i32(3, repetition)
+		el.string(4, c.Name)
+		switch c.Type {
+		case String:
+			el.i32(6, convertedUTF8)
+			var str, logical thriftWriter
+			logical.structure(1, &str)
+			el.structure(10, &logical)
+		case Timestamp:
+			el.i32(6, convertedTimestampMicros)
+			var micros, unit, ts, logical thriftWriter
+			unit.structure(2, &micros)
+			ts.bool(1, true)
+			ts.structure(2, &unit)
+			logical.structure(8, &ts)
+			el.structure(10, &logical)
+		}
+		schema = append(schema, el)
+	}
+
+	var meta thriftWriter
+	meta.i32(1, 1)
+	meta.structList(2, schema)
+	meta.i64(3, pw.total)
+	meta.structList(4, pw.groups)
+	meta.string(6, "stormsync")
+	return meta.bytes()
+}
+
+// countingWriter tracks the offset of what is written, column chunks are
+// located by their offset in the file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,127 @@
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriter reads the file back with parquet-go, so the writer is checked
+// against a reader other than itself.
+func TestWriter(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: Int64},
+		{Name: "time", Type: Timestamp},
+		{Name: "magnitude", Type: Int32, Optional: true},
+		{Name: "location", Type: String, Optional: true},
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf, columns)
+	w.rowGroupSize = 2
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	rows := [][]any{
+		{int64(1), day, int32(175), "Norman"},
+		{int64(2), day.Add(time.Hour), nil, nil},
+		{int64(3), day.Add(2 * time.Hour), nil, "Moore"},
+	}
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	f, err := pq.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(rows)), f.NumRows())
+	assert.Equal(t, "stormsync", f.Metadata().CreatedBy)
+
+	fields := f.Schema().Fields()
+	require.Len(t, fields, len(columns))
+	for i, c := range columns {
+		assert.Equal(t, c.Name, fields[i].Name())
+		assert.Equal(t, c.Optional, fields[i].Optional(), c.Name)
+	}
+	assert.Equal(t, "STRING", fields[3].Type().LogicalType().String())
+	assert.Equal(t, "TIMESTAMP(isAdjustedToUTC=true,unit=MICROS)", fields[1].Type().LogicalType().String())
+
+	require.Len(t, f.RowGroups(), 2)
+	var got [][]any
+	for _, g := range f.RowGroups() {
+		read := make([]pq.Row, g.NumRows())
+		r := g.Rows()
+		n, err := r.ReadRows(read)
+		if !errors.Is(err, io.EOF) {
+			require.NoError(t, err)
+		}
+		require.NoError(t, r.Close())
+		require.Equal(t, int(g.NumRows()), n)
+		for _, row := range read {
+			values := make([]any, len(columns))
+			for _, v := range row {
+				if v.IsNull() {
+					continue
+				}
+				switch columns[v.Column()].Type {
+				case Int32:
+					values[v.Column()] = v.Int32()
+				case Int64:
+					values[v.Column()] = v.Int64()
+				case Timestamp:
+					values[v.Column()] = time.UnixMicro(v.Int64()).UTC()
+				case String:
+					values[v.Column()] = string(v.ByteArray())
+				}
+			}
+			got = append(got, values)
+		}
+	}
+	assert.Equal(t, rows, got)
+}
+
+func TestWriter_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		row  []any
+	}{
+		{name: "too few values", row: []any{int64(1)}},
+		{name: "null in required column", row: []any{nil, "x"}},
+		{name: "wrong type", row: []any{int32(1), "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(&bytes.Buffer{}, []Column{
+				{Name: "id", Type: Int64},
+				{Name: "location", Type: String, Optional: true},
+			})
+			assert.Error(t, w.Write(tt.row))
+		})
+	}
+}
+
+func TestDefinitionLevels(t *testing.T) {
+	values := []any{1, nil, 3, nil, nil, nil, nil, nil, 9}
+	// two groups of 8 bit-packed levels, header (2 << 1) | 1.
+	assert.Equal(t, []byte{5, 0b101, 0b1}, definitionLevels(values))
+}
+
+func TestThriftWriter(t *testing.T) {
+	var inner thriftWriter
+	inner.bool(1, true)
+	var tw thriftWriter
+	tw.i32(1, -1)
+	tw.string(2, "ab")
+	tw.i64(20, 3)
+	tw.structure(21, &inner)
+	assert.Equal(t, []byte{
+		0x15, 0x01, // field 1 i32, zigzag -1
+		0x18, 0x02, 'a', 'b', // field 2 binary
+		0x06, 0x28, 0x06, // field 20 i64, long form
+		0x1c, 0x11, 0x00, // field 21 struct, bool true, stop
+		0x00,
+	}, tw.bytes())
+}
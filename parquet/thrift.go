@@ -0,0 +1,101 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+// Thrift compact protocol types, the parquet footer and page headers are
+// thrift structs.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes one struct with the compact protocol.  Fields must
+// be written in increasing id order, nested structs are written with
+// their own writer and added as bytes.
+type thriftWriter struct {
+	b    []byte
+	last int16
+}
+
+func (t *thriftWriter) header(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.b = append(t.b, byte(delta)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.b = binary.AppendVarint(t.b, int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.header(id, thriftI32)
+	t.b = binary.AppendVarint(t.b, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.header(id, thriftI64)
+	t.b = binary.AppendVarint(t.b, v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.header(id, thriftTrue)
+	} else {
+		t.header(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.header(id, thriftBinary)
+	t.b = binary.AppendUvarint(t.b, uint64(len(v)))
+	t.b = append(t.b, v...)
+}
+
+func (t *thriftWriter) structure(id int16, s *thriftWriter) {
+	t.header(id, thriftStruct)
+	t.b = append(t.b, s.bytes()...)
+}
+
+func (t *thriftWriter) listHeader(id int16, elemType byte, n int) {
+	t.header(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elemType)
+	} else {
+		t.b = append(t.b, 0xf0|elemType)
+		t.b = binary.AppendUvarint(t.b, uint64(n))
+	}
+}
+
+func (t *thriftWriter) i32List(id int16, vs []int32) {
+	t.listHeader(id, thriftI32, len(vs))
+	for _, v := range vs {
+		t.b = binary.AppendVarint(t.b, int64(v))
+	}
+}
+
+func (t *thriftWriter) stringList(id int16, vs []string) {
+	t.listHeader(id, thriftBinary, len(vs))
+	for _, v := range vs {
+		t.b = binary.AppendUvarint(t.b, uint64(len(v)))
+		t.b = append(t.b, v...)
+	}
+}
+
+func (t *thriftWriter) structList(id int16, vs []*thriftWriter) {
+	t.listHeader(id, thriftStruct, len(vs))
+	for _, v := range vs {
+		t.b = append(t.b, v.bytes()...)
+	}
+}
+
+// bytes returns the struct with its stop byte.
+func (t *thriftWriter) bytes() []byte {
+	return append(t.b[:len(t.b):len(t.b)], 0)
+}
@@ -0,0 +1,123 @@
+// Package pubsub fans reports stored by the consumer out to the parts of
+// the service that react to new data, like the api response cache and
+// the live report streams.
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/stormsync/database"
+)
+
+// Message is a report that has been stored in the database.
+type Message struct {
+	// ID is the report's id in the database.  The consumer stores and
+	// publishes reports one at a time so ids increase, and a client can
+	// resume from the database with the same id once it has fallen out of
+	// the broker's history.
+	ID     int64
+	Report database.InsertReportParams
+	// Flags are the names of the quality flags raised for the report.
+	Flags []string
+}
+
+// Broker delivers every published message to every subscriber and keeps
+// the most recent messages so reconnecting subscribers can catch up.
+// Publish never blocks, a subscriber that can't keep up misses messages.
+type Broker struct {
+	mu      sync.RWMutex
+	subs    map[*Subscription]struct{}
+	history []Message
+	size    int
+	lastID  int64
+}
+
+// NewBroker creates a broker that remembers the last history messages.
+func NewBroker(history int) *Broker {
+	return &Broker{
+		subs: make(map[*Subscription]struct{}),
+		size: history,
+	}
+}
+
+// Subscription receives published messages on C until Close is called.
+type Subscription struct {
+	C       <-chan Message
+	c       chan Message
+	broker  *Broker
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Subscribe registers a subscriber whose channel holds up to buffer
+// messages before new ones are dropped.
+func (b *Broker) Subscribe(buffer int) *Subscription {
+	sub, _, _ := b.SubscribeSince(buffer, 0)
+	return sub
+}
+
+// SubscribeSince registers a subscriber and returns the messages
+// published after lastID that are still in the history.  complete is
+// false when lastID is older than the history, the caller has to fetch
+// what is missing elsewhere.  A lastID of 0 returns no history.
+func (b *Broker) SubscribeSince(buffer int, lastID int64) (sub *Subscription, missed []Message, complete bool) {
+	c := make(chan Message, buffer)
+	sub = &Subscription{C: c, c: c, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	i := sort.Search(len(b.history), func(i int) bool { return b.history[i].ID > lastID })
+	missed = append(missed, b.history[i:]...)
+	// an id newer than anything published means the broker restarted
+	// since the client last saw it, so the history can't be trusted.
+	complete = lastID == b.lastID || (len(b.history) > 0 && b.history[0].ID <= lastID && lastID <= b.lastID)
+	return sub, missed, complete
+}
+
+// Publish sends the message to every subscriber.  Messages must be
+// published in ID order.
+func (b *Broker) Publish(msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID = msg.ID
+
+	if b.size > 0 {
+		if len(b.history) == b.size {
+			copy(b.history, b.history[1:])
+			b.history = b.history[:b.size-1]
+		}
+		b.history = append(b.history, msg)
+	}
+
+	for s := range b.subs {
+		select {
+		case s.c <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns how many messages were dropped because the
+// subscriber's buffer was full.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unregisters the subscription and closes its channel.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		s.broker.mu.Unlock()
+		close(s.c)
+	})
+}
@@ -0,0 +1,41 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_SubscribeSince(t *testing.T) {
+	b := NewBroker(2)
+	created := time.Date(2024, 5, 9, 18, 0, 0, 0, time.UTC)
+	ids := []int64{7, 8, 9}
+	for _, id := range ids {
+		// reports stored together can share a created_at.
+		b.Publish(Message{ID: id, Report: database.InsertReportParams{CreatedAt: pgtype.Timestamptz{Time: created, Valid: true}}})
+	}
+
+	tests := []struct {
+		name         string
+		lastID       int64
+		wantMissed   int
+		wantComplete bool
+	}{
+		{name: "should not replay for a new client", lastID: 0, wantMissed: 0, wantComplete: true},
+		{name: "should replay from the history", lastID: ids[1], wantMissed: 1, wantComplete: true},
+		{name: "should be up to date", lastID: ids[2], wantMissed: 0, wantComplete: true},
+		{name: "should be incomplete past the history", lastID: ids[0] - 1, wantMissed: 2, wantComplete: false},
+		{name: "should be incomplete for an id from before a restart", lastID: ids[2] + 10, wantMissed: 0, wantComplete: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, missed, complete := b.SubscribeSince(1, tt.lastID)
+			defer sub.Close()
+			assert.Len(t, missed, tt.wantMissed)
+			assert.Equal(t, tt.wantComplete, complete)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package quality flags stored reports that are probably wrong so they can
+// be reviewed rather than trusted.
+package quality
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+// The flags the default rules raise.
+const (
+	// FlagOutsideUS is raised for coordinates that aren't near any state,
+	// usually a point in the ocean.
+	FlagOutsideUS = "coordinates_outside_us"
+	// FlagCoordinateStateMismatch is raised when the coordinates aren't
+	// near the state the report names.
+	FlagCoordinateStateMismatch = "coordinate_state_mismatch"
+	// FlagMagnitudeOutlier is raised for magnitudes that are possible but
+	// rare enough to double check, like giant hail in winter.
+	FlagMagnitudeOutlier = "magnitude_outlier"
+	// FlagDuplicateNearby is raised when a report of the same type was
+	// stored close by at nearly the same time.
+	FlagDuplicateNearby = "duplicate_nearby"
+	// FlagCorrected is raised when ingestion had to correct a value.
+	FlagCorrected = "corrected_value"
+)
+
+// Flag is a reason to doubt a report.
+type Flag struct {
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Rule checks a report before it is stored, it returns nil when the
+// report looks fine.
+type Rule interface {
+	Check(ctx context.Context, r database.InsertReportParams) (*Flag, error)
+}
+
+// RuleFunc adapts a function to a Rule.
+type RuleFunc func(ctx context.Context, r database.InsertReportParams) (*Flag, error)
+
+// Check calls f.
+func (f RuleFunc) Check(ctx context.Context, r database.InsertReportParams) (*Flag, error) {
+	return f(ctx, r)
+}
+
+// Engine runs every rule against a report.
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine creates an engine that runs rules in order.
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// DefaultRules are the rules run at ingestion, duplicates are looked up
+// in db.
+func DefaultRules(db database.DBTX) []Rule {
+	return []Rule{
+		CoordinateState(),
+		MagnitudeOutlier(),
+		DuplicateNearby(db, 3, defaultDuplicateWindow),
+	}
+}
+
+// Check returns the flags raised for the report.  A rule that fails
+// doesn't stop the others, its error is returned with whatever flags the
+// rest raised.
+func (e *Engine) Check(ctx context.Context, r database.InsertReportParams) ([]Flag, error) {
+	var flags []Flag
+	var errs []string
+	for _, rule := range e.rules {
+		f, err := rule.Check(ctx, r)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if f != nil {
+			flags = append(flags, *f)
+		}
+	}
+	if len(errs) > 0 {
+		return flags, fmt.Errorf("quality rules failed: %s", strings.Join(errs, "; "))
+	}
+	return flags, nil
+}
+
+// reportPoint returns the report's coordinates, ok is false if they
+// aren't numbers.
+func reportPoint(r database.InsertReportParams) (geo.Point, bool) {
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(r.Latitude.String), 64)
+	lon, lonErr := strconv.ParseFloat(strings.TrimSpace(r.Longitude.String), 64)
+	return geo.Point{Lon: lon, Lat: lat}, latErr == nil && lonErr == nil
+}
@@ -0,0 +1,74 @@
+package quality
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stormsync/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Check(t *testing.T) {
+	report := func(typ database.ReportType, magnitude int32, month time.Month, state, lat, lon string) database.InsertReportParams {
+		return database.InsertReportParams{
+			RptType:      typ,
+			ReportedTime: pgtype.Timestamptz{Time: time.Date(2024, month, 10, 20, 0, 0, 0, time.UTC), Valid: true},
+			VarCol:       pgtype.Int4{Int32: magnitude, Valid: true},
+			State:        pgtype.Text{String: state, Valid: true},
+			Latitude:     pgtype.Text{String: lat, Valid: true},
+			Longitude:    pgtype.Text{String: lon, Valid: true},
+		}
+	}
+	tests := []struct {
+		name   string
+		report database.InsertReportParams
+		want   []string
+	}{
+		{
+			name:   "should not flag an ordinary report",
+			report: report(database.ReportTypeHail, 175, time.May, "OK", "35.22", "-97.44"),
+		},
+		{
+			name:   "should flag a point in the gulf",
+			report: report(database.ReportTypeWind, 50, time.May, "TX", "25.0", "-90.0"),
+			want:   []string{FlagOutsideUS},
+		},
+		{
+			name:   "should flag coordinates in another state",
+			report: report(database.ReportTypeWind, 50, time.May, "FL", "35.22", "-97.44"),
+			want:   []string{FlagCoordinateStateMismatch},
+		},
+		{
+			name:   "should allow a point just over the border",
+			report: report(database.ReportTypeWind, 50, time.May, "OK", "37.1", "-97.44"),
+		},
+		{
+			name:   "should flag large hail in winter",
+			report: report(database.ReportTypeHail, 250, time.January, "TX", "32.78", "-96.80"),
+			want:   []string{FlagMagnitudeOutlier},
+		},
+		{
+			name:   "should not flag the same hail in spring",
+			report: report(database.ReportTypeHail, 250, time.April, "TX", "32.78", "-96.80"),
+		},
+		{
+			name:   "should flag an EF4",
+			report: report(database.ReportTypeTornado, 4, time.April, "MS", "32.3", "-90.2"),
+			want:   []string{FlagMagnitudeOutlier},
+		},
+	}
+	e := NewEngine(CoordinateState(), MagnitudeOutlier())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := e.Check(context.Background(), tt.report)
+			assert.NoError(t, err)
+			var names []string
+			for _, f := range flags {
+				names = append(names, f.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
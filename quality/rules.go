@@ -0,0 +1,127 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stormsync/database"
+
+	"github.com/jason-costello/weather/accesssvc/geo"
+)
+
+const (
+	// reports give the location of the event, which can be just over the
+	// border from the county it was filed under.
+	stateMarginDegrees = 0.25
+
+	defaultDuplicateWindow = 15 * time.Minute
+
+	hailOutlier       = 400 // hundredths of an inch
+	winterHailOutlier = 200
+	windOutlier       = 100 // mph
+	tornadoOutlier    = 4   // EF scale
+)
+
+// CoordinateState flags reports whose coordinates are outside the state
+// they name, or outside every state.
+func CoordinateState() Rule {
+	return RuleFunc(func(_ context.Context, r database.InsertReportParams) (*Flag, error) {
+		pt, ok := reportPoint(r)
+		if !ok {
+			return nil, nil
+		}
+		if !inAnyState(pt) {
+			return &Flag{Name: FlagOutsideUS, Detail: fmt.Sprintf("%g,%g is not near any state", pt.Lat, pt.Lon)}, nil
+		}
+		state := strings.ToUpper(r.State.String)
+		boxes, known := stateBounds[state]
+		if !known {
+			return nil, nil
+		}
+		for _, b := range boxes {
+			if b.contains(pt, stateMarginDegrees) {
+				return nil, nil
+			}
+		}
+		return &Flag{Name: FlagCoordinateStateMismatch, Detail: fmt.Sprintf("%g,%g is not in %s", pt.Lat, pt.Lon, state)}, nil
+	})
+}
+
+// MagnitudeOutlier flags magnitudes in the top fraction of a percent of
+// reports, and large hail outside the warm season.
+func MagnitudeOutlier() Rule {
+	return RuleFunc(func(_ context.Context, r database.InsertReportParams) (*Flag, error) {
+		if !r.VarCol.Valid {
+			return nil, nil
+		}
+		m := r.VarCol.Int32
+		switch r.RptType {
+		case database.ReportTypeHail:
+			if m >= hailOutlier {
+				return &Flag{Name: FlagMagnitudeOutlier, Detail: fmt.Sprintf("%.2f inch hail", float64(m)/100)}, nil
+			}
+			if m >= winterHailOutlier && isWinter(r.ReportedTime.Time, r.Latitude.String) {
+				return &Flag{Name: FlagMagnitudeOutlier, Detail: fmt.Sprintf("%.2f inch hail in %s", float64(m)/100, r.ReportedTime.Time.Month())}, nil
+			}
+		case database.ReportTypeWind:
+			if m >= windOutlier {
+				return &Flag{Name: FlagMagnitudeOutlier, Detail: fmt.Sprintf("%d mph wind", m)}, nil
+			}
+		case database.ReportTypeTornado:
+			if m >= tornadoOutlier {
+				return &Flag{Name: FlagMagnitudeOutlier, Detail: fmt.Sprintf("EF%d tornado", m)}, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// isWinter reports whether t is in the cold season of the hemisphere the
+// latitude is in.
+func isWinter(t time.Time, latitude string) bool {
+	month := t.UTC().Month()
+	winter := month == time.December || month == time.January || month == time.February
+	if strings.HasPrefix(strings.TrimSpace(latitude), "-") {
+		winter = month == time.June || month == time.July || month == time.August
+	}
+	return winter
+}
+
+const nearbyReports = `select latitude, longitude
+from reports
+where rpt_type = $1
+  and reported_time between $2 and $3`
+
+// DuplicateNearby flags a report when one of the same type was already
+// stored within radiusMiles and window of it, often the same event
+// reported twice from different landmarks.
+func DuplicateNearby(db database.DBTX, radiusMiles float64, window time.Duration) Rule {
+	return RuleFunc(func(ctx context.Context, r database.InsertReportParams) (*Flag, error) {
+		pt, ok := reportPoint(r)
+		if !ok || !r.ReportedTime.Valid {
+			return nil, nil
+		}
+		t := r.ReportedTime.Time
+		rows, err := db.Query(ctx, nearbyReports, r.RptType, t.Add(-window), t.Add(window))
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up nearby reports: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var other database.InsertReportParams
+			if err := rows.Scan(&other.Latitude, &other.Longitude); err != nil {
+				return nil, err
+			}
+			otherPt, ok := reportPoint(other)
+			if !ok {
+				continue
+			}
+			if d := geo.DistanceMiles(pt, otherPt); d <= radiusMiles {
+				return &Flag{Name: FlagDuplicateNearby, Detail: fmt.Sprintf("%s report %.1f miles away", r.RptType, d)}, nil
+			}
+		}
+		return nil, rows.Err()
+	})
+}
@@ -0,0 +1,268 @@
+// Package shapefile writes ESRI point Shapefiles, the .shp geometry, its
+// .shx index and the .dbf attribute table, as described in the ESRI
+// Shapefile Technical Description and the dBASE III file format.
+package shapefile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WGS84 is the .prj contents for longitude and latitude in WGS84.
+const WGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// CodePage is the .cpg contents, the .dbf strings are UTF-8.
+const CodePage = "UTF-8"
+
+// FieldType is a dBASE column type.
+type FieldType byte
+
+// Column types.  Dates have no time, put times in a Character column.
+const (
+	Character FieldType = 'C'
+	Numeric   FieldType = 'N'
+	Date      FieldType = 'D'
+	Logical   FieldType = 'L'
+)
+
+// Field is a column of the attribute table.  Names are at most 10 bytes,
+// Character fields at most 254.
+type Field struct {
+	Name     string
+	Type     FieldType
+	Length   uint8
+	Decimals uint8
+}
+
+const (
+	headerSize     = 100
+	fileCode       = 9994
+	version        = 1000
+	shapeTypePoint = 1
+	// a point record is its 8 byte header and 20 bytes of content.
+	pointContentSize = 20
+	pointRecordSize  = 8 + pointContentSize
+)
+
+// Writer writes point records.  The files are seeked back to when the
+// writer is closed to fill in the counts and bounds the headers need.
+type Writer struct {
+	shp, shx, dbf io.WriteSeeker
+	fields        []Field
+	records       int
+	bounds        [4]float64
+	record        []byte
+}
+
+// NewPointWriter starts a point shapefile with the given columns.
+func NewPointWriter(shp, shx, dbf io.WriteSeeker, fields []Field) (*Writer, error) {
+	for _, f := range fields {
+		if f.Name == "" || len(f.Name) > 10 {
+			return nil, fmt.Errorf("field name %q must be 1 to 10 bytes", f.Name)
+		}
+		if f.Length == 0 || (f.Type == Character && f.Length > 254) {
+			return nil, fmt.Errorf("field %s length %d not valid", f.Name, f.Length)
+		}
+	}
+	w := &Writer{
+		shp:    shp,
+		shx:    shx,
+		dbf:    dbf,
+		fields: fields,
+		bounds: [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+	}
+	// placeholders, Close writes the real headers.
+	for _, f := range []io.Writer{shp, shx} {
+		if _, err := f.Write(make([]byte, headerSize)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dbf.Write(w.dbfHeader(time.Now())); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write adds a point at x, y with a value per field.  Values are strings
+// for Character fields, numbers for Numeric fields, time.Time for Date
+// fields and bools for Logical fields.  A nil value is left blank, which
+// GIS tools read as null.
+func (w *Writer) Write(x, y float64, values []any) error {
+	if len(values) != len(w.fields) {
+		return fmt.Errorf("got %d values for %d fields", len(values), len(w.fields))
+	}
+	record := w.record[:0]
+	record = append(record, ' ')
+	for i, f := range w.fields {
+		v, err := formatValue(f, values[i])
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		record = append(record, v...)
+	}
+	w.record = record
+
+	w.records++
+	var rec [pointRecordSize]byte
+	binary.BigEndian.PutUint32(rec[0:], uint32(w.records))
+	binary.BigEndian.PutUint32(rec[4:], pointContentSize/2)
+	binary.LittleEndian.PutUint32(rec[8:], shapeTypePoint)
+	binary.LittleEndian.PutUint64(rec[12:], math.Float64bits(x))
+	binary.LittleEndian.PutUint64(rec[20:], math.Float64bits(y))
+	if _, err := w.shp.Write(rec[:]); err != nil {
+		return err
+	}
+
+	var idx [8]byte
+	offset := headerSize + (w.records-1)*pointRecordSize
+	binary.BigEndian.PutUint32(idx[0:], uint32(offset/2))
+	binary.BigEndian.PutUint32(idx[4:], pointContentSize/2)
+	if _, err := w.shx.Write(idx[:]); err != nil {
+		return err
+	}
+	if _, err := w.dbf.Write(record); err != nil {
+		return err
+	}
+
+	w.bounds[0] = math.Min(w.bounds[0], x)
+	w.bounds[1] = math.Min(w.bounds[1], y)
+	w.bounds[2] = math.Max(w.bounds[2], x)
+	w.bounds[3] = math.Max(w.bounds[3], y)
+	return nil
+}
+
+// Close writes the headers now the counts and bounds are known.  It
+// doesn't close the files.
+func (w *Writer) Close() error {
+	if _, err := w.dbf.Write([]byte{0x1a}); err != nil {
+		return err
+	}
+	bounds := w.bounds
+	if w.records == 0 {
+		bounds = [4]float64{}
+	}
+	files := []struct {
+		f    io.WriteSeeker
+		size int
+	}{
+		{w.shp, headerSize + w.records*pointRecordSize},
+		{w.shx, headerSize + w.records*8},
+	}
+	for _, file := range files {
+		if _, err := file.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := file.f.Write(mainHeader(file.size, bounds)); err != nil {
+			return err
+		}
+	}
+	if _, err := w.dbf.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.dbf.Write(w.dbfHeader(time.Now()))
+	return err
+}
+
+func mainHeader(size int, bounds [4]float64) []byte {
+	h := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(h[0:], fileCode)
+	binary.BigEndian.PutUint32(h[24:], uint32(size/2))
+	binary.LittleEndian.PutUint32(h[28:], version)
+	binary.LittleEndian.PutUint32(h[32:], shapeTypePoint)
+	for i, b := range bounds {
+		binary.LittleEndian.PutUint64(h[36+8*i:], math.Float64bits(b))
+	}
+	return h
+}
+
+func (w *Writer) dbfHeader(now time.Time) []byte {
+	recordSize := 1
+	for _, f := range w.fields {
+		recordSize += int(f.Length)
+	}
+	size := 32 + 32*len(w.fields) + 1
+	h := make([]byte, size)
+	h[0] = 0x03
+	h[1], h[2], h[3] = byte(now.Year()-1900), byte(now.Month()), byte(now.Day())
+	binary.LittleEndian.PutUint32(h[4:], uint32(w.records))
+	binary.LittleEndian.PutUint16(h[8:], uint16(size))
+	binary.LittleEndian.PutUint16(h[10:], uint16(recordSize))
+	for i, f := range w.fields {
+		d := h[32+32*i:]
+		copy(d[0:11], f.Name)
+		d[11] = byte(f.Type)
+		d[16] = f.Length
+		d[17] = f.Decimals
+	}
+	h[size-1] = 0x0d
+	return h
+}
+
+var errValueType = errors.New("value type doesn't match the field type")
+
+func formatValue(f Field, v any) ([]byte, error) {
+	n := int(f.Length)
+	if v == nil {
+		return []byte(strings.Repeat(" ", n)), nil
+	}
+	var s string
+	switch f.Type {
+	case Character:
+		str, ok := v.(string)
+		if !ok {
+			return nil, errValueType
+		}
+		s = truncate(str, n)
+		return []byte(s + strings.Repeat(" ", n-len(s))), nil
+	case Numeric:
+		switch v := v.(type) {
+		case int:
+			s = strconv.Itoa(v)
+		case int32:
+			s = strconv.FormatInt(int64(v), 10)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', int(f.Decimals), 64)
+		default:
+			return nil, errValueType
+		}
+		if len(s) > n {
+			return nil, fmt.Errorf("%s doesn't fit in %d characters", s, n)
+		}
+	case Date:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, errValueType
+		}
+		s = t.Format("20060102")
+	case Logical:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errValueType
+		}
+		s = "F"
+		if b {
+			s = "T"
+		}
+	}
+	return []byte(strings.Repeat(" ", n-len(s)) + s), nil
+}
+
+// truncate cuts s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
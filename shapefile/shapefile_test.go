@@ -0,0 +1,75 @@
+package shapefile
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) *os.File {
+		f, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	shp, shx, dbf := open("r.shp"), open("r.shx"), open("r.dbf")
+
+	w, err := NewPointWriter(shp, shx, dbf, []Field{
+		{Name: "TYPE", Type: Character, Length: 7},
+		{Name: "MAG", Type: Numeric, Length: 6, Decimals: 2},
+		{Name: "DATE", Type: Date, Length: 8},
+		{Name: "MEASURED", Type: Logical, Length: 1},
+	})
+	require.NoError(t, err)
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, w.Write(-97.44, 35.22, []any{"hail", 1.75, day, nil}))
+	require.NoError(t, w.Write(-95.99, 36.15, []any{"wind", nil, day, true}))
+	require.NoError(t, w.Close())
+
+	b, err := os.ReadFile(shp.Name())
+	require.NoError(t, err)
+	require.Len(t, b, 100+2*28)
+	assert.Equal(t, uint32(9994), binary.BigEndian.Uint32(b[0:]))
+	assert.Equal(t, uint32(len(b)/2), binary.BigEndian.Uint32(b[24:]))
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(b[32:]))
+	bound := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b[36+8*i:])) }
+	assert.Equal(t, []float64{-97.44, 35.22, -95.99, 36.15}, []float64{bound(0), bound(1), bound(2), bound(3)})
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(b[128:]), "second record number")
+	assert.Equal(t, -95.99, math.Float64frombits(binary.LittleEndian.Uint64(b[140:])))
+
+	b, err = os.ReadFile(shx.Name())
+	require.NoError(t, err)
+	require.Len(t, b, 100+2*8)
+	assert.Equal(t, uint32((100+28)/2), binary.BigEndian.Uint32(b[108:]), "second record offset")
+
+	b, err = os.ReadFile(dbf.Name())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(b[4:]))
+	headerSize := int(binary.LittleEndian.Uint16(b[8:]))
+	recordSize := int(binary.LittleEndian.Uint16(b[10:]))
+	assert.Equal(t, 32+4*32+1, headerSize)
+	assert.Equal(t, 1+7+6+8+1, recordSize)
+	records := b[headerSize:]
+	assert.Equal(t, " hail     1.7520240506 ", string(records[:recordSize]))
+	assert.Equal(t, " wind         20240506T", string(records[recordSize:2*recordSize]))
+	assert.Equal(t, byte(0x1a), records[2*recordSize])
+}
+
+func TestNewPointWriter_RejectsLongNames(t *testing.T) {
+	_, err := NewPointWriter(nil, nil, nil, []Field{{Name: "MAGNITUDE_IN", Type: Numeric, Length: 6}})
+	assert.Error(t, err)
+}
+
+func Test_truncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	assert.Equal(t, "a", truncate("aé", 2), "é is two bytes")
+}
@@ -1,21 +1,25 @@
 package hoarder
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stormsync/database"
 	report "github.com/stormsync/transformer/proto"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/jason-costello/weather/accesssvc/nws"
 )
 
 func hailMsgToDBReport(msgValue []byte) (database.InsertReportParams, error) {
 	var msg report.HailMsg
 	if err := proto.Unmarshal(msgValue, &msg); err != nil {
-		return database.InsertReportParams{}, nil
+		return database.InsertReportParams{}, fmt.Errorf("failed to unmarshal hail message: %w", err)
 	}
 
 	uTime := time.Unix(msg.GetTime(), 0).UTC()
+	office, comments := nws.ParseOffice(msg.GetRemarks())
 
 	dbReport := database.InsertReportParams{
 		RptType: database.ReportTypeHail,
@@ -45,21 +49,22 @@ func hailMsgToDBReport(msgValue []byte) (database.InsertReportParams, error) {
 			Valid:  true},
 		EventLocation: msg.Location,
 		Comments: pgtype.Text{
-			String: msg.State,
+			String: comments,
 			Valid:  true},
 		NwsOffice: pgtype.Text{
-			String: msg.State,
-			Valid:  true}, // TODO: add parsing the office to the transformer svc.
+			String: office,
+			Valid:  office != ""},
 	}
 	return dbReport, nil
 }
 func windMsgToDBReport(msgValue []byte) (database.InsertReportParams, error) {
 	var msg report.WindMsg
 	if err := proto.Unmarshal(msgValue, &msg); err != nil {
-		return database.InsertReportParams{}, nil
+		return database.InsertReportParams{}, fmt.Errorf("failed to unmarshal wind message: %w", err)
 	}
 
 	uTime := time.Unix(msg.GetTime(), 0).UTC()
+	office, comments := nws.ParseOffice(msg.GetRemarks())
 
 	dbReport := database.InsertReportParams{
 		RptType: database.ReportTypeHail,
@@ -89,21 +94,22 @@ func windMsgToDBReport(msgValue []byte) (database.InsertReportParams, error) {
 			Valid:  true},
 		EventLocation: msg.Location,
 		Comments: pgtype.Text{
-			String: msg.State,
+			String: comments,
 			Valid:  true},
 		NwsOffice: pgtype.Text{
-			String: msg.State,
-			Valid:  true}, // TODO: add parsing the office to the transformer svc.
+			String: office,
+			Valid:  office != ""},
 	}
 	return dbReport, nil
 }
 func tornadoMsgToDBReport(msgValue []byte) (database.InsertReportParams, error) {
 	var msg report.TornadoMsg
 	if err := proto.Unmarshal(msgValue, &msg); err != nil {
-		return database.InsertReportParams{}, nil
+		return database.InsertReportParams{}, fmt.Errorf("failed to unmarshal tornado message: %w", err)
 	}
 
 	uTime := time.Unix(msg.GetTime(), 0).UTC()
+	office, comments := nws.ParseOffice(msg.GetRemarks())
 
 	dbReport := database.InsertReportParams{
 		RptType: database.ReportTypeTornado,
@@ -133,11 +139,11 @@ func tornadoMsgToDBReport(msgValue []byte) (database.InsertReportParams, error)
 			Valid:  true},
 		EventLocation: msg.Location,
 		Comments: pgtype.Text{
-			String: msg.State,
+			String: comments,
 			Valid:  true},
 		NwsOffice: pgtype.Text{
-			String: msg.State,
-			Valid:  true}, // TODO: add parsing the office to the transformer svc.
+			String: office,
+			Valid:  office != ""},
 	}
 	return dbReport, nil
 }